@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// Adguard syncs the records as AdGuard Home DNS rewrites. The file keeps the rewrites that
+// are managed, so the removed ones can be deleted.
+type Adguard struct {
+	Client reload.Adguard
+	// Debugf is called with every rewrite added or deleted.
+	Debugf output.Logf
+}
+
+// Render writes one "domain answer" line per record.
+func (a Adguard) Render(builder *strings.Builder, records []output.Record) {
+	output.Adguard{}.Write(builder, records)
+}
+
+// Verify does nothing since AdGuard Home validates the rewrites when they are added.
+func (a Adguard) Verify(path string) error {
+	return nil
+}
+
+// Apply adds the new rewrites and deletes the removed ones.
+func (a Adguard) Apply(oldContents string, newContents string) error {
+	added, deleted, err := a.Client.Reconcile(oldContents, newContents)
+	for _, rewrite := range deleted {
+		a.Debugf.Printf("Deleted AdGuard Home rewrite %s to %s", rewrite.Domain, rewrite.Answer)
+	}
+	for _, rewrite := range added {
+		a.Debugf.Printf("Added AdGuard Home rewrite %s to %s", rewrite.Domain, rewrite.Answer)
+	}
+	return err
+}
+
+// Rollback restores the rewrites of oldContents, reverting the ones Apply already changed.
+func (a Adguard) Rollback(oldContents string, newContents string) error {
+	return a.Apply(newContents, oldContents)
+}
@@ -0,0 +1,47 @@
+// Package backend combines the output writers and reload strategies of each DNS server
+// behind a single interface, so the sync doesn't depend on the DNS servers it feeds.
+package backend
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// OutputBackend is a DNS server fed with a generated file.
+type OutputBackend interface {
+	// Render writes the records in the format of the DNS server.
+	Render(builder *strings.Builder, records []output.Record)
+	// Verify checks the file at path after it is written and before it is applied.
+	Verify(path string) error
+	// Apply makes the DNS server use the new contents of the file, given the previous ones.
+	Apply(oldContents string, newContents string) error
+	// Rollback undoes a failed Apply once the file has been restored to oldContents.
+	Rollback(oldContents string, newContents string) error
+}
+
+// FileWriter is implemented by the backends that write their whole file from the records of
+// every instance at once, like a zone file with a single SOA record. The header of the file is
+// left to them and Render is not used.
+type FileWriter interface {
+	// WriteFile returns the file with the comment header and recordSets, the records of each
+	// instance, given the current contents of the file.
+	WriteFile(header string, recordSets [][]output.Record, current string) (string, error)
+}
+
+// check runs a configuration checker, returning its output and errors when it fails.
+func check(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	var outb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &outb
+	err := cmd.Run()
+
+	if err != nil && outb.Len() > 0 {
+		return fmt.Errorf("%s, %s", err, strings.TrimSpace(outb.String()))
+	}
+	return err
+}
@@ -0,0 +1,53 @@
+package backend
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// Bind writes a BIND zone file with the records of its zone, checked with named-checkzone and
+// optionally reloaded with rndc.
+type Bind struct {
+	Writer output.Bind
+	// Checkzone is the path of the named-checkzone executable.
+	Checkzone string
+	// Rndc is the path of the rndc executable reloading the zone after every change. The
+	// zone is left for BIND to pick up when it is empty.
+	Rndc string
+}
+
+// Render writes the records of the zone.
+func (b Bind) Render(builder *strings.Builder, records []output.Record) {
+	b.Writer.Write(builder, records)
+}
+
+// WriteFile returns the zone file with the records of every instance and its SOA and NS
+// records, bumping the serial of current when the zone changes.
+func (b Bind) WriteFile(header string, recordSets [][]output.Record, current string) (string, error) {
+	builder := strings.Builder{}
+	for _, records := range recordSets {
+		b.Writer.Write(&builder, records)
+	}
+	return b.Writer.WriteZone(header, builder.String(), current, time.Now()), nil
+}
+
+// Verify checks the zone file with named-checkzone.
+func (b Bind) Verify(path string) error {
+	return check(b.Checkzone, "-q", strings.TrimSuffix(b.Writer.Zone, "."), path)
+}
+
+// Apply reloads the zone with rndc when enabled.
+func (b Bind) Apply(oldContents string, newContents string) error {
+	if b.Rndc == "" {
+		return nil
+	}
+	return reload.Rndc(b.Rndc, strings.TrimSuffix(b.Writer.Zone, "."))
+}
+
+// Rollback reloads the restored zone.
+func (b Bind) Rollback(oldContents string, newContents string) error {
+	return b.Apply(newContents, oldContents)
+}
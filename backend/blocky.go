@@ -0,0 +1,44 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// Blocky writes the customDNS mapping of Blocky and refreshes it with its API.
+type Blocky struct {
+	Writer output.Blocky
+	// Client refreshes Blocky after every change when its URL is set.
+	Client reload.Blocky
+}
+
+// Render writes the mapping entries of the records.
+func (b Blocky) Render(builder *strings.Builder, records []output.Record) {
+	b.Writer.Write(builder, records)
+}
+
+// WriteFile returns the customDNS section with the records of every instance, since a host
+// can only have a single mapping.
+func (b Blocky) WriteFile(header string, recordSets [][]output.Record, current string) (string, error) {
+	return b.Writer.WriteConfig(header, recordSets), nil
+}
+
+// Verify does nothing since Blocky has no checker for its configuration.
+func (b Blocky) Verify(path string) error {
+	return nil
+}
+
+// Apply refreshes Blocky when its API is set.
+func (b Blocky) Apply(oldContents string, newContents string) error {
+	if b.Client.URL == "" {
+		return nil
+	}
+	return b.Client.Refresh()
+}
+
+// Rollback refreshes Blocky again for the restored file.
+func (b Blocky) Rollback(oldContents string, newContents string) error {
+	return b.Apply(newContents, oldContents)
+}
@@ -0,0 +1,64 @@
+package backend
+
+import (
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// CoreDNS writes a hosts file for the hosts plugin of CoreDNS or, when its Zone is set, a zone
+// file like Bind for the file plugin. CoreDNS picks up the changes by itself, every 5 seconds
+// for the hosts plugin and when the serial of the zone changes for the file plugin, after
+// its reload interval.
+type CoreDNS struct {
+	Hosts output.Hosts
+	Zone  output.Bind
+	// Command is run after every change when set, e.g. to restart CoreDNS instead of waiting
+	// for it.
+	Command string
+}
+
+// Render writes the records as hosts lines, or the records of the zone.
+func (c CoreDNS) Render(builder *strings.Builder, records []output.Record) {
+	if c.Zone.Zone != "" {
+		c.Zone.Write(builder, records)
+		return
+	}
+	c.Hosts.Write(builder, records)
+}
+
+// WriteFile returns the hosts file with the records of every instance or, with a Zone, the
+// zone file with its SOA and NS records, bumping the serial of current when the zone changes.
+func (c CoreDNS) WriteFile(header string, recordSets [][]output.Record, current string) (string, error) {
+	builder := strings.Builder{}
+	if c.Zone.Zone == "" {
+		output.WriteHeader(&builder, header)
+	}
+	for _, records := range recordSets {
+		c.Render(&builder, records)
+	}
+	if c.Zone.Zone == "" {
+		return builder.String(), nil
+	}
+	return c.Zone.WriteZone(header, builder.String(), current, time.Now()), nil
+}
+
+// Verify does nothing since CoreDNS has no checker for its files.
+func (c CoreDNS) Verify(path string) error {
+	return nil
+}
+
+// Apply runs Command when set.
+func (c CoreDNS) Apply(oldContents string, newContents string) error {
+	if c.Command == "" {
+		return nil
+	}
+	return reload.Command(c.Command)
+}
+
+// Rollback runs Command again for the restored file.
+func (c CoreDNS) Rollback(oldContents string, newContents string) error {
+	return c.Apply(newContents, oldContents)
+}
@@ -0,0 +1,66 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// DnsmasqReloadStrategies are the reload strategies of Unbound that also apply to dnsmasq. The
+// ones of unbound-control don't.
+var DnsmasqReloadStrategies = []string{ReloadRestart, ReloadSystemd, ReloadService, ReloadInit, ReloadCommand, ReloadSignal, ReloadDocker, ReloadNone}
+
+// Dnsmasq writes address and cname lines and restarts dnsmasq.
+type Dnsmasq struct {
+	Writer output.Dnsmasq
+	// Dnsmasq is the path of the dnsmasq executable used to check the file.
+	Dnsmasq string
+	// Reload is one of DnsmasqReloadStrategies, ReloadRestart by default.
+	Reload string
+	// Command is the command of ReloadCommand and Pidfile the pid file of ReloadSignal, like
+	// /var/run/dnsmasq/dnsmasq.pid.
+	Command string
+	Pidfile string
+	// Systemd is the systemd of ReloadSystemd.
+	Systemd reload.Systemd
+	// Container is the dnsmasq container of ReloadDocker.
+	Container reload.DockerContainer
+}
+
+// Render writes the records as address and cname lines.
+func (d Dnsmasq) Render(builder *strings.Builder, records []output.Record) {
+	d.Writer.Write(builder, records)
+}
+
+// Verify runs dnsmasq --test against the file only.
+func (d Dnsmasq) Verify(path string) error {
+	return check(d.Dnsmasq, "--test", "--conf-file="+path)
+}
+
+// Apply reloads or restarts dnsmasq with the reload strategy.
+func (d Dnsmasq) Apply(oldContents string, newContents string) error {
+	switch d.Reload {
+	case ReloadSystemd:
+		return d.Systemd.ReloadOrRestart("dnsmasq.service")
+	case ReloadService:
+		return reload.Service("dnsmasq")
+	case ReloadInit:
+		return reload.Init("dnsmasq")
+	case ReloadCommand:
+		return reload.Command(d.Command)
+	case ReloadSignal:
+		return reload.Signal(d.Pidfile)
+	case ReloadDocker:
+		return d.Container.Reload()
+	case ReloadNone:
+		return nil
+	default:
+		return reload.Systemctl("dnsmasq")
+	}
+}
+
+// Rollback does nothing since a failed restart leaves nothing to undo.
+func (d Dnsmasq) Rollback(oldContents string, newContents string) error {
+	return nil
+}
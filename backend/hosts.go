@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// Hosts writes a file in the format of /etc/hosts for the DNS servers that watch it, like
+// the hosts plugin of CoreDNS.
+type Hosts struct {
+	Writer output.Hosts
+}
+
+// Render writes the records as hosts lines.
+func (h Hosts) Render(builder *strings.Builder, records []output.Record) {
+	h.Writer.Write(builder, records)
+}
+
+// Verify does nothing since there is no checker for hosts files.
+func (h Hosts) Verify(path string) error {
+	return nil
+}
+
+// Apply does nothing since the readers of the file pick up its changes.
+func (h Hosts) Apply(oldContents string, newContents string) error {
+	return nil
+}
+
+// Rollback does nothing.
+func (h Hosts) Rollback(oldContents string, newContents string) error {
+	return nil
+}
+
+// Pihole writes the Pi-hole custom.list and reloads the Pi-hole DNS.
+type Pihole struct {
+	Hosts
+	// Pihole is the path of the pihole executable.
+	Pihole string
+}
+
+// Apply reloads the Pi-hole DNS.
+func (p Pihole) Apply(oldContents string, newContents string) error {
+	return reload.Pihole(p.Pihole)
+}
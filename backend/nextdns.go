@@ -0,0 +1,43 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// NextDNS syncs the records as the rewrites of a NextDNS profile. The file keeps the rewrites
+// that are managed, so the removed ones can be deleted.
+type NextDNS struct {
+	Client reload.NextDNS
+	// Debugf is called with every rewrite added or deleted.
+	Debugf output.Logf
+}
+
+// Render writes one "domain answer" line per record, like the adguard backend.
+func (n NextDNS) Render(builder *strings.Builder, records []output.Record) {
+	output.Adguard{}.Write(builder, records)
+}
+
+// Verify does nothing since NextDNS validates the rewrites when they are added.
+func (n NextDNS) Verify(path string) error {
+	return nil
+}
+
+// Apply adds the new rewrites and deletes the removed ones.
+func (n NextDNS) Apply(oldContents string, newContents string) error {
+	added, deleted, err := n.Client.Reconcile(oldContents, newContents)
+	for _, rewrite := range deleted {
+		n.Debugf.Printf("Deleted NextDNS rewrite %s to %s", rewrite.Domain, rewrite.Answer)
+	}
+	for _, rewrite := range added {
+		n.Debugf.Printf("Added NextDNS rewrite %s to %s", rewrite.Domain, rewrite.Answer)
+	}
+	return err
+}
+
+// Rollback restores the rewrites of oldContents, reverting the ones Apply already changed.
+func (n NextDNS) Rollback(oldContents string, newContents string) error {
+	return n.Apply(newContents, oldContents)
+}
@@ -0,0 +1,65 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// HostOverridesClient manages the host overrides of the unbound of a firewall, or the records
+// of a DNS server, through its API.
+type HostOverridesClient interface {
+	// Reconcile makes the managed host overrides match records and returns the records it
+	// added and deleted.
+	Reconcile(records []output.Record) (added []output.Record, deleted []output.Record, err error)
+	// Reconfigure makes unbound use the current host overrides.
+	Reconfigure() error
+}
+
+// HostOverrides syncs the records as the host overrides of the unbound of OPNsense or pfSense,
+// which rewrite the unbound configuration themselves, as the static DNS entries of MikroTik
+// RouterOS or as the records of a Technitium zone.
+// The file keeps the overrides that are managed.
+type HostOverrides struct {
+	Writer output.HostOverrides
+	Client HostOverridesClient
+	// Name of the firewall or DNS server in the logs.
+	Name string
+	// Debugf is called with every host override added or deleted.
+	Debugf output.Logf
+}
+
+// Render writes one "host type value" line per record.
+func (h HostOverrides) Render(builder *strings.Builder, records []output.Record) {
+	h.Writer.Write(builder, records)
+}
+
+// Verify does nothing since the firewall validates the host overrides when they are added.
+func (h HostOverrides) Verify(path string) error {
+	return nil
+}
+
+// Apply adds the new host overrides, deletes the removed ones and, when any changed,
+// reconfigures unbound.
+func (h HostOverrides) Apply(oldContents string, newContents string) error {
+	added, deleted, err := h.Client.Reconcile(output.ParseHostOverrides(newContents))
+	for _, r := range deleted {
+		h.Debugf.Printf("Deleted %s record %s %s %s", h.Name, r.Host, r.Type, r.Value)
+	}
+	for _, r := range added {
+		h.Debugf.Printf("Added %s record %s %s %s", h.Name, r.Host, r.Type, r.Value)
+	}
+	if len(added) == 0 && len(deleted) == 0 {
+		return err
+	}
+	reconfigureErr := h.Client.Reconfigure()
+	if err != nil {
+		return err
+	}
+	return reconfigureErr
+}
+
+// Rollback restores the host overrides of oldContents.
+func (h HostOverrides) Rollback(oldContents string, newContents string) error {
+	return h.Apply(newContents, oldContents)
+}
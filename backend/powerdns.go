@@ -0,0 +1,104 @@
+package backend
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// PowerDNS pushes the records to a zone of the PowerDNS Authoritative Server through its API.
+// The file keeps the records that are managed, so the RRsets of the removed hosts can be
+// deleted from the zone. An RRset of a managed host and type is replaced as a whole, like the
+// API does. With the Owner of Client, the RRsets are only changed on the hosts of its TXT
+// registry.
+type PowerDNS struct {
+	Writer output.RFC2136
+	Client reload.PowerDNS
+	// Debugf is called with every RRset replaced or deleted.
+	Debugf output.Logf
+}
+
+// Render writes one "host ttl type value" line per record of the zone.
+func (p PowerDNS) Render(builder *strings.Builder, records []output.Record) {
+	p.Writer.Write(builder, records)
+}
+
+// Verify does nothing since PowerDNS validates the RRsets of the patch.
+func (p PowerDNS) Verify(path string) error {
+	return nil
+}
+
+// Apply replaces the RRsets of newContents that changed since oldContents and deletes the
+// ones only in oldContents in a single patch.
+func (p PowerDNS) Apply(oldContents string, newContents string) error {
+	managed := powerdnsRRsets(output.ParseRFC2136Records(oldContents))
+	wanted := powerdnsRRsets(output.ParseRFC2136Records(newContents))
+	changed := []reload.PowerDNSRRset{}
+	for key, rrset := range wanted {
+		if !sameRRset(rrset, managed[key]) {
+			changed = append(changed, rrset)
+		}
+	}
+	for key, rrset := range managed {
+		if _, ok := wanted[key]; !ok {
+			changed = append(changed, reload.PowerDNSRRset{Name: rrset.Name, Type: rrset.Type})
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Slice(changed, func(i, j int) bool {
+		if changed[i].Name != changed[j].Name {
+			return changed[i].Name < changed[j].Name
+		}
+		return changed[i].Type < changed[j].Type
+	})
+
+	changed, err := p.Client.Patch(changed)
+	if err != nil {
+		return err
+	}
+	for _, rrset := range changed {
+		if len(rrset.Contents) == 0 {
+			p.Debugf.Printf("Deleted %s %s from the zone %s", rrset.Name, rrset.Type, p.Client.Zone)
+			continue
+		}
+		p.Debugf.Printf("Replaced %s %d %s %s in the zone %s", rrset.Name, rrset.TTL, rrset.Type, strings.Join(rrset.Contents, ", "), p.Client.Zone)
+	}
+	return nil
+}
+
+// Rollback restores the RRsets of oldContents.
+func (p PowerDNS) Rollback(oldContents string, newContents string) error {
+	return p.Apply(newContents, oldContents)
+}
+
+type rrsetKey struct {
+	name       string
+	recordType string
+}
+
+// powerdnsRRsets groups the records by host and type, with their values sorted.
+func powerdnsRRsets(records map[output.RFC2136Record]bool) map[rrsetKey]reload.PowerDNSRRset {
+	rrsets := map[rrsetKey]reload.PowerDNSRRset{}
+	for record := range records {
+		key := rrsetKey{name: record.Host, recordType: record.Type}
+		rrset := rrsets[key]
+		rrset.Name = record.Host
+		rrset.Type = record.Type
+		rrset.TTL = record.TTL
+		rrset.Contents = append(rrset.Contents, record.Value)
+		rrsets[key] = rrset
+	}
+	for key, rrset := range rrsets {
+		sort.Strings(rrset.Contents)
+		rrsets[key] = rrset
+	}
+	return rrsets
+}
+
+func sameRRset(a reload.PowerDNSRRset, b reload.PowerDNSRRset) bool {
+	return a.TTL == b.TTL && strings.Join(a.Contents, ",") == strings.Join(b.Contents, ",")
+}
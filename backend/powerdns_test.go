@@ -0,0 +1,40 @@
+package backend
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+func TestPowerDNSApply(t *testing.T) {
+	patches := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		patches = append(patches, r.Method+" "+string(body))
+	}))
+	defer server.Close()
+	p := PowerDNS{Client: reload.PowerDNS{URL: server.URL, Server: "localhost", Zone: "example.com"}}
+
+	err := p.Apply(
+		"a.example.com 300 A 192.168.1.10\nb.example.com 300 A 192.168.1.11\nc.example.com 300 A 192.168.1.12\n",
+		"a.example.com 300 A 192.168.1.10\na.example.com 300 A 192.168.1.13\nc.example.com 300 A 192.168.1.12\n",
+	)
+	if err != nil {
+		t.Fatalf("Apply() failed. %s", err)
+	}
+	// The RRset of a is replaced as a whole, b is deleted and c is left as it is
+	want := `PATCH {"rrsets":[` +
+		`{"name":"a.example.com.","type":"A","ttl":300,"changetype":"REPLACE","records":[{"content":"192.168.1.10","disabled":false},{"content":"192.168.1.13","disabled":false}]},` +
+		`{"name":"b.example.com.","type":"A","changetype":"DELETE","records":[]}]}`
+	if len(patches) != 1 || patches[0] != want {
+		t.Errorf("Apply() sent %q, want %q", patches, want)
+	}
+
+	err = p.Apply("a.example.com 300 A 192.168.1.10\n", "a.example.com 300 A 192.168.1.10\n")
+	if err != nil || len(patches) != 1 {
+		t.Errorf("Apply() = %v with %d patches, want no patch without changes", err, len(patches))
+	}
+}
@@ -0,0 +1,148 @@
+package backend
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// RFC2136 pushes the records to a zone of any DNS server accepting RFC 2136 dynamic updates,
+// like BIND, Knot or PowerDNS. The file keeps the records that are managed, so the removed
+// ones can be deleted from the zone. With the Owner of Nsupdate, the records are only changed
+// on the hosts of its TXT registry.
+type RFC2136 struct {
+	Writer   output.RFC2136
+	Nsupdate reload.Nsupdate
+	// Debugf is called with every record added or deleted.
+	Debugf output.Logf
+	// Warnf is called with the hosts of other owners, which are skipped.
+	Warnf output.Logf
+}
+
+// Render writes one "host ttl type value" line per record of the zone.
+func (r RFC2136) Render(builder *strings.Builder, records []output.Record) {
+	r.Writer.Write(builder, records)
+}
+
+// Verify does nothing since the server validates the records of the update.
+func (r RFC2136) Verify(path string) error {
+	return nil
+}
+
+// Apply deletes the records of oldContents that are not in newContents and adds the new ones
+// in a single update.
+func (r RFC2136) Apply(oldContents string, newContents string) error {
+	managed := output.ParseRFC2136Records(oldContents)
+	wanted := output.ParseRFC2136Records(newContents)
+	deleted := missingRecords(managed, wanted)
+	added := missingRecords(wanted, managed)
+	if len(deleted) == 0 && len(added) == 0 {
+		return nil
+	}
+	if r.Nsupdate.Owner != "" {
+		var err error
+		deleted, added, err = r.register(deleted, added, wanted)
+		if err != nil {
+			return err
+		}
+		if len(deleted) == 0 && len(added) == 0 {
+			return nil
+		}
+	}
+
+	err := r.Nsupdate.Update(deleted, added)
+	if err != nil {
+		return err
+	}
+	for _, record := range deleted {
+		r.Debugf.Printf("Deleted %s %s %s from the zone %s", record.Host, record.Type, record.Value, r.Nsupdate.Zone)
+	}
+	for _, record := range added {
+		r.Debugf.Printf("Added %s %d %s %s to the zone %s", record.Host, record.TTL, record.Type, record.Value, r.Nsupdate.Zone)
+	}
+	return nil
+}
+
+// Rollback restores the records of oldContents.
+func (r RFC2136) Rollback(oldContents string, newContents string) error {
+	return r.Apply(newContents, oldContents)
+}
+
+// register keeps the changes of the hosts owned by the Owner of Nsupdate and of the new ones,
+// adding the TXT record of the owner to the new hosts and deleting it from the hosts without
+// records in wanted.
+func (r RFC2136) register(deleted []output.RFC2136Record, added []output.RFC2136Record, wanted map[output.RFC2136Record]bool) ([]output.RFC2136Record, []output.RFC2136Record, error) {
+	hosts := []string{}
+	ttls := map[string]int{}
+	for _, record := range append(append([]output.RFC2136Record{}, deleted...), added...) {
+		if _, ok := ttls[record.Host]; !ok {
+			hosts = append(hosts, record.Host)
+		}
+		ttls[record.Host] = record.TTL
+	}
+	wantedHosts := map[string]bool{}
+	for record := range wanted {
+		wantedHosts[record.Host] = true
+	}
+
+	owned := map[string]bool{}
+	created := map[string]bool{}
+	registryDeleted := []output.RFC2136Record{}
+	registryAdded := []output.RFC2136Record{}
+	for _, host := range hosts {
+		isOwned, exists, err := r.Nsupdate.Owned(host)
+		if err != nil {
+			return nil, nil, err
+		}
+		marker := output.RFC2136Record{Record: output.Record{Host: host, Type: "TXT", Value: strconv.Quote(reload.OwnerTXT(r.Nsupdate.Owner))}, TTL: ttls[host]}
+		switch {
+		case isOwned:
+			owned[host] = true
+			if !wantedHosts[host] {
+				registryDeleted = append(registryDeleted, marker)
+			}
+		case exists:
+			r.Warnf.Printf("Skipping host %s of the zone %s with records not owned by traefik2unbound", host, r.Nsupdate.Zone)
+		case wantedHosts[host]:
+			created[host] = true
+			registryAdded = append(registryAdded, marker)
+		}
+	}
+
+	keptDeleted := []output.RFC2136Record{}
+	for _, record := range deleted {
+		if owned[record.Host] {
+			keptDeleted = append(keptDeleted, record)
+		}
+	}
+	keptAdded := []output.RFC2136Record{}
+	for _, record := range added {
+		if owned[record.Host] || created[record.Host] {
+			keptAdded = append(keptAdded, record)
+		}
+	}
+	return append(keptDeleted, registryDeleted...), append(keptAdded, registryAdded...), nil
+}
+
+// missingRecords returns the records of a that are not in b, sorted.
+func missingRecords(a map[output.RFC2136Record]bool, b map[output.RFC2136Record]bool) []output.RFC2136Record {
+	missing := []output.RFC2136Record{}
+	for record := range a {
+		if !b[record] {
+			missing = append(missing, record)
+		}
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		if missing[i].Host != missing[j].Host {
+			return missing[i].Host < missing[j].Host
+		}
+		if missing[i].Type != missing[j].Type {
+			return missing[i].Type < missing[j].Type
+		}
+		return missing[i].Value < missing[j].Value
+	})
+	return missing
+}
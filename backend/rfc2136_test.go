@@ -0,0 +1,172 @@
+package backend
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// fakeNsupdate returns the path of an nsupdate that appends its script to the returned log.
+func fakeNsupdate(t *testing.T) (string, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake nsupdate is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), "nsupdate")
+	err := os.WriteFile(path, []byte("#!/bin/sh\ncat >> \"$0.log\"\n"), 0755)
+	if err != nil {
+		t.Fatalf("WriteFile() failed. %s", err)
+	}
+	return path, path + ".log"
+}
+
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	contents, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("ReadFile() failed. %s", err)
+	}
+	return string(contents)
+}
+
+// fakeDNS answers the TXT, A and AAAA queries with the records of its zone, keyed by name and
+// type, and with NXDOMAIN the names without any. It returns the address it listens on.
+func fakeDNS(t *testing.T, zone map[string][]string) string {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("ListenPacket() failed. %s", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	names := map[string]bool{}
+	for key := range zone {
+		names[strings.Fields(key)[0]] = true
+	}
+
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var parser dnsmessage.Parser
+			header, err := parser.Start(buffer[:n])
+			if err != nil {
+				continue
+			}
+			question, err := parser.Question()
+			if err != nil {
+				continue
+			}
+			name := strings.ToLower(question.Name.String())
+			header.Response = true
+			header.Authoritative = true
+			if !names[name] {
+				header.RCode = dnsmessage.RCodeNameError
+			}
+			builder := dnsmessage.NewBuilder(nil, header)
+			_ = builder.StartQuestions()
+			_ = builder.Question(question)
+			_ = builder.StartAnswers()
+			resource := dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 300}
+			for _, value := range zone[name+" "+strings.TrimPrefix(question.Type.String(), "Type")] {
+				switch question.Type {
+				case dnsmessage.TypeTXT:
+					_ = builder.TXTResource(resource, dnsmessage.TXTResource{TXT: []string{value}})
+				case dnsmessage.TypeA:
+					a := dnsmessage.AResource{}
+					copy(a.A[:], net.ParseIP(value).To4())
+					_ = builder.AResource(resource, a)
+				case dnsmessage.TypeAAAA:
+					aaaa := dnsmessage.AAAAResource{}
+					copy(aaaa.AAAA[:], net.ParseIP(value))
+					_ = builder.AAAAResource(resource, aaaa)
+				}
+			}
+			response, err := builder.Finish()
+			if err == nil {
+				_, _ = conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestRFC2136Apply(t *testing.T) {
+	path, log := fakeNsupdate(t)
+	r := RFC2136{Nsupdate: reload.Nsupdate{Path: path, Server: "ns.example.com", Zone: "example.com"}}
+
+	err := r.Apply("a.example.com 300 A 192.168.1.10\nb.example.com 300 A 192.168.1.11\n", "a.example.com 300 A 192.168.1.10\nc.example.com 60 A 192.168.1.12\n")
+	if err != nil {
+		t.Fatalf("Apply() failed. %s", err)
+	}
+	want := "server ns.example.com\n" +
+		"zone example.com.\n" +
+		"update delete b.example.com. A 192.168.1.11\n" +
+		"update add c.example.com. 60 A 192.168.1.12\n" +
+		"send\n"
+	if got := readLog(t, log); got != want {
+		t.Errorf("Apply() sent:\n%s\nwant:\n%s", got, want)
+	}
+
+	err = r.Apply("a.example.com 300 A 192.168.1.10\n", "# Endpoints extracted from 192.168.1.10\na.example.com 300 A 192.168.1.10\n")
+	if err != nil {
+		t.Fatalf("second Apply() failed. %s", err)
+	}
+	if got := readLog(t, log); got != want {
+		t.Errorf("second Apply() sent an update without changes:\n%s", got)
+	}
+}
+
+func TestRFC2136ApplyRegistry(t *testing.T) {
+	path, log := fakeNsupdate(t)
+	marker := reload.OwnerTXT("home")
+	server := fakeDNS(t, map[string][]string{
+		"a.example.com. A":   {"192.168.1.10"},
+		"a.example.com. TXT": {marker},
+		"b.example.com. A":   {"192.168.1.2"},
+		"d.example.com. A":   {"192.168.1.14"},
+		"d.example.com. TXT": {marker, "v=other"},
+	})
+	warnings := []string{}
+	r := RFC2136{
+		Nsupdate: reload.Nsupdate{Path: path, Server: server, Zone: "example.com", Owner: "home"},
+		Warnf:    func(format string, v ...interface{}) { warnings = append(warnings, format) },
+	}
+
+	err := r.Apply(
+		"a.example.com 300 A 192.168.1.10\nd.example.com 300 A 192.168.1.14\n",
+		"a.example.com 300 A 192.168.1.11\nb.example.com 300 A 192.168.1.12\nc.example.com 300 A 192.168.1.13\n",
+	)
+	if err != nil {
+		t.Fatalf("Apply() failed. %s", err)
+	}
+	host, port, _ := net.SplitHostPort(server)
+	// b has records of someone else, c is created with the TXT record of the owner and d is
+	// emptied so its TXT record is deleted too
+	want := "server " + host + " " + port + "\n" +
+		"zone example.com.\n" +
+		"update delete a.example.com. A 192.168.1.10\n" +
+		"update delete d.example.com. A 192.168.1.14\n" +
+		"update delete d.example.com. TXT " + strconv.Quote(marker) + "\n" +
+		"update add a.example.com. 300 A 192.168.1.11\n" +
+		"update add c.example.com. 300 A 192.168.1.13\n" +
+		"update add c.example.com. 300 TXT " + strconv.Quote(marker) + "\n" +
+		"send\n"
+	if got := readLog(t, log); got != want {
+		t.Errorf("Apply() sent:\n%s\nwant:\n%s", got, want)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("got the warnings %v, want the skipped host b", warnings)
+	}
+}
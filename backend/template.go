@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// Template writes the file with a user supplied Go text/template, leaving it for the DNS
+// server to pick up.
+type Template struct {
+	Writer output.Template
+}
+
+// Render executes the template with the records of a single instance. The file is written by
+// WriteFile with the records of every instance.
+func (t Template) Render(builder *strings.Builder, records []output.Record) {
+	_ = t.Writer.Write(builder, "", records)
+}
+
+// WriteFile executes the template with the header and the records of every instance.
+func (t Template) WriteFile(header string, recordSets [][]output.Record, current string) (string, error) {
+	records := []output.Record{}
+	for _, set := range recordSets {
+		records = append(records, set...)
+	}
+	builder := strings.Builder{}
+	err := t.Writer.Write(&builder, header, records)
+	return builder.String(), err
+}
+
+// Verify does nothing since there is no checker for the formats of the templates.
+func (t Template) Verify(path string) error {
+	return nil
+}
+
+// Apply does nothing since the readers of the file pick up its changes.
+func (t Template) Apply(oldContents string, newContents string) error {
+	return nil
+}
+
+// Rollback does nothing.
+func (t Template) Rollback(oldContents string, newContents string) error {
+	return nil
+}
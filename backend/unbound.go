@@ -0,0 +1,167 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+// Reload strategies of Unbound.
+const (
+	// ReloadRestart restarts the unbound service with systemctl.
+	ReloadRestart = "restart"
+	// ReloadSystemd reloads or restarts the unbound service through the D-Bus API of systemd.
+	ReloadSystemd = "systemd"
+	// ReloadUnboundControl runs unbound-control reload, keeping unbound running.
+	ReloadUnboundControl = "unbound-control"
+	// ReloadIncremental only adds and removes the changed records with unbound-control,
+	// keeping the cache of unbound.
+	ReloadIncremental = "incremental"
+	// ReloadService reloads the unbound service with the service command, like the rc.d script
+	// of FreeBSD.
+	ReloadService = "service"
+	// ReloadInit reloads the unbound service with its init script in /etc/init.d, like on
+	// OpenWrt.
+	ReloadInit = "init"
+	// ReloadCommand runs a command, e.g. for OpenRC or FreeBSD.
+	ReloadCommand = "command"
+	// ReloadSignal sends SIGHUP to the unbound process of a pid file.
+	ReloadSignal = "signal"
+	// ReloadDocker restarts the unbound container, or sends it a signal, with the Docker API.
+	ReloadDocker = "docker"
+	// ReloadNone leaves unbound as is, e.g. when something else reloads it.
+	ReloadNone = "none"
+)
+
+// ReloadStrategies are the reload strategies of Unbound.
+var ReloadStrategies = []string{ReloadRestart, ReloadSystemd, ReloadService, ReloadInit, ReloadUnboundControl, ReloadIncremental, ReloadCommand, ReloadSignal, ReloadDocker, ReloadNone}
+
+// Unbound writes local-data entries checked with unbound-checkconf.
+type Unbound struct {
+	Writer output.Unbound
+	// Checkconf is the path of the unbound-checkconf executable.
+	Checkconf string
+	// Config is the unbound.conf checked by Checkconf, which includes the file. Empty checks
+	// the default one of unbound-checkconf.
+	Config string
+	// CheckInclude checks the file alone, included by a minimal configuration, instead of
+	// Config.
+	CheckInclude bool
+	Control      reload.UnboundControl
+	// Reload is the reload strategy, ReloadRestart by default.
+	Reload string
+	// Command is the command of ReloadCommand and Pidfile the pid file of ReloadSignal.
+	Command string
+	Pidfile string
+	// Systemd is the systemd of ReloadSystemd.
+	Systemd reload.Systemd
+	// Container is the unbound container of ReloadDocker.
+	Container reload.DockerContainer
+	// Debugf is called with the names updated incrementally and Errorf when the incremental
+	// changes fail and unbound is reloaded instead.
+	Debugf output.Logf
+	Errorf output.Logf
+}
+
+// Render writes the records as local-data entries.
+func (u Unbound) Render(builder *strings.Builder, records []output.Record) {
+	u.Writer.Write(builder, records)
+}
+
+// WriteFile writes the local zones and domain-insecure of Writer and the records of every
+// instance, inside its views when it has any.
+func (u Unbound) WriteFile(header string, recordSets [][]output.Record, current string) (string, error) {
+	builder := strings.Builder{}
+	output.WriteHeader(&builder, header)
+	if len(u.Writer.Views) > 0 {
+		u.Writer.WriteViews(&builder, recordSets)
+		return builder.String(), nil
+	}
+	u.Writer.WriteLocalZones(&builder)
+	u.Writer.WriteDomainInsecure(&builder, recordSets)
+	u.Writer.WriteRecordSets(&builder, recordSets)
+	return builder.String(), nil
+}
+
+// Verify runs unbound-checkconf on the configuration of unbound, which includes the file, or
+// on the file alone with CheckInclude.
+func (u Unbound) Verify(path string) error {
+	if !u.CheckInclude {
+		if u.Config == "" {
+			return check(u.Checkconf)
+		}
+		return check(u.Checkconf, u.Config)
+	}
+
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	config, err := os.CreateTemp("", "traefik2unbound-*.conf")
+	if err != nil {
+		return fmt.Errorf("error creating the configuration to check %s. %s", path, err)
+	}
+	defer os.Remove(config.Name())
+	// Without a chroot and user the check doesn't depend on the host
+	_, err = fmt.Fprintf(config, "server:\n\tchroot: \"\"\n\tusername: \"\"\n\tinclude: %q\n", absolute)
+	if err == nil {
+		err = config.Close()
+	}
+	if err != nil {
+		return fmt.Errorf("error writing the configuration to check %s. %s", path, err)
+	}
+	return check(u.Checkconf, config.Name())
+}
+
+// Apply reloads unbound with the reload strategy.
+func (u Unbound) Apply(oldContents string, newContents string) error {
+	switch u.Reload {
+	case ReloadSystemd:
+		return u.Systemd.ReloadOrRestart("unbound.service")
+	case ReloadService:
+		return reload.Service("unbound")
+	case ReloadInit:
+		return reload.Init("unbound")
+	case ReloadUnboundControl:
+		return u.Control.Reload()
+	case ReloadIncremental:
+		return u.applyIncrementally(oldContents, newContents)
+	case ReloadCommand:
+		return reload.Command(u.Command)
+	case ReloadSignal:
+		return reload.Signal(u.Pidfile)
+	case ReloadDocker:
+		return u.Container.Reload()
+	case ReloadNone:
+		return nil
+	default:
+		return reload.Systemctl("unbound")
+	}
+}
+
+// Rollback removes the incremental changes already applied. The other strategies never leave
+// unbound with part of the changes.
+func (u Unbound) Rollback(oldContents string, newContents string) error {
+	if u.Reload != ReloadIncremental {
+		return nil
+	}
+	return u.applyIncrementally(newContents, oldContents)
+}
+
+// applyIncrementally applies only the changes, falling back to a full unbound-control reload
+// of the already written file if that fails.
+func (u Unbound) applyIncrementally(oldContents string, newContents string) error {
+	updated, err := u.Control.ApplyIncrementally(oldContents, newContents)
+	if err != nil {
+		u.Errorf.Printf("Error applying changes incrementally, reloading unbound. %s", err)
+		return u.Control.Reload()
+	}
+	for _, name := range updated {
+		u.Debugf.Printf("Updated %s incrementally", name)
+	}
+	return nil
+}
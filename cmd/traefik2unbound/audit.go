@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+// auditEntry is a line of the audit log, a change of a file and its record changes.
+type auditEntry struct {
+	Timestamp time.Time             `json:"timestamp"`
+	Path      string                `json:"path"`
+	Backend   string                `json:"backend"`
+	Result    string                `json:"result"`
+	Error     string                `json:"error,omitempty"`
+	Added     []syncer.RecordChange `json:"added"`
+	Removed   []syncer.RecordChange `json:"removed"`
+	Repointed []syncer.RecordChange `json:"repointed"`
+}
+
+// auditChange appends the change of the file of t, with the record changes of report, to
+// -audit-log. It is best effort, failures are only logged.
+func auditChange(t *syncer.Target, report syncer.ChangeReport, result string, err error) {
+	if auditLogPath == "" {
+		return
+	}
+	entry := auditEntry{
+		Timestamp: time.Now(),
+		Path:      t.Path,
+		Backend:   t.Name,
+		Result:    result,
+		Added:     report.Added,
+		Removed:   report.Removed,
+		Repointed: report.Repointed,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	line, marshalErr := json.Marshal(entry)
+	if marshalErr != nil {
+		errorf("Error marshalling the audit log entry. %s", marshalErr)
+		return
+	}
+	writeErr := appendAuditLog(append(line, '\n'))
+	if writeErr != nil {
+		errorf("Error writing the audit log %s. %s", auditLogPath, writeErr)
+	}
+}
+
+// appendAuditLog appends line to -audit-log, rotating it first when it would grow beyond
+// -audit-log-max-size.
+func appendAuditLog(line []byte) error {
+	info, err := os.Stat(auditLogPath)
+	if err == nil && auditLogMaxSize > 0 && info.Size()+int64(len(line)) > int64(auditLogMaxSize)<<20 {
+		err = rotateAuditLog()
+		if err != nil {
+			return fmt.Errorf("error rotating it. %s", err)
+		}
+	}
+	file, err := os.OpenFile(auditLogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(line)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// rotateAuditLog renames the audit log to .1, shifting the older ones and removing the one
+// beyond -audit-log-backups.
+func rotateAuditLog() error {
+	if auditLogBackups <= 0 {
+		return os.Remove(auditLogPath)
+	}
+	err := os.Remove(fmt.Sprintf("%s.%d", auditLogPath, auditLogBackups))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for i := auditLogBackups - 1; i >= 1; i-- {
+		err := os.Rename(fmt.Sprintf("%s.%d", auditLogPath, i), fmt.Sprintf("%s.%d", auditLogPath, i+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(auditLogPath, auditLogPath+".1")
+}
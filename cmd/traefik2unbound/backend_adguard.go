@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatAdguard = "adguard"
+
+var (
+	adguardURL          string
+	adguardUsername     string
+	adguardPassword     string
+	adguardPasswordFile string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatAdguard,
+		description: "syncs the records as AdGuard Home DNS rewrites, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&adguardURL, "adguard-url", "", "URL of AdGuard Home for -format adguard, e.g. \"http://adguard.lan:3000\"")
+			fs.StringVar(&adguardUsername, "adguard-username", os.Getenv("ADGUARD_USERNAME"), "Username of AdGuard Home. Defaults to $ADGUARD_USERNAME")
+			fs.StringVar(&adguardPassword, "adguard-password", os.Getenv("ADGUARD_PASSWORD"), "Password of AdGuard Home. Defaults to $ADGUARD_PASSWORD or the contents of the file of $ADGUARD_PASSWORD_FILE")
+			fs.StringVar(&adguardPasswordFile, "adguard-password-file", "", "Path of a file with -adguard-password, like a Docker secret")
+		},
+		secrets: []secretFlag{{name: "adguard-password", env: "ADGUARD_PASSWORD", file: &adguardPasswordFile}},
+		url:     &adguardURL,
+		validate: func(t *target) {
+			if t.output.URL == "" {
+				fatalf("The %s backend of %s requires -adguard-url", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{cname: true, merge: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Adguard{
+				Client: reload.Adguard{URL: o.URL, Username: adguardUsername, Password: adguardPassword},
+				Debugf: debugf,
+			}
+		},
+	})
+}
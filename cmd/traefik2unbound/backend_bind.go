@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+const formatBind = "bind"
+
+// The settings of the zone file are also used by the coredns backend with -coredns-zone.
+var (
+	bindZone           string
+	bindNameserver     string
+	bindNameserverIP   string
+	bindHostmaster     string
+	namedCheckzonePath string
+	rndcPath           string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatBind,
+		description: "writes a zone file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&bindZone, "bind-zone", "", "Zone of the zone file of -format bind. The hosts outside of it are skipped")
+			fs.StringVar(&bindNameserver, "bind-ns", "", "Nameserver of the NS and SOA records of the zone file. Defaults to ns1 of -bind-zone")
+			fs.StringVar(&bindNameserverIP, "bind-ns-ip", "", "IP of the nameserver, published when it is inside the zone")
+			fs.StringVar(&bindHostmaster, "bind-hostmaster", "", "Email of the SOA record of the zone file, with its @ replaced by a dot. Defaults to hostmaster of -bind-zone")
+			fs.StringVar(&namedCheckzonePath, "named-checkzone", "named-checkzone", "Path of the named-checkzone executable used to check the zone file of -format bind")
+			fs.StringVar(&rndcPath, "rndc", "", "Path of the rndc executable used to reload the zone after every change with -format bind, e.g. \"rndc\". Empty leaves it for BIND to pick up")
+		},
+		zone: &bindZone,
+		validate: func(t *target) {
+			if t.output.Zone == "" {
+				fatalf("The %s backend of %s requires -bind-zone", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{cname: true, ordered: alwaysOrdered},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Bind{
+				Writer:    zoneWriter(o),
+				Checkzone: namedCheckzonePath,
+				Rndc:      rndcPath,
+			}
+		},
+	})
+}
+
+// zoneWriter returns the writer of the zone file of o.
+func zoneWriter(o *outputConfig) output.Bind {
+	return output.Bind{
+		Zone:         o.Zone,
+		Nameserver:   bindNameserver,
+		NameserverIP: bindNameserverIP,
+		Hostmaster:   bindHostmaster,
+		TTL:          ttl,
+		HostTTLs:     ttlOverrides,
+		Warnf:        warnf,
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatBlocky = "blocky"
+
+var blockyURL string
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatBlocky,
+		description: "writes the customDNS mapping of a file of the configuration directory of Blocky",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&blockyURL, "blocky-url", "", "URL of the API of Blocky, e.g. \"http://blocky:4000\", refreshed after every change with -format blocky. Empty leaves Blocky as is")
+		},
+		url:          &blockyURL,
+		capabilities: backendCapabilities{cname: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Blocky{Writer: output.Blocky{Warnf: warnf}, Client: reload.Blocky{URL: o.URL}}
+		},
+	})
+}
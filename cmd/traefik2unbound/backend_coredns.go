@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+const formatCoreDNS = "coredns"
+
+var (
+	corednsZone          string
+	corednsReloadCommand string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatCoreDNS,
+		description: "writes a hosts file or, with -coredns-zone, a zone file for CoreDNS",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&corednsZone, "coredns-zone", "", "Zone of -format coredns. The records are then written as a zone file for the file plugin, like -format bind with its -bind-ns and -bind-hostmaster, instead of a hosts file for the hosts plugin")
+			fs.StringVar(&corednsReloadCommand, "coredns-reload-command", "", "Command run with sh after every change with -format coredns, e.g. \"docker restart coredns\". Empty leaves CoreDNS to pick up the file, which its hosts plugin does every 5 seconds")
+		},
+		zone: &corednsZone,
+		validate: func(t *target) {
+			if t.output.Zone != "" && merge {
+				fatalf("-merge is not supported with the zone file of %s", t.Path)
+			}
+		},
+		capabilities: backendCapabilities{
+			cname: true,
+			merge: true,
+			// The zone files of CoreDNS are like the ones of BIND
+			ordered: func(o *outputConfig) bool {
+				return o.Zone != ""
+			},
+		},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.CoreDNS{
+				Hosts:   output.Hosts{HostsPerLine: hostsPerLine, Warnf: warnf},
+				Zone:    zoneWriter(o),
+				Command: corednsReloadCommand,
+			}
+		},
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+const formatDnsmasq = "dnsmasq"
+
+var dnsmasqPath string
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatDnsmasq,
+		description: "writes address lines and reloads dnsmasq with -reload",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&dnsmasqPath, "dnsmasq", "dnsmasq", "Path of the dnsmasq executable used to check the generated file with -format dnsmasq")
+		},
+		validate: func(t *target) {
+			if reloadStrategy == "" && defaultDnsmasqReload == backend.ReloadNone {
+				warnf("dnsmasq has no service to reload here, so the changes of %s are only applied when it rereads the file. Use -reload to apply them", t.Path)
+			}
+			if reloadStrategy == backend.ReloadSignal {
+				warnf("dnsmasq only rereads its hosts files on SIGHUP, so the address lines of %s are applied when it restarts. Use -reload %s on OpenWrt", t.Path, backend.ReloadInit)
+			}
+		},
+		capabilities: backendCapabilities{
+			cname:            true,
+			merge:            true,
+			reloadStrategies: backend.DnsmasqReloadStrategies,
+		},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Dnsmasq{
+				Writer:    output.Dnsmasq{Warnf: warnf},
+				Dnsmasq:   dnsmasqPath,
+				Reload:    reloadOr(defaultDnsmasqReload),
+				Command:   reloadCommand,
+				Pidfile:   pidfilePath,
+				Container: reloadDockerContainer(),
+			}
+		},
+	})
+}
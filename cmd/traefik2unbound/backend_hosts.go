@@ -0,0 +1,27 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+const formatHosts = "hosts"
+
+// hostsPerLine is also used by the hosts files of the pihole and coredns backends.
+var hostsPerLine int
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatHosts,
+		description: "only writes a hosts file, for the CoreDNS hosts plugin, dnsmasq addn-hosts or Blocky",
+		flags: func(fs *flag.FlagSet) {
+			fs.IntVar(&hostsPerLine, "hosts-per-line", 1, "Number of hosts of the same IP written on a line of the hosts and pihole files")
+		},
+		capabilities: backendCapabilities{merge: true, unprobed: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Hosts{Writer: output.Hosts{HostsPerLine: hostsPerLine, Warnf: warnf}}
+		},
+	})
+}
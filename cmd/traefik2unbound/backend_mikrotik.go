@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatMikrotik = "mikrotik"
+
+var (
+	mikrotikURL          string
+	mikrotikUsername     string
+	mikrotikPassword     string
+	mikrotikPasswordFile string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatMikrotik,
+		description: "syncs the records as the static DNS entries of RouterOS through its REST API, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&mikrotikURL, "mikrotik-url", "", "URL of the REST API of RouterOS 7 for -format mikrotik, e.g. \"https://router.lan\"")
+			fs.StringVar(&mikrotikUsername, "mikrotik-username", os.Getenv("MIKROTIK_USERNAME"), "Username of RouterOS. Defaults to $MIKROTIK_USERNAME")
+			fs.StringVar(&mikrotikPassword, "mikrotik-password", os.Getenv("MIKROTIK_PASSWORD"), "Password of RouterOS. Defaults to $MIKROTIK_PASSWORD or the contents of the file of $MIKROTIK_PASSWORD_FILE")
+			fs.StringVar(&mikrotikPasswordFile, "mikrotik-password-file", "", "Path of a file with -mikrotik-password, like a Docker secret")
+		},
+		secrets: []secretFlag{{name: "mikrotik-password", env: "MIKROTIK_PASSWORD", file: &mikrotikPasswordFile}},
+		url:     &mikrotikURL,
+		validate: func(t *target) {
+			if t.output.URL == "" || mikrotikUsername == "" {
+				fatalf("The %s backend of %s requires -mikrotik-url and -mikrotik-username", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{merge: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.HostOverrides{
+				Writer: output.HostOverrides{Warnf: warnf},
+				Client: reload.Mikrotik{URL: o.URL, Username: mikrotikUsername, Password: mikrotikPassword, TTL: ttl, HTTPClient: firewallHTTPClient},
+				Name:   "RouterOS",
+				Debugf: debugf,
+			}
+		},
+	})
+}
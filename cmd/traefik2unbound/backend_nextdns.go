@@ -0,0 +1,45 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatNextDNS = "nextdns"
+
+var (
+	nextdnsURL        string
+	nextdnsAPIKey     string
+	nextdnsAPIKeyFile string
+	nextdnsProfile    string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatNextDNS,
+		description: "syncs the records as the rewrites of a NextDNS profile, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&nextdnsProfile, "nextdns-profile", "", "Id of the NextDNS profile of -format nextdns, e.g. \"abc123\"")
+			fs.StringVar(&nextdnsAPIKey, "nextdns-api-key", os.Getenv("NEXTDNS_API_KEY"), "API key of the NextDNS account. Defaults to $NEXTDNS_API_KEY or the contents of the file of $NEXTDNS_API_KEY_FILE")
+			fs.StringVar(&nextdnsAPIKeyFile, "nextdns-api-key-file", "", "Path of a file with -nextdns-api-key, like a Docker secret")
+			fs.StringVar(&nextdnsURL, "nextdns-url", reload.DefaultNextDNSURL, "URL of the NextDNS API")
+		},
+		secrets: []secretFlag{{name: "nextdns-api-key", env: "NEXTDNS_API_KEY", file: &nextdnsAPIKeyFile}},
+		url:     &nextdnsURL,
+		validate: func(t *target) {
+			if nextdnsProfile == "" || nextdnsAPIKey == "" {
+				fatalf("The %s backend of %s requires -nextdns-profile and -nextdns-api-key", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{cname: true, merge: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.NextDNS{
+				Client: reload.NextDNS{URL: o.URL, APIKey: nextdnsAPIKey, Profile: nextdnsProfile},
+				Debugf: debugf,
+			}
+		},
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatOpnsense = "opnsense"
+
+var (
+	opnsenseURL        string
+	opnsenseKey        string
+	opnsenseKeyFile    string
+	opnsenseSecret     string
+	opnsenseSecretFile string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatOpnsense,
+		description: "syncs the records as the host overrides of the unbound of OPNsense through its API, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&opnsenseURL, "opnsense-url", "", "URL of OPNsense for -format opnsense, e.g. \"https://opnsense.lan\"")
+			fs.StringVar(&opnsenseKey, "opnsense-key", os.Getenv("OPNSENSE_KEY"), "API key of OPNsense. Defaults to $OPNSENSE_KEY or the contents of the file of $OPNSENSE_KEY_FILE")
+			fs.StringVar(&opnsenseKeyFile, "opnsense-key-file", "", "Path of a file with -opnsense-key, like a Docker secret")
+			fs.StringVar(&opnsenseSecret, "opnsense-secret", os.Getenv("OPNSENSE_SECRET"), "API secret of OPNsense. Defaults to $OPNSENSE_SECRET or the contents of the file of $OPNSENSE_SECRET_FILE")
+			fs.StringVar(&opnsenseSecretFile, "opnsense-secret-file", "", "Path of a file with -opnsense-secret, like a Docker secret")
+		},
+		secrets: []secretFlag{
+			{name: "opnsense-key", env: "OPNSENSE_KEY", file: &opnsenseKeyFile},
+			{name: "opnsense-secret", env: "OPNSENSE_SECRET", file: &opnsenseSecretFile},
+		},
+		url: &opnsenseURL,
+		validate: func(t *target) {
+			if t.output.URL == "" || opnsenseKey == "" || opnsenseSecret == "" {
+				fatalf("The %s backend of %s requires -opnsense-url, -opnsense-key and -opnsense-secret", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{merge: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.HostOverrides{
+				Writer: output.HostOverrides{Warnf: warnf},
+				Client: reload.Opnsense{URL: o.URL, Key: opnsenseKey, Secret: opnsenseSecret, HTTPClient: firewallHTTPClient},
+				Name:   "OPNsense",
+				Debugf: debugf,
+			}
+		},
+	})
+}
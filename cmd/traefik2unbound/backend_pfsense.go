@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatPfsense = "pfsense"
+
+var (
+	pfsenseURL     string
+	pfsenseKey     string
+	pfsenseKeyFile string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatPfsense,
+		description: "syncs the records as the host overrides of the unbound of pfSense through its REST API package, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&pfsenseURL, "pfsense-url", "", "URL of pfSense with the REST API package for -format pfsense, e.g. \"https://pfsense.lan\"")
+			fs.StringVar(&pfsenseKey, "pfsense-key", os.Getenv("PFSENSE_KEY"), "API key of the pfSense REST API. Defaults to $PFSENSE_KEY or the contents of the file of $PFSENSE_KEY_FILE")
+			fs.StringVar(&pfsenseKeyFile, "pfsense-key-file", "", "Path of a file with -pfsense-key, like a Docker secret")
+		},
+		secrets: []secretFlag{{name: "pfsense-key", env: "PFSENSE_KEY", file: &pfsenseKeyFile}},
+		url:     &pfsenseURL,
+		validate: func(t *target) {
+			if t.output.URL == "" || pfsenseKey == "" {
+				fatalf("The %s backend of %s requires -pfsense-url and -pfsense-key", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{merge: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.HostOverrides{
+				Writer: output.HostOverrides{Warnf: warnf},
+				Client: reload.Pfsense{URL: o.URL, Key: pfsenseKey, HTTPClient: firewallHTTPClient},
+				Name:   "pfSense",
+				Debugf: debugf,
+			}
+		},
+	})
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+const formatPihole = "pihole"
+
+var piholePath string
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatPihole,
+		description: "writes a hosts file like /etc/pihole/custom.list and reloads the Pi-hole DNS",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&piholePath, "pihole", "pihole", "Path of the pihole executable used to reload the DNS with -format pihole")
+		},
+		capabilities: backendCapabilities{merge: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Pihole{Hosts: backend.Hosts{Writer: output.Hosts{HostsPerLine: hostsPerLine, Warnf: warnf}}, Pihole: piholePath}
+		},
+	})
+}
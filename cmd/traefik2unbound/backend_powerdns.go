@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatPowerDNS = "powerdns"
+
+var (
+	powerdnsURL        string
+	powerdnsAPIKey     string
+	powerdnsAPIKeyFile string
+	powerdnsServer     string
+	powerdnsZone       string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatPowerDNS,
+		description: "replaces the RRsets of the hosts in a zone of the PowerDNS Authoritative Server through its API, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&powerdnsURL, "powerdns-url", "", "URL of the API of the PowerDNS Authoritative Server for -format powerdns, e.g. \"http://pdns.lan:8081\"")
+			fs.StringVar(&powerdnsAPIKey, "powerdns-api-key", os.Getenv("POWERDNS_API_KEY"), "API key of PowerDNS. Defaults to $POWERDNS_API_KEY or the contents of the file of $POWERDNS_API_KEY_FILE")
+			fs.StringVar(&powerdnsAPIKeyFile, "powerdns-api-key-file", "", "Path of a file with -powerdns-api-key, like a Docker secret")
+			fs.StringVar(&powerdnsServer, "powerdns-server", "localhost", "Id of the server of the zone in the API of PowerDNS")
+			fs.StringVar(&powerdnsZone, "powerdns-zone", "", "Zone of PowerDNS updated with -format powerdns. The hosts outside of it are skipped")
+		},
+		secrets: []secretFlag{{name: "powerdns-api-key", env: "POWERDNS_API_KEY", file: &powerdnsAPIKeyFile}},
+		url:     &powerdnsURL,
+		zone:    &powerdnsZone,
+		validate: func(t *target) {
+			if t.output.URL == "" || powerdnsAPIKey == "" || t.output.Zone == "" {
+				fatalf("The %s backend of %s requires -powerdns-url, -powerdns-api-key and -powerdns-zone", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{cname: true, merge: true, txtRegistry: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.PowerDNS{
+				Writer: output.RFC2136{Zone: o.Zone, TTL: ttl, HostTTLs: ttlOverrides, Warnf: warnf},
+				Client: reload.PowerDNS{URL: o.URL, APIKey: powerdnsAPIKey, Server: powerdnsServer, Zone: o.Zone, Owner: registryOwner(), Warnf: warnf},
+				Debugf: debugf,
+			}
+		},
+	})
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatRFC2136 = "rfc2136"
+
+var (
+	rfc2136Server  string
+	rfc2136Zone    string
+	rfc2136KeyFile string
+	rfc2136Key     string
+	nsupdatePath   string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatRFC2136,
+		description: "sends the records to a zone with dynamic updates, keeping the managed ones in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&rfc2136Server, "rfc2136-server", "", "Server receiving the dynamic updates of -format rfc2136, with an optional port, e.g. \"ns1.lan:53\"")
+			fs.StringVar(&rfc2136Zone, "rfc2136-zone", "", "Zone updated with -format rfc2136. The hosts outside of it are skipped")
+			fs.StringVar(&rfc2136KeyFile, "rfc2136-key-file", "", "Path of the TSIG key file signing the dynamic updates")
+			fs.StringVar(&rfc2136Key, "rfc2136-tsig", os.Getenv("RFC2136_TSIG"), "TSIG key signing the dynamic updates in the format [hmac:]name:secret, e.g. \"hmac-sha256:traefik:c2VjcmV0\". Defaults to $RFC2136_TSIG or the contents of the file of $RFC2136_TSIG_FILE")
+			fs.StringVar(&nsupdatePath, "nsupdate", "nsupdate", "Path of the nsupdate executable used to send the dynamic updates of -format rfc2136")
+		},
+		secrets: []secretFlag{{name: "rfc2136-tsig", env: "RFC2136_TSIG"}},
+		zone:    &rfc2136Zone,
+		validate: func(t *target) {
+			if rfc2136Server == "" || t.output.Zone == "" {
+				fatalf("The %s backend of %s requires -rfc2136-server and -rfc2136-zone", t.Name, t.Path)
+			}
+			if rfc2136KeyFile != "" && rfc2136Key != "" {
+				fatalf("Use either -rfc2136-key-file or -rfc2136-tsig")
+			}
+			if rfc2136Key != "" && strings.Count(rfc2136Key, ":") == 0 {
+				fatalf("Invalid -rfc2136-tsig. Use the format [hmac:]name:secret")
+			}
+		},
+		capabilities: backendCapabilities{cname: true, merge: true, txtRegistry: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.RFC2136{
+				Writer: output.RFC2136{Zone: o.Zone, TTL: ttl, HostTTLs: ttlOverrides, Warnf: warnf},
+				Nsupdate: reload.Nsupdate{
+					Path:    nsupdatePath,
+					Server:  rfc2136Server,
+					Zone:    o.Zone,
+					KeyFile: rfc2136KeyFile,
+					Key:     rfc2136Key,
+					Owner:   registryOwner(),
+				},
+				Debugf: debugf,
+				Warnf:  warnf,
+			}
+		},
+	})
+}
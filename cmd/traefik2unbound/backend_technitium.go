@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatTechnitium = "technitium"
+
+var (
+	technitiumURL       string
+	technitiumToken     string
+	technitiumTokenFile string
+	technitiumZone      string
+	technitiumOwner     string
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatTechnitium,
+		description: "syncs the records of the hosts it owns in a zone of Technitium DNS Server through its API, keeping them in the file",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&technitiumURL, "technitium-url", "", "URL of Technitium DNS Server for -format technitium, e.g. \"http://technitium.lan:5380\"")
+			fs.StringVar(&technitiumToken, "technitium-token", os.Getenv("TECHNITIUM_TOKEN"), "API token of Technitium DNS Server. Defaults to $TECHNITIUM_TOKEN or the contents of the file of $TECHNITIUM_TOKEN_FILE")
+			fs.StringVar(&technitiumTokenFile, "technitium-token-file", "", "Path of a file with -technitium-token, like a Docker secret")
+			fs.StringVar(&technitiumZone, "technitium-zone", "", "Zone of Technitium DNS Server updated with -format technitium. The hosts outside of it are skipped")
+		},
+		secrets: []secretFlag{{name: "technitium-token", env: "TECHNITIUM_TOKEN", file: &technitiumTokenFile}},
+		url:     &technitiumURL,
+		zone:    &technitiumZone,
+		validate: func(t *target) {
+			if t.output.URL == "" || technitiumToken == "" || t.output.Zone == "" {
+				fatalf("The %s backend of %s requires -technitium-url, -technitium-token and -technitium-zone", t.Name, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{merge: true, ownership: true},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.HostOverrides{
+				Writer: output.HostOverrides{Zone: o.Zone, Warnf: warnf},
+				Client: reload.Technitium{URL: o.URL, Token: technitiumToken, Zone: o.Zone, Owner: txtOwner, TTL: ttl, Warnf: warnf},
+				Name:   "Technitium",
+				Debugf: debugf,
+			}
+		},
+	})
+}
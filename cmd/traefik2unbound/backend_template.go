@@ -0,0 +1,41 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+const formatTemplate = "template"
+
+var templatePath string
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatTemplate,
+		description: "renders the records with the Go template of -template",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&templatePath, "template", "", "Path of the Go text/template file of -format template. It is executed with .Header, .Records, every record with its .Host, .Type and .Value, and .Hosts, the records of each host, and can use the join, lower, upper, replace, trimPrefix, trimSuffix, hasPrefix, hasSuffix and isWildcard functions")
+		},
+		setup: func(o *outputConfig) error {
+			if o.Template == "" {
+				o.Template = templatePath
+			}
+			if o.Template == "" {
+				return fmt.Errorf("the %s backend of %s requires -template", formatTemplate, o.Path)
+			}
+			var err error
+			o.template, err = output.ParseTemplate(o.Template)
+			if err != nil {
+				return fmt.Errorf("error parsing the template %s. %s", o.Template, err)
+			}
+			return nil
+		},
+		capabilities: backendCapabilities{cname: true, merge: true, unprobed: true, ordered: alwaysOrdered},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Template{Writer: output.Template{Template: o.template}}
+		},
+	})
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+const formatUnbound = "unbound"
+
+var (
+	unboundCheckconfPath string
+	unboundConfPath      string
+	checkconfInclude     bool
+	unboundControlPath   string
+	unboundViews         unboundViewList
+	unboundViewFirst     bool
+	unboundLocalZones    = localZones{}
+	domainInsecure       bool
+)
+
+func init() {
+	registerBackend(&backendFactory{
+		name:        formatUnbound,
+		description: "writes local-data lines",
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&unboundCheckconfPath, "c", "unbound-checkconf", "Path of the unbound-checkconf executable")
+			fs.StringVar(&unboundConfPath, "unbound-conf", "", "Path of the unbound.conf including the file, checked with unbound-checkconf. Defaults to the one of unbound-checkconf")
+			fs.BoolVar(&checkconfInclude, "checkconf-include", false, "Check only the file with unbound-checkconf, included by a minimal configuration, instead of the whole unbound configuration")
+			fs.StringVar(&unboundControlPath, "unbound-control", "unbound-control", "Path of the unbound-control executable")
+			fs.Var(unboundLocalZones, "local-zone", "Type of the unbound local-zone of a domain in the format domain=type, e.g. \"lan=static\" to answer the names of lan without records with NXDOMAIN or \"example.com=typetransparent\" to resolve the rest of the types of its hosts upstream. Can be given several times. The zones are written before the local-data of the file")
+			fs.BoolVar(&domainInsecure, "domain-insecure", false, "Write a domain-insecure for the parent domain of every host, e.g. example.com for app.example.com, so a DNSSEC validating unbound accepts the records overriding public domains")
+			fs.Var(&unboundViews, "unbound-view", "Unbound view the records are written in instead of the server clause, in the format name or name=subnet,subnet to also bind the clients of the subnets to it with access-control-view, e.g. \"lan=192.168.1.0/24\". Can be given several times. The view must not be defined elsewhere, and the file must be included at the top level or in the server clause")
+			fs.BoolVar(&unboundViewFirst, "unbound-view-first", false, "Answer the names without records in -unbound-view with the local data of the server clause, with view-first")
+		},
+		setup: func(o *outputConfig) error {
+			o.views = unboundViews
+			if o.Views == nil {
+				return nil
+			}
+			o.views = nil
+			for _, value := range o.Views {
+				view, err := parseUnboundView(value)
+				if err != nil {
+					return fmt.Errorf("invalid views of %s. %s", o.Path, err)
+				}
+				o.views = append(o.views, view)
+			}
+			return nil
+		},
+		validate: func(t *target) {
+			if unboundConfPath != "" && checkconfInclude {
+				fatalf("Use either -unbound-conf or -checkconf-include")
+			}
+			if len(t.output.views) > 0 && reloadStrategy == backend.ReloadIncremental {
+				fatalf("Reload strategy %s doesn't support the views of %s", backend.ReloadIncremental, t.Path)
+			}
+		},
+		capabilities: backendCapabilities{
+			cname:            true,
+			ptr:              true,
+			localZones:       true,
+			domainInsecure:   true,
+			merge:            true,
+			reloadStrategies: backend.ReloadStrategies,
+		},
+		new: func(o *outputConfig) backend.OutputBackend {
+			return backend.Unbound{
+				Writer: output.Unbound{
+					TTL:            ttl,
+					HostTTLs:       ttlOverrides,
+					PTR:            ptr,
+					PTRNames:       ptrCanonicalNames,
+					WildcardZone:   wildcardZone,
+					LocalZones:     unboundLocalZones,
+					DomainInsecure: domainInsecure,
+					Views:          o.views,
+					ViewFirst:      unboundViewFirst,
+					Comment:        hostComment,
+					Warnf:          warnf,
+				},
+				Checkconf:    unboundCheckconfPath,
+				Config:       unboundConfPath,
+				CheckInclude: checkconfInclude,
+				Control:      reload.UnboundControl{Path: unboundControlPath},
+				Reload:       reloadOr(defaultUnboundReload),
+				Command:      reloadCommand,
+				Pidfile:      pidfilePath,
+				Container:    reloadDockerContainer(),
+				Debugf:       debugf,
+				Errorf:       errorf,
+			}
+		},
+	})
+}
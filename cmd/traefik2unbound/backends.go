@@ -0,0 +1,374 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/reload"
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+// target is a generated file and the output backend that feeds it to its DNS server, with the
+// output it was set up from.
+type target struct {
+	*syncer.Target
+	output *outputConfig
+}
+
+// outputConfig is an output of the outputs list of the config file: a backend, its file and
+// the settings overriding the flags of the backend, so several outputs can use the same one.
+type outputConfig struct {
+	Backend string `yaml:"backend"`
+	Path    string `yaml:"path"`
+	// URL of the API of the adguard, nextdns, opnsense, pfsense, mikrotik, blocky, technitium and
+	// powerdns backends.
+	URL string `yaml:"url"`
+	// Zone of the bind, rfc2136, technitium and powerdns backends, and of the zone file of the coredns one.
+	Zone string `yaml:"zone"`
+	// Template of the template backend.
+	Template string `yaml:"template"`
+	// Probe is the address of the DNS server queried after applying the records.
+	Probe string `yaml:"probe"`
+	// AddressSource is where the IPs of the records come from, the Traefik hosts or their
+	// Tailscale nodes.
+	AddressSource string `yaml:"address-source"`
+	// IPFamily narrows the records to the A or AAAA ones of -ip-family.
+	IPFamily string `yaml:"ip-family"`
+	// IP replaces the IPs of every record, and IPMap the ones of its keys, so outputs for
+	// different views of the network point the same hosts to different IPs.
+	IP    string            `yaml:"ip"`
+	IPMap map[string]string `yaml:"ip-map"`
+	// Views are the unbound views of the unbound backend, in the format of -unbound-view.
+	Views []string `yaml:"views"`
+
+	template *template.Template
+	ip       net.IP
+	ipMap    map[string]net.IP
+	views    []output.UnboundView
+}
+
+var configOutputs []*outputConfig
+
+// setDefaults fills the settings of o it doesn't set with the flags of its backend, and
+// prepares the ones of its factory, like its template.
+func (o *outputConfig) setDefaults(factory *backendFactory) error {
+	if o.URL == "" && factory.url != nil {
+		o.URL = *factory.url
+	}
+	if o.Zone == "" && factory.zone != nil {
+		o.Zone = *factory.zone
+	}
+	if o.Probe == "" && !factory.capabilities.unprobed {
+		o.Probe = probeAddress
+	}
+	if o.AddressSource == "" {
+		o.AddressSource = addressSource
+	}
+	if o.AddressSource != syncer.AddressSourceHost && o.AddressSource != syncer.AddressSourceTailscale {
+		return fmt.Errorf("unknown address source %s of %s. Use %s or %s", o.AddressSource, o.Path, syncer.AddressSourceHost, syncer.AddressSourceTailscale)
+	}
+	if o.IPFamily == "" {
+		o.IPFamily = ipFamily
+	}
+	if o.IPFamily != syncer.IPFamilyV4 && o.IPFamily != syncer.IPFamilyV6 && o.IPFamily != syncer.IPFamilyBoth {
+		return fmt.Errorf("unknown IP family %s of %s. Use %s, %s or %s", o.IPFamily, o.Path, syncer.IPFamilyV4, syncer.IPFamilyV6, syncer.IPFamilyBoth)
+	}
+	err := o.parseIPs()
+	if err != nil {
+		return err
+	}
+	if factory.setup == nil {
+		return nil
+	}
+	return factory.setup(o)
+}
+
+// parseIPs parses the ip and ip-map settings of o.
+func (o *outputConfig) parseIPs() error {
+	if o.IP != "" {
+		if o.AddressSource == syncer.AddressSourceTailscale {
+			return fmt.Errorf("ip and address-source %s are mutually exclusive for %s", syncer.AddressSourceTailscale, o.Path)
+		}
+		o.ip = parseOutputIP(o.IP)
+		if o.ip == nil {
+			return fmt.Errorf("invalid ip %s of %s", o.IP, o.Path)
+		}
+	}
+	o.ipMap = map[string]net.IP{}
+	for from, to := range o.IPMap {
+		fromIP := parseOutputIP(from)
+		toIP := parseOutputIP(to)
+		if fromIP == nil || toIP == nil {
+			return fmt.Errorf("invalid ip-map %s: %s of %s", from, to, o.Path)
+		}
+		o.ipMap[fromIP.String()] = toIP
+	}
+	return nil
+}
+
+func parseOutputIP(value string) net.IP {
+	ip := net.ParseIP(value)
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+	return ip
+}
+
+// backendList is a flag that can be given several times in the format name or name=path.
+type backendList []string
+
+func (b *backendList) Set(value string) error {
+	*b = append(*b, value)
+	return nil
+}
+
+func (b *backendList) String() string {
+	return strings.Join(*b, ",")
+}
+
+// backendFactory is an output backend that can be selected with -backend or -format. Every
+// backend registers its factory in the init of its file, with its flags and what it supports,
+// so adding one doesn't change the checks of the rest.
+type backendFactory struct {
+	name string
+	// description completes the sentence of the -format help starting with the quoted name.
+	description string
+	// flags registers the flags of the backend.
+	flags func(fs *flag.FlagSet)
+	// secrets are the flags of flags with credentials, read like the ones of secretFlags.
+	secrets []secretFlag
+	// url and zone are the flags the url and zone of the outputs of the config file default to.
+	url  *string
+	zone *string
+	// setup prepares the settings of the output o of the backend, after its defaults are set.
+	setup func(o *outputConfig) error
+	// validate fails on the settings of t the backend requires and are missing.
+	validate     func(t *target)
+	capabilities backendCapabilities
+	new          func(o *outputConfig) backend.OutputBackend
+}
+
+// backendCapabilities are the flags and features a backend supports, checked by validateOutput
+// and validateTarget.
+type backendCapabilities struct {
+	// cname writes the CNAME records of -cname.
+	cname bool
+	// ptr, localZones and domainInsecure write the records of -ptr, -local-zone and
+	// -domain-insecure.
+	ptr            bool
+	localZones     bool
+	domainInsecure bool
+	// merge keeps the rest of the file with -merge.
+	merge bool
+	// txtRegistry only changes the hosts it owns with -txt-registry, and ownership always does,
+	// both with the TXT records of -txt-owner.
+	txtRegistry bool
+	ownership   bool
+	// reloadStrategies are the values of -reload the backend applies its file with, none when it
+	// ignores it.
+	reloadStrategies []string
+	// unprobed backends only write a file and don't feed a DNS server, so -probe doesn't apply.
+	unprobed bool
+	// ordered reports whether the file of o is compared byte for byte, since the order of its
+	// lines matters, like the SOA record of a zone file or a template.
+	ordered func(o *outputConfig) bool
+}
+
+// backendFactories are the registered output backends, by name.
+var backendFactories = map[string]*backendFactory{}
+
+func registerBackend(factory *backendFactory) {
+	backendFactories[factory.name] = factory
+}
+
+func alwaysOrdered(o *outputConfig) bool {
+	return true
+}
+
+// sortedBackendFactories returns the registered backends sorted by name, unbound first as the
+// default.
+func sortedBackendFactories() []*backendFactory {
+	factories := make([]*backendFactory, 0, len(backendFactories))
+	for _, name := range backendNames() {
+		factories = append(factories, backendFactories[name])
+	}
+	sort.SliceStable(factories, func(i, j int) bool {
+		return factories[i].name == formatUnbound && factories[j].name != formatUnbound
+	})
+	return factories
+}
+
+// registerBackendFlags registers the flags of every backend.
+func registerBackendFlags(fs *flag.FlagSet) {
+	for _, factory := range sortedBackendFactories() {
+		factory.flags(fs)
+	}
+}
+
+// formatUsage returns the help of -format, with the description of every backend.
+func formatUsage() string {
+	descriptions := []string{}
+	for _, factory := range sortedBackendFactories() {
+		descriptions = append(descriptions, fmt.Sprintf("\"%s\" %s.", factory.name, factory.description))
+	}
+	return "Format of the generated file. " + strings.Join(descriptions, " ")
+}
+
+// backendsWith returns the registered backends with the capability of has, as the backend or
+// the backends of the messages of the flags they support.
+func backendsWith(has func(c backendCapabilities) bool) string {
+	names := []string{}
+	for _, name := range backendNames() {
+		if has(backendFactories[name].capabilities) {
+			names = append(names, name)
+		}
+	}
+	switch len(names) {
+	case 0:
+		return "no backend"
+	case 1:
+		return "the " + names[0] + " backend"
+	}
+	return "the " + strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1] + " backends"
+}
+
+// hasCapability reports whether any target uses a backend with the capability of has.
+func hasCapability(has func(c backendCapabilities) bool) bool {
+	for _, t := range targets {
+		if has(backendFactories[t.Name].capabilities) {
+			return true
+		}
+	}
+	return false
+}
+
+// entriesHaveCapability reports whether any of the outputs of entries uses a backend with the
+// capability of has, before their targets are created.
+func entriesHaveCapability(entries []*outputConfig, has func(c backendCapabilities) bool) bool {
+	for _, o := range entries {
+		if factory, ok := backendFactories[o.Backend]; ok && has(factory.capabilities) {
+			return true
+		}
+	}
+	return false
+}
+
+// reloadDockerContainer returns the container of -reload docker.
+func reloadDockerContainer() reload.DockerContainer {
+	return reload.DockerContainer{URL: "http://docker", HTTPClient: newDockerClient(dockerSocket), Name: reloadContainer, Signal: reloadContainerSignal}
+}
+
+var targets []*target
+
+// registryOwner returns the owner of the TXT registry of the rfc2136 and powerdns backends,
+// empty without -txt-registry.
+func registryOwner() string {
+	if !txtRegistry {
+		return ""
+	}
+	return txtOwner
+}
+
+// outputEntries returns the outputs of -backend and of the config file or, without them, the
+// one of -format written to -p.
+func outputEntries() []*outputConfig {
+	entries := make([]*outputConfig, 0, len(backends)+len(configOutputs))
+	for _, entry := range backends {
+		name, path, found := strings.Cut(entry, "=")
+		if !found {
+			path = traefikServicesFilePath
+		}
+		entries = append(entries, &outputConfig{Backend: name, Path: path})
+	}
+	entries = append(entries, configOutputs...)
+	if len(entries) == 0 {
+		entries = append(entries, &outputConfig{Backend: outputFormat, Path: traefikServicesFilePath})
+	}
+	return entries
+}
+
+// setupTargets creates the targets of the outputs of entries.
+func setupTargets(entries []*outputConfig) error {
+	all := make([]*target, 0, len(entries))
+	paths := map[string]bool{}
+	for _, o := range entries {
+		factory, ok := backendFactories[o.Backend]
+		if !ok {
+			return fmt.Errorf("unknown backend %s. Use %s", o.Backend, strings.Join(backendNames(), ", "))
+		}
+		if o.Path == "" {
+			return fmt.Errorf("backend %s needs the path of its file", o.Backend)
+		}
+		if paths[o.Path] {
+			return fmt.Errorf("several backends write %s", o.Path)
+		}
+		paths[o.Path] = true
+		err := o.setDefaults(factory)
+		if err != nil {
+			return err
+		}
+		ordered := factory.capabilities.ordered != nil && factory.capabilities.ordered(o)
+		all = append(all, &target{
+			Target: &syncer.Target{
+				Name:          o.Backend,
+				Path:          o.Path,
+				Backend:       factory.new(o),
+				Ordered:       ordered,
+				Probe:         o.Probe,
+				Zone:          o.Zone,
+				AddressSource: o.AddressSource,
+				IPFamily:      o.IPFamily,
+				IP:            o.ip,
+				IPMap:         o.ipMap,
+			},
+			output: o,
+		})
+	}
+	targets = all
+	return nil
+}
+
+func backendNames() []string {
+	names := make([]string, 0, len(backendFactories))
+	for name := range backendFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// targetForPath returns the target that writes path.
+func targetForPath(path string) (*target, error) {
+	for _, t := range targets {
+		if t.Path == path {
+			return t, nil
+		}
+	}
+	return nil, errors.New("no backend writes " + path)
+}
+
+// checkIfOutputIsValid verifies the file of t with the checker of its backend.
+func checkIfOutputIsValid(t *target) bool {
+	err := engine.Verify(t.Target)
+	if err != nil {
+		errorf("Error checking configuration of %s. %s", t.Path, err)
+		return false
+	}
+	return true
+}
+
+// reloadOr returns -reload, or defaultReload, the reload strategy of a backend, when it isn't
+// set.
+func reloadOr(defaultReload string) string {
+	if reloadStrategy == "" {
+		return defaultReload
+	}
+	return reloadStrategy
+}
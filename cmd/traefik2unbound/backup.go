@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+// runRollback lists the backup generations of the file or, given a generation number with
+// 1 being the newest, restores it, checks it and applies it with the backend that writes it.
+func runRollback(args []string) {
+	generations, err := syncer.BackupGenerations(traefikServicesFilePath)
+	if err != nil {
+		fatalf("Error listing the backups of %s. %s", traefikServicesFilePath, err)
+	}
+
+	if len(args) == 0 {
+		if len(generations) == 0 {
+			fmt.Printf("No backups of %s\n", traefikServicesFilePath)
+		}
+		for i, generation := range generations {
+			fmt.Printf("%d\t%s\n", i+1, generation)
+		}
+		return
+	}
+
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < 1 || n > len(generations) {
+		fatalf("Invalid generation %s. Run rollback without arguments to list the %d backups", args[0], len(generations))
+	}
+	generation := generations[n-1]
+	t, err := targetForPath(traefikServicesFilePath)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	oldContents, err := os.ReadFile(traefikServicesFilePath)
+	if err != nil {
+		fatalf("Error reading %s. %s", traefikServicesFilePath, err)
+	}
+	newContents, err := os.ReadFile(generation)
+	if err != nil {
+		fatalf("Error reading %s. %s", generation, err)
+	}
+
+	err = engine.Backup(traefikServicesFilePath)
+	if err != nil {
+		fatalf("Error backing up %s. %s", traefikServicesFilePath, err)
+	}
+	err = syncer.WriteFile(traefikServicesFilePath, string(newContents))
+	if err != nil {
+		fatalf("Error restoring %s. %s", generation, err)
+	}
+	if !checkIfOutputIsValid(t) {
+		err = syncer.Restore(traefikServicesFilePath)
+		if err != nil {
+			fatalf("Error restoring backup %s. %s", traefikServicesFilePath, err)
+		}
+		fatalf("The backup %s is not valid, keeping the current file", generation)
+	}
+	err = engine.Apply(t.Target, string(oldContents), string(newContents))
+	if err != nil {
+		fatalf("%s", err)
+	}
+	infof("Restored %s from %s", traefikServicesFilePath, generation)
+}
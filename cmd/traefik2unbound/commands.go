@@ -0,0 +1,408 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/notify"
+	"github.com/dcasado/traefik2unbound/reload"
+	"github.com/dcasado/traefik2unbound/syncer"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// command is a subcommand of the CLI with the flags it accepts.
+type command struct {
+	name        string
+	description string
+	flags       []func(fs *flag.FlagSet)
+	run         func(args []string)
+}
+
+var commands = []command{
+	{
+		name:        "sync",
+		description: "Sync the hosts of the Traefik routers once",
+		flags:       []func(fs *flag.FlagSet){registerSourceFlags, registerOutputFlags, registerRenamedFlags},
+		run:         runSync,
+	},
+	{
+		name:        "daemon",
+		description: "Keep running and sync the hosts every -interval. Supports systemd Type=notify services, with a WatchdogSec longer than the slowest sync",
+		flags:       []func(fs *flag.FlagSet){registerSourceFlags, registerOutputFlags, registerDaemonFlags, registerRenamedFlags},
+		run:         runDaemonCommand,
+	},
+	{
+		name:        "diff",
+		description: "Print a diff between the current file and the one that would be written, without touching the DNS server. Also available as dry-run",
+		flags:       []func(fs *flag.FlagSet){registerSourceFlags, registerOutputFlags, registerRenamedFlags},
+		run:         runDiff,
+	},
+	{
+		name:        "validate",
+		description: "Check the current file with unbound-checkconf, or the checker of -format, without syncing",
+		flags:       []func(fs *flag.FlagSet){registerOutputFlags, registerRenamedFlags},
+		run:         runValidate,
+	},
+	{
+		name:        "rollback",
+		description: "List the backups of the file or restore the given generation, 1 being the newest",
+		flags:       []func(fs *flag.FlagSet){registerOutputFlags, registerRenamedFlags},
+		run: func(args []string) {
+			validateOutput()
+			setupEngine()
+			runRollback(args)
+		},
+	},
+	{
+		name:        "export",
+		description: "Print the records of the Traefik instances by host as JSON, with the instance and routers they come from, without writing any file",
+		flags:       []func(fs *flag.FlagSet){registerSourceFlags},
+		run:         runExport,
+	},
+	{
+		name:        "status",
+		description: "Print the state of the last successful sync saved in -state-file",
+		flags:       []func(fs *flag.FlagSet){registerOutputFlags, registerRenamedFlags},
+		run:         runStatus,
+	},
+}
+
+var commandAliases = map[string]string{
+	"dry-run": "diff",
+}
+
+// lookupCommand returns the command with the given name or alias.
+func lookupCommand(name string) (command, bool) {
+	if alias, ok := commandAliases[name]; ok {
+		name = alias
+	}
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+// splitCommand returns the name of the command args start with, empty without one, and the
+// rest of args.
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
+	return "", args
+}
+
+// newFlagSet returns the flag set of c, or of every mode when the command line has no command,
+// registering its flags and so setting them to their defaults. Without a command the usage is
+// left to the caller, as it lists the commands.
+func newFlagSet(c command, hasCommand bool, errorHandling flag.ErrorHandling) *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], errorHandling)
+	registerLogFlags(fs)
+	if hasCommand {
+		for _, register := range c.flags {
+			register(fs)
+		}
+		fs.Usage = func() {
+			fmt.Fprintf(fs.Output(), "Usage of %s %s:\n%s\n\n", os.Args[0], c.name, c.description)
+			fs.PrintDefaults()
+		}
+		return fs
+	}
+	registerSourceFlags(fs)
+	registerOutputFlags(fs)
+	registerDaemonFlags(fs)
+	registerLegacyFlags(fs)
+	return fs
+}
+
+// allFlags returns a flag set with the flags of every command, used to accept config files
+// shared by several commands. It must be called before the flags of the command are
+// registered, as registering a flag sets its default value.
+func allFlags() *flag.FlagSet {
+	fs := flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+	registerLogFlags(fs)
+	registerSourceFlags(fs)
+	registerOutputFlags(fs)
+	registerDaemonFlags(fs)
+	registerLegacyFlags(fs)
+	return fs
+}
+
+// activeCommand is the command of the command line when hasCommand is set.
+var (
+	activeCommand command
+	hasCommand    bool
+)
+
+func main() {
+	known := allFlags()
+
+	name, args := splitCommand(os.Args[1:])
+	c, ok := command{}, false
+	if name != "" {
+		c, ok = lookupCommand(name)
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Unknown command %s\n\n", name)
+			printUsage()
+			os.Exit(2)
+		}
+	}
+	activeCommand, hasCommand = c, ok
+
+	fs := newFlagSet(c, ok, flag.ExitOnError)
+	if !ok {
+		fs.Usage = printUsage
+	}
+	// Parse can't fail with ExitOnError
+	_ = fs.Parse(args)
+
+	if configFilePath != "" {
+		err := loadConfig(configFilePath, fs, known)
+		if err != nil {
+			fatalf("Error loading config file %s. %s", configFilePath, err)
+		}
+	}
+	err := loadSecrets(fs)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if debug {
+		logLevelName = logLevelNames[levelDebug]
+	}
+	err = configureLogging(logLevelName, logFormat)
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if os.Getenv(checkConfigEnv) != "" {
+		setupSources()
+		validateOutput()
+		setupEngine()
+		os.Exit(0)
+	}
+
+	if ok {
+		c.run(fs.Args())
+		return
+	}
+
+	// Without a command the mode is selected with -status, -dry-run and -daemon
+	switch {
+	case showStatus:
+		runStatus(fs.Args())
+	case dryRun:
+		runDiff(fs.Args())
+	case daemon:
+		runDaemonCommand(fs.Args())
+	default:
+		runSync(fs.Args())
+	}
+}
+
+func printUsage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [command] [flags]\n\nCommands:\n", os.Args[0])
+	for _, c := range commands {
+		fmt.Fprintf(out, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintf(out, "\nRun %s <command> -h for the flags of a command. Without a command it syncs once, or runs as selected with -daemon, -dry-run or -status\n", os.Args[0])
+}
+
+func runSync(args []string) {
+	setupSources()
+	validateOutput()
+	setupEngine()
+	changed, err := engine.Sync()
+	if metricsTextfilePath != "" && !dryRun {
+		metrics.setSyncResult(err)
+		writeMetricsTextfile(metricsTextfilePath)
+	}
+	if err != nil {
+		exitWithError(err)
+	}
+	if strict && !changed {
+		os.Exit(exitUnchanged)
+	}
+}
+
+func runDaemonCommand(args []string) {
+	setupSources()
+	validateOutput()
+	setupEngine()
+	runDaemon()
+}
+
+func runDiff(args []string) {
+	dryRun = true
+	runSync(args)
+}
+
+func runValidate(args []string) {
+	validateOutput()
+	setupEngine()
+	valid := true
+	for _, t := range targets {
+		if !checkIfOutputIsValid(t) {
+			valid = false
+			continue
+		}
+		infof("%s is valid", t.Path)
+	}
+	if !valid {
+		os.Exit(1)
+	}
+}
+
+func runStatus(args []string) {
+	if stateFilePath == "" {
+		fatalf("status requires -state-file")
+	}
+	printStatus(stateFilePath)
+}
+
+// setupSources reads the Traefik URLs, sets up the instances and checks the flags of the records.
+func setupSources() {
+	if traefikURLsFilePath != "" {
+		err := traefikURLs.readURLsFile(traefikURLsFilePath)
+		if err != nil {
+			fatalf("Error reading Traefik URLs from %s. %s", traefikURLsFilePath, err)
+		}
+	}
+
+	if resolverAddress != "" {
+		traefikResolver = syncer.NewResolver(resolverAddress)
+	}
+	err := setupInstances()
+	if err != nil {
+		fatalf("%s", err)
+	}
+
+	if ipFamily != syncer.IPFamilyV4 && ipFamily != syncer.IPFamilyV6 && ipFamily != syncer.IPFamilyBoth {
+		fatalf("Unknown IP family %s. Use %s, %s or %s", ipFamily, syncer.IPFamilyV4, syncer.IPFamilyV6, syncer.IPFamilyBoth)
+	}
+	if ipSelection != syncer.IPSelectionFirst && ipSelection != syncer.IPSelectionAll && ipSelection != syncer.IPSelectionPrivate {
+		_, _, err := net.ParseCIDR(ipSelection)
+		if err != nil {
+			fatalf("Unknown IP selection %s. Use %s, %s, %s or a CIDR", ipSelection, syncer.IPSelectionFirst, syncer.IPSelectionAll, syncer.IPSelectionPrivate)
+		}
+	}
+	if hostRegexpMode != syncer.HostRegexpIgnore && hostRegexpMode != syncer.HostRegexpWildcard && hostRegexpMode != syncer.HostRegexpExpand {
+		fatalf("Unknown -host-regexp mode %s. Use %s, %s or %s", hostRegexpMode, syncer.HostRegexpIgnore, syncer.HostRegexpWildcard, syncer.HostRegexpExpand)
+	}
+	if markerMode != syncer.MarkerOptOut && markerMode != syncer.MarkerOptIn {
+		fatalf("Unknown -marker-mode %s. Use %s or %s", markerMode, syncer.MarkerOptOut, syncer.MarkerOptIn)
+	}
+	if outsideZones != syncer.OutsideZonesSkip && outsideZones != syncer.OutsideZonesInclude {
+		fatalf("Unknown -outside-zones policy %s. Use %s or %s", outsideZones, syncer.OutsideZonesSkip, syncer.OutsideZonesInclude)
+	}
+	if conflictPolicy != syncer.ConflictEmitAll && conflictPolicy != syncer.ConflictFirstWins && conflictPolicy != syncer.ConflictLastWins && conflictPolicy != syncer.ConflictFail {
+		fatalf("Unknown -on-conflict policy %s. Use %s, %s, %s or %s", conflictPolicy, syncer.ConflictEmitAll, syncer.ConflictFirstWins, syncer.ConflictLastWins, syncer.ConflictFail)
+	}
+	if fetchErrorPolicy != syncer.FetchErrorDrop && fetchErrorPolicy != syncer.FetchErrorKeep {
+		fatalf("Unknown -on-fetch-error policy %s. Use %s or %s", fetchErrorPolicy, syncer.FetchErrorDrop, syncer.FetchErrorKeep)
+	}
+	if fetchErrorPolicy == syncer.FetchErrorKeep && stateFilePath == "" {
+		fatalf("-on-fetch-error %s requires -state-file", syncer.FetchErrorKeep)
+	}
+	if maxRemovedPercent < 0 || maxRemovedPercent > 100 {
+		fatalf("-max-removed-percent must be between 0 and 100")
+	}
+	if maxRemoved < 0 {
+		fatalf("-max-removed can't be negative")
+	}
+}
+
+// validateOutput sets up the output backends and checks their flags.
+func validateOutput() {
+	reload.Privileged = strings.Fields(privilegedCommand)
+	if reloadStrategy != "" && !containsString(backend.ReloadStrategies, reloadStrategy) {
+		fatalf("Unknown reload strategy %s. Use %s", reloadStrategy, strings.Join(backend.ReloadStrategies, ", "))
+	}
+	if privilegedCommand != "" && (reloadStrategy == backend.ReloadSystemd || reloadStrategy == backend.ReloadSignal || reloadStrategy == backend.ReloadDocker) {
+		warnf("Reload strategy %s doesn't run a command, so it runs without -privileged-command", reloadStrategy)
+	}
+	if reloadStrategy == backend.ReloadCommand && reloadCommand == "" {
+		fatalf("Reload strategy %s requires -reload-command", backend.ReloadCommand)
+	}
+	if reloadStrategy == backend.ReloadSignal && pidfilePath == "" {
+		fatalf("Reload strategy %s requires -pidfile", backend.ReloadSignal)
+	}
+	if reloadStrategy == backend.ReloadDocker && reloadContainer == "" {
+		fatalf("Reload strategy %s requires -reload-container", backend.ReloadDocker)
+	}
+	var err error
+	firewallHTTPClient, err = traefik.NewHTTPClient(traefik.TLSConfig{CACert: firewallCACert, InsecureSkipVerify: firewallInsecure}, requestTimeout)
+	if err != nil {
+		fatalf("Error creating the HTTP client of the firewall APIs. %s", err)
+	}
+	// The backends capture the owner when they are created
+	if technitiumOwner != "" {
+		warnf("-technitium-owner is deprecated. Use -txt-owner")
+		txtOwner = technitiumOwner
+	}
+	entries := outputEntries()
+	owners := func(c backendCapabilities) bool { return c.ownership }
+	if txtOwner == "" && (txtRegistry || entriesHaveCapability(entries, owners)) {
+		fatalf("-txt-owner can't be empty")
+	}
+	err = setupTargets(entries)
+	if err != nil {
+		fatalf("%s", err)
+	}
+	for _, t := range targets {
+		validateTarget(t)
+	}
+	reloads := func(c backendCapabilities) bool { return len(c.reloadStrategies) > 0 }
+	if reloadStrategy != "" && !hasCapability(reloads) {
+		fatalf("Reload strategy %s is only supported with %s", reloadStrategy, backendsWith(reloads))
+	}
+	for _, event := range notifyEvents {
+		if !containsString(notify.Events, event) {
+			fatalf("Unknown -notify-on event %s. Use %s", event, strings.Join(notify.Events, ", "))
+		}
+	}
+	registries := func(c backendCapabilities) bool { return c.txtRegistry }
+	if txtRegistry && !hasCapability(registries) {
+		fatalf("-txt-registry is only supported with %s", backendsWith(registries))
+	}
+	if cname && txtRegistry {
+		fatalf("-cname is not supported with -txt-registry, as the TXT record of the owner can't share its name with a CNAME")
+	}
+	ptrs := func(c backendCapabilities) bool { return c.ptr }
+	if ptr && !hasCapability(ptrs) {
+		fatalf("-ptr is only supported with %s", backendsWith(ptrs))
+	}
+	zones := func(c backendCapabilities) bool { return c.localZones }
+	if len(unboundLocalZones) > 0 && !hasCapability(zones) {
+		fatalf("-local-zone is only supported with %s", backendsWith(zones))
+	}
+	insecure := func(c backendCapabilities) bool { return c.domainInsecure }
+	if domainInsecure && !hasCapability(insecure) {
+		fatalf("-domain-insecure is only supported with %s", backendsWith(insecure))
+	}
+}
+
+// validateTarget checks the settings of the backend of t, set with flags or in its output of
+// the config file.
+func validateTarget(t *target) {
+	factory := backendFactories[t.Name]
+	if cname && !factory.capabilities.cname {
+		fatalf("-cname is not supported with the %s backend of %s", t.Name, t.Path)
+	}
+	if merge && !factory.capabilities.merge {
+		fatalf("-merge is not supported with the %s backend of %s", t.Name, t.Path)
+	}
+	strategies := factory.capabilities.reloadStrategies
+	if len(strategies) > 0 && reloadStrategy != "" && !containsString(strategies, reloadStrategy) {
+		fatalf("Reload strategy %s is not supported with the %s backend. Use %s", reloadStrategy, t.Name, strings.Join(strategies, ", "))
+	}
+	if factory.validate != nil {
+		factory.validate(t)
+	}
+}
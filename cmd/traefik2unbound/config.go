@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configAliases maps readable config file keys to the short flags they set.
+var configAliases = map[string]string{
+	"urls":      "u",
+	"file":      "p",
+	"checkconf": "c",
+}
+
+// loadConfig reads a YAML file, or a TOML one when it has the .toml extension, whose keys are
+// flag names (or their aliases) and sets every flag that wasn't given on the command line, so
+// flags override the file.
+// The instances key lists Traefik APIs with their own settings and the outputs key the
+// backends with their files and settings. Keys of flags in known but not in flags belong
+// to other commands and are ignored.
+func loadConfig(path string, flags *flag.FlagSet, known *flag.FlagSet) error {
+	settings, err := readConfig(path)
+	if err != nil {
+		return err
+	}
+	err = checkConfigSecrets(path, settings)
+	if err != nil {
+		return err
+	}
+	loadedSettings = settings
+
+	setOnCommandLine := map[string]bool{}
+	flags.Visit(func(f *flag.Flag) {
+		setOnCommandLine[f.Name] = true
+	})
+
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		name := key
+		if alias, ok := configAliases[key]; ok {
+			name = alias
+		}
+		if key == "instances" {
+			err := decodeInstances(settings[key])
+			if err != nil {
+				return fmt.Errorf("invalid instances. %s", err)
+			}
+			continue
+		}
+		if key == "outputs" {
+			err := decodeList(settings[key], &configOutputs)
+			if err != nil {
+				return fmt.Errorf("invalid outputs. %s", err)
+			}
+			continue
+		}
+		if known.Lookup(name) == nil || name == "config" {
+			return fmt.Errorf("unknown setting %s", key)
+		}
+		if flags.Lookup(name) == nil {
+			continue
+		}
+		if setOnCommandLine[name] {
+			continue
+		}
+		values, ok := settings[key].([]interface{})
+		if !ok {
+			values = []interface{}{settings[key]}
+		}
+		// Lists set the flag once per item, so flags that can be repeated get every value
+		for _, v := range values {
+			value, err := configValueString(v)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s. %s", key, err)
+			}
+			err = flags.Set(name, value)
+			if err != nil {
+				return fmt.Errorf("invalid value for %s. %s", key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// readConfig returns the settings of the config file at path, parsed as TOML when it has the
+// .toml extension and as YAML otherwise.
+func readConfig(path string) (map[string]interface{}, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	settings := map[string]interface{}{}
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		err = toml.Unmarshal(contents, &settings)
+	} else {
+		err = yaml.Unmarshal(contents, &settings)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+// decodeInstances decodes the instances list of the config file, the Traefik APIs that need
+// their own settings.
+func decodeInstances(value interface{}) error {
+	return decodeList(value, &configInstances)
+}
+
+// decodeList decodes a list of the config file into list, rejecting the unknown keys.
+func decodeList(value interface{}, list interface{}) error {
+	contents, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	return decoder.Decode(list)
+}
+
+// configValueString converts a YAML or TOML value to the string representation its flag
+// parses.
+func configValueString(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool, int, int64, float64:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("unsupported value %v", v)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// runDaemon syncs the hosts every interval, and after Docker or Kubernetes events or changes of the
+// Traefik IPs when enabled, until the process receives SIGTERM or SIGINT. A sync in progress is
+// always finished before exiting. SIGHUP reloads the configuration.
+func runDaemon() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	serveHTTP(metricsAddress, healthAddress)
+
+	trigger := make(chan string, 1)
+	if dockerEventsEnabled {
+		go watchDockerEvents(dockerSocket, dockerDebounce, debounceMaxWait, trigger)
+	}
+	if kubernetesWatch {
+		watchKubernetesInstances(kubernetesDebounce, trigger)
+	}
+	if resolveInterval > 0 {
+		go watchTraefikIPs(resolveInterval, trigger)
+	}
+
+	// A reload applies a new -interval to the next syncs
+	reload := func() {
+		previous := interval
+		reloadConfig()
+		if interval != previous {
+			infof("Syncing every %s", interval)
+			ticker.Reset(interval)
+		}
+	}
+
+	infof("Running in daemon mode, syncing every %s", interval)
+	go superviseWithSystemd()
+	var lastChange time.Time
+	ready := false
+	for {
+		start := time.Now()
+		startSyncLoop()
+		changed, err := engine.Sync()
+		endSyncLoop()
+		metrics.setSyncResult(err)
+		if metricsTextfilePath != "" {
+			writeMetricsTextfile(metricsTextfilePath)
+		}
+		if err != nil {
+			errorf("Sync failed after %s. %s", time.Since(start), err)
+		}
+		if changed {
+			lastChange = time.Now()
+		}
+		if !ready {
+			// systemd starts the services ordered after this one once the first sync is done
+			ready = true
+			sdNotify("READY=1\nSTATUS=" + syncStatus(time.Time{}))
+		}
+
+		for {
+			select {
+			case <-ticker.C:
+			case source := <-trigger:
+				infof("Syncing after %s events", source)
+			case sig := <-signals:
+				if sig == syscall.SIGHUP {
+					// Syncs right away with the new settings
+					reload()
+					break
+				}
+				infof("Received %s, exiting", sig)
+				sdNotify("STOPPING=1")
+				return
+			}
+			break
+		}
+
+		// Changes right after a reload wait for -min-reload-interval, coalescing the events
+		if wait := minReloadInterval - time.Since(lastChange); !lastChange.IsZero() && wait > 0 {
+			debugf("Waiting %s before syncing, since the last change was applied %s ago", wait.Round(time.Millisecond), time.Since(lastChange).Round(time.Millisecond))
+			select {
+			case <-time.After(wait):
+			case sig := <-signals:
+				if sig != syscall.SIGHUP {
+					infof("Received %s, exiting", sig)
+					sdNotify("STOPPING=1")
+					return
+				}
+				reload()
+			}
+			// The events received meanwhile are part of this sync
+			select {
+			case <-trigger:
+			default:
+			}
+		}
+	}
+}
+
+// debounceEvents sends source on trigger once no event has been received for quiet, so a
+// burst of events like a compose up only causes one sync, or once maxWait has passed since
+// the first event of the burst, so constant churn doesn't delay the sync forever.
+func debounceEvents(events <-chan struct{}, quiet time.Duration, maxWait time.Duration, source string, trigger chan<- string) {
+	timer := time.NewTimer(quiet)
+	timer.Stop()
+	var first time.Time
+	for {
+		select {
+		case <-events:
+			if first.IsZero() {
+				first = time.Now()
+			}
+			wait := quiet
+			if maxWait > 0 && time.Until(first.Add(maxWait)) < wait {
+				wait = time.Until(first.Add(maxWait))
+			}
+			timer.Stop()
+			select {
+			case <-timer.C:
+			default:
+			}
+			timer.Reset(wait)
+		case <-timer.C:
+			first = time.Time{}
+			select {
+			case trigger <- source:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/docker"
+)
+
+// schemeDocker is the scheme of the instance URLs that read the routers from the labels of the
+// containers, like docker:///run/podman/podman.sock=192.168.1.10 or docker://dockerhost:2375.
+const schemeDocker = "docker"
+
+// defaultDockerSocket is the socket of the Docker API used when no other is given.
+const defaultDockerSocket = "/var/run/docker.sock"
+
+// dockerEventsRetry is how long to wait before reconnecting to the Docker socket.
+const dockerEventsRetry = 10 * time.Second
+
+// dockerEvents are the container events that can change the Traefik routers.
+var dockerEvents = []string{"start", "stop", "die", "destroy", "update", "rename"}
+
+// newDockerSource returns the client of the Docker instance with URL u. The instances on the
+// local socket, which has no host to resolve, are published with the IP of the instance.
+func newDockerSource(instance *traefikInstance, u *url.URL) (*docker.Client, error) {
+	exposedByDefault := true
+	if value := u.Query().Get("exposed-by-default"); value != "" {
+		var err error
+		exposedByDefault, err = strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exposed-by-default of %s. %s", instance.URL, err)
+		}
+	}
+
+	if u.Host != "" {
+		return &docker.Client{
+			URL:              "http://" + u.Host,
+			HTTPClient:       &http.Client{Timeout: requestTimeout},
+			ExposedByDefault: exposedByDefault,
+		}, nil
+	}
+	if instance.overrideIP == nil {
+		return nil, fmt.Errorf("the Docker instance %s needs the IP to publish its hosts with, like %s=192.168.1.10", instance.URL, instance.URL)
+	}
+	socket := u.Path
+	if socket == "" {
+		socket = defaultDockerSocket
+	}
+	client := newDockerClient(socket)
+	client.Timeout = requestTimeout
+	return &docker.Client{URL: "http://docker", HTTPClient: client, ExposedByDefault: exposedByDefault}, nil
+}
+
+type dockerEvent struct {
+	Type   string `json:"Type"`
+	Action string `json:"Action"`
+	Actor  struct {
+		ID         string            `json:"ID"`
+		Attributes map[string]string `json:"Attributes"`
+	} `json:"Actor"`
+}
+
+// newDockerClient returns an HTTP client that talks to the Docker API over the unix socket.
+func newDockerClient(socket string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+}
+
+// watchDockerEvents sends on trigger once the container events are debounced with debounce
+// and maxWait. It reconnects when the stream ends.
+func watchDockerEvents(socket string, debounce time.Duration, maxWait time.Duration, trigger chan<- string) {
+	client := newDockerClient(socket)
+	events := make(chan dockerEvent)
+	changes := make(chan struct{})
+
+	go func() {
+		for {
+			err := streamDockerEvents(client, events)
+			errorf("Error watching Docker events on %s, retrying in %s. %s", socket, dockerEventsRetry, err)
+			time.Sleep(dockerEventsRetry)
+		}
+	}()
+	go func() {
+		for event := range events {
+			debugf("Received Docker event %s for container %s", event.Action, event.Actor.Attributes["name"])
+			changes <- struct{}{}
+		}
+	}()
+
+	debounceEvents(changes, debounce, maxWait, "Docker", trigger)
+}
+
+func streamDockerEvents(client *http.Client, events chan<- dockerEvent) error {
+	filters, err := json.Marshal(map[string][]string{"type": {"container"}, "event": dockerEvents})
+	if err != nil {
+		return err
+	}
+	resp, err := client.Get("http://docker/events?filters=" + url.QueryEscape(string(filters)))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("response from Docker not successful. Status: %s", resp.Status)
+	}
+
+	infof("Watching Docker events")
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var event dockerEvent
+		err := decoder.Decode(&event)
+		if err != nil {
+			return err
+		}
+		events <- event
+	}
+}
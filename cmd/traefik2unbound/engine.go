@@ -0,0 +1,126 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/dcasado/traefik2unbound/notify"
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+var (
+	// engine syncs the instances to the targets with the settings of the flags. It is only
+	// replaced by the goroutine of the command, the other goroutines use currentEngine.
+	engine   *syncer.Syncer
+	engineMu sync.Mutex
+)
+
+// setupEngine creates the engine for the instances and targets set up by the command.
+func setupEngine() {
+	e, err := syncer.New(engineOptions())
+	if err != nil {
+		fatalf("%s", err)
+	}
+	setEngine(e)
+}
+
+func setEngine(e *syncer.Syncer) {
+	engineMu.Lock()
+	engine = e
+	engineMu.Unlock()
+}
+
+func currentEngine() *syncer.Syncer {
+	engineMu.Lock()
+	defer engineMu.Unlock()
+	return engine
+}
+
+// engineOptions returns the options of the engine for the instances, targets and flags.
+func engineOptions() syncer.Options {
+	syncTargets := make([]*syncer.Target, 0, len(targets))
+	for _, t := range targets {
+		syncTargets = append(syncTargets, t.Target)
+	}
+	return syncer.Options{
+		Instances: instances,
+		Targets:   syncTargets,
+
+		IPFamily:     ipFamily,
+		IPSelection:  ipSelection,
+		AllowedCIDRs: allowedCIDRs,
+		Resolver:     traefikResolver,
+		CNAME:        cname,
+
+		Include:      includeHosts,
+		Exclude:      excludeHosts,
+		Zones:        internalZones,
+		OutsideZones: outsideZones,
+		HostRegexp:   hostRegexpMode,
+		RegexpHosts:  regexpCandidates,
+
+		Providers:        includeProviders,
+		ExcludeProviders: excludeProviders,
+		EntryPoints:      includeEntryPoints,
+		IncludeDisabled:  includeDisabled,
+		SkipDashboard:    skipDashboard,
+		RouterMarker:     routerMarker,
+		MarkerMiddleware: markerMiddleware,
+		MarkerMode:       markerMode,
+
+		Concurrency:       concurrency,
+		InstanceTimeout:   instanceTimeout,
+		FetchErrorPolicy:  fetchErrorPolicy,
+		MaxStale:          maxStale,
+		ConflictPolicy:    conflictPolicy,
+		RemovalGrace:      removalGrace,
+		MaxRemoved:        maxRemoved,
+		MaxRemovedPercent: maxRemovedPercent,
+		Force:             force,
+		Strict:            strict,
+		SkipEmpty:         skipEmpty,
+
+		Header:       header,
+		Annotate:     annotate,
+		Merge:        merge,
+		DryRun:       dryRun,
+		Backups:      backupRetention,
+		StateFile:    stateFilePath,
+		ChangeReport: changeReportPath,
+		LockFile:     lockFilePath,
+		LockWait:     lockWait,
+
+		ProbeSamples:    probeSampleSize,
+		ProbeTimeout:    probeTimeout,
+		TailscaleSocket: tailscaleSocket,
+		RequestTimeout:  requestTimeout,
+
+		Debugf:   debugf,
+		Infof:    infof,
+		Warnf:    warnf,
+		Errorf:   errorf,
+		Metrics:  metrics,
+		Observer: fileObserver{},
+	}
+}
+
+// hostComment returns the comment of host written by the unbound backend.
+func hostComment(host string) string {
+	return engine.HostComment(host)
+}
+
+// fileObserver notifies -notify and appends to -audit-log the changes of the files.
+type fileObserver struct{}
+
+func (fileObserver) Applied(t *syncer.Target, oldContents string, newContents string, report syncer.ChangeReport) {
+	notifyChange(t, oldContents, newContents)
+	auditChange(t, report, syncer.ResultApplied, nil)
+}
+
+func (fileObserver) Failed(t *syncer.Target, result string, err error, report syncer.ChangeReport) {
+	event := notify.EventReloadFailure
+	if result == syncer.ResultInvalid {
+		event = notify.EventInvalid
+	}
+	notifyFailure(t, event, err)
+	auditChange(t, report, result, err)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"os"
+
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+// Exit statuses of a sync, so cron jobs and systemd units can tell the failures apart. 1 is
+// any other error and 2 an invalid command line.
+const (
+	// exitFetchFailure is returned when -strict aborts a sync since an instance failed.
+	exitFetchFailure = 3
+	// exitValidationFailure is returned when a file fails its check with -strict.
+	exitValidationFailure = 4
+	// exitReloadFailure is returned when a file can't be applied to its DNS server.
+	exitReloadFailure = 5
+	// exitUnchanged is returned with -strict when the sync changed nothing.
+	exitUnchanged = 6
+	// exitLocked is returned when another run holds the lock of the files for longer than
+	// -lock-wait.
+	exitLocked = 7
+)
+
+// exitStatuses are the exit statuses of the kinds of the errors of a sync.
+var exitStatuses = map[syncer.ErrorKind]int{
+	syncer.FetchFailure:      exitFetchFailure,
+	syncer.ValidationFailure: exitValidationFailure,
+	syncer.ReloadFailure:     exitReloadFailure,
+	syncer.Locked:            exitLocked,
+}
+
+// exitWithError logs err and exits with the status of its kind, or 1.
+func exitWithError(err error) {
+	errorf("%s", err)
+	var syncErr syncer.Error
+	if errors.As(err, &syncErr) {
+		if status, ok := exitStatuses[syncErr.Kind]; ok {
+			os.Exit(status)
+		}
+	}
+	os.Exit(1)
+}
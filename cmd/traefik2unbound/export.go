@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+// recordExport is the export of the records of the Traefik instances by host, for other tools.
+type recordExport struct {
+	Timestamp time.Time                   `json:"timestamp"`
+	Hosts     map[string][]exportedRecord `json:"hosts"`
+	Instances []exportedInstance          `json:"instances"`
+}
+
+// exportedRecord is a record of a host with the instance and the routers it comes from.
+type exportedRecord struct {
+	Type     string          `json:"type"`
+	Value    string          `json:"value"`
+	Instance string          `json:"instance"`
+	Routers  []syncer.Origin `json:"routers"`
+}
+
+type exportedInstance struct {
+	URL     string `json:"url"`
+	Records int    `json:"records"`
+	Error   string `json:"error,omitempty"`
+}
+
+// runExport prints the records of every instance as JSON, without writing any file. It fails
+// when no instance could be fetched.
+func runExport(args []string) {
+	setupSources()
+	setupEngine()
+	results := engine.FetchAll()
+	export := recordExport{Timestamp: time.Now(), Hosts: map[string][]exportedRecord{}, Instances: []exportedInstance{}}
+	failed := 0
+	for i, result := range results {
+		instance := exportedInstance{URL: instances[i].URL}
+		if result.Err != nil {
+			errorf("%s", result.Err)
+			instance.Error = result.Err.Error()
+			failed++
+		}
+		records := output.UniqueRecords(result.Records)
+		instance.Records = len(records)
+		export.Instances = append(export.Instances, instance)
+		for _, r := range records {
+			routers := result.Origins[r.Host]
+			if routers == nil {
+				routers = []syncer.Origin{}
+			}
+			export.Hosts[r.Host] = append(export.Hosts[r.Host], exportedRecord{Type: r.Type, Value: r.Value, Instance: instances[i].URL, Routers: routers})
+		}
+	}
+	for _, records := range export.Hosts {
+		sort.SliceStable(records, func(i, j int) bool {
+			if records[i].Type != records[j].Type {
+				return records[i].Type < records[j].Type
+			}
+			return records[i].Value < records[j].Value
+		})
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	err := encoder.Encode(export)
+	if err != nil {
+		fatalf("Error writing the export. %s", err)
+	}
+	if len(results) > 0 && failed == len(results) {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/dcasado/traefik2unbound/fileprovider"
+)
+
+// schemeFile is the scheme of the instance URLs that read the routers from the dynamic
+// configuration or Docker Compose files on disk, like file:///etc/traefik/dynamic=192.168.1.10.
+const schemeFile = "file"
+
+// newFileSource returns the source of the file instance with URL u, which has no host to resolve
+// so its hosts are published with the IP of the instance.
+func newFileSource(instance *traefikInstance, u *url.URL) (*fileprovider.Source, error) {
+	if u.Host != "" && u.Host != "localhost" {
+		return nil, fmt.Errorf("the file instance %s can't have a host", instance.URL)
+	}
+	if instance.overrideIP == nil {
+		return nil, fmt.Errorf("the file instance %s needs the IP to publish its hosts with, like %s=192.168.1.10", instance.URL, instance.URL)
+	}
+	path := u.Path
+	if u.Opaque != "" {
+		// Relative paths like file:dynamic.yml
+		path = u.Opaque
+	}
+	return &fileprovider.Source{Path: path}, nil
+}
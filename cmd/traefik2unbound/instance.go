@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/syncer"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// traefikInstance is a Traefik API to retrieve the routers from. The instances of the config
+// file can have their own connection settings, the rest use the ones given with flags.
+type traefikInstance struct {
+	URL                string `yaml:"url"`
+	IP                 string `yaml:"ip"`
+	Interface          string `yaml:"interface"`
+	CACert             string `yaml:"ca-cert"`
+	InsecureSkipVerify *bool  `yaml:"insecure-skip-verify"`
+	ClientCert         string `yaml:"client-cert"`
+	ClientKey          string `yaml:"client-key"`
+	// Priority publishes the records of the instance for the hosts also on instances of lower
+	// priority, like a primary Traefik with a standby. The instances of -u have priority 0.
+	Priority int `yaml:"priority"`
+	// HTTPRouters, TCPRouters and RawData replace the routers endpoints of the API,
+	// resolved against URL.
+	HTTPRouters string `yaml:"http-routers"`
+	TCPRouters  string `yaml:"tcp-routers"`
+	RawData     string `yaml:"rawdata"`
+
+	overrideIP net.IP
+}
+
+var (
+	configInstances []*traefikInstance
+	instances       []*syncer.Instance
+)
+
+// setupInstances builds the instances from -u, -urls-file and the instances of the config file,
+// filling the settings they don't set with the flags and creating their sources.
+func setupInstances() error {
+	all := make([]*traefikInstance, 0, len(traefikURLs)+len(configInstances))
+	for _, entry := range traefikURLs {
+		traefikURL, ip := splitIPOverride(entry)
+		all = append(all, &traefikInstance{URL: traefikURL, overrideIP: ip})
+	}
+	for _, instance := range configInstances {
+		if instance.URL == "" {
+			return errors.New("every instance of the config file needs a url")
+		}
+		if instance.IP != "" {
+			instance.overrideIP = net.ParseIP(instance.IP)
+			if instance.overrideIP == nil {
+				return fmt.Errorf("invalid ip %s for instance %s", instance.IP, instance.URL)
+			}
+			if instance.overrideIP.To4() != nil {
+				instance.overrideIP = instance.overrideIP.To4()
+			}
+		}
+		if instance.IP != "" && instance.Interface != "" {
+			return fmt.Errorf("ip and interface are mutually exclusive for instance %s", instance.URL)
+		}
+		all = append(all, instance)
+	}
+
+	instances = make([]*syncer.Instance, 0, len(all))
+	for _, instance := range all {
+		if instance.overrideIP == nil && instance.Interface == "" {
+			instance.Interface = targetInterface
+		}
+		u, err := url.Parse(instance.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url %s. %s", instance.URL, err)
+		}
+		var source syncer.RouterSource
+		switch u.Scheme {
+		case schemeKubernetes:
+			source, err = newKubernetesSource(instance, u)
+		case schemeDocker:
+			source, err = newDockerSource(instance, u)
+		case schemeFile:
+			source, err = newFileSource(instance, u)
+		default:
+			source, err = newTraefikSource(instance)
+		}
+		if err != nil {
+			return err
+		}
+		instances = append(instances, &syncer.Instance{
+			URL:       instance.URL,
+			IP:        instance.overrideIP,
+			Interface: instance.Interface,
+			Priority:  instance.Priority,
+			Source:    source,
+		})
+	}
+	return nil
+}
+
+// newTraefikSource returns the client of the Traefik API of instance, filling the connection
+// settings it doesn't set with the flags.
+func newTraefikSource(instance *traefikInstance) (*traefik.Client, error) {
+	if instance.CACert == "" {
+		instance.CACert = caCertPath
+	}
+	if instance.InsecureSkipVerify == nil {
+		instance.InsecureSkipVerify = &insecureSkipVerify
+	}
+	if instance.ClientCert == "" && instance.ClientKey == "" {
+		instance.ClientCert = clientCertPath
+		instance.ClientKey = clientKeyPath
+	}
+	httpClient, err := traefik.NewHTTPClient(traefik.TLSConfig{
+		CACert:             instance.CACert,
+		InsecureSkipVerify: *instance.InsecureSkipVerify,
+		ClientCert:         instance.ClientCert,
+		ClientKey:          instance.ClientKey,
+	}, requestTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("error creating the HTTP client for %s. %s", instance.URL, err)
+	}
+	if traefikResolver != nil {
+		// Connect to the IP the records are published with
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Resolver: traefikResolver}
+		httpClient.Transport.(*http.Transport).DialContext = dialer.DialContext
+	}
+	return &traefik.Client{
+		URL:        instance.URL,
+		HTTPClient: httpClient,
+
+		HTTPRoutersURL: instance.HTTPRouters,
+		TCPRoutersURL:  instance.TCPRouters,
+		RawDataURL:     instance.RawData,
+
+		Token:    traefikToken,
+		Username: traefikUsername,
+		Password: traefikPassword,
+
+		Retries:      retries,
+		RetryBackoff: retryBackoff,
+		OnRetry:      retryLogger(instance.URL),
+		RawData:      rawData,
+	}, nil
+}
+
+// retryLogger returns the OnRetry callback that logs the retries of the requests to traefikURL.
+func retryLogger(traefikURL string) func(err error, delay time.Duration) {
+	return func(err error, delay time.Duration) {
+		warnf("Retrying %s in %s. %s", traefikURL, delay.Round(time.Millisecond), err)
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/kubernetes"
+)
+
+// schemeKubernetes is the scheme of the instance URLs that read the routers from the Kubernetes
+// API, like kubernetes://?namespace=apps&ingress-class=traefik=192.168.1.10.
+const schemeKubernetes = "kubernetes"
+
+// kubernetesWatchRetry is how long to wait before watching the Kubernetes API again after an error.
+const kubernetesWatchRetry = 10 * time.Second
+
+// newKubernetesSource returns the client of the Kubernetes instance with URL u. Its hosts are
+// published with the IP of the instance, usually the one of the LoadBalancer of Traefik.
+func newKubernetesSource(instance *traefikInstance, u *url.URL) (*kubernetes.Client, error) {
+	if instance.overrideIP == nil {
+		return nil, fmt.Errorf("the Kubernetes instance %s needs the IP to publish its hosts with, like %s=192.168.1.10", instance.URL, instance.URL)
+	}
+	query := u.Query()
+	config, err := kubernetesConfig(query.Get("kubeconfig"), query.Get("context"))
+	if err != nil {
+		return nil, fmt.Errorf("error loading the Kubernetes config of %s. %s", instance.URL, err)
+	}
+	return &kubernetes.Client{
+		Config:       config,
+		HTTPClient:   kubernetes.NewHTTPClient(config, requestTimeout),
+		Namespace:    query.Get("namespace"),
+		IngressClass: query.Get("ingress-class"),
+		Warnf:        warnf,
+	}, nil
+}
+
+// kubernetesConfig uses the service account of the pod when running in a cluster without an
+// explicit kubeconfig or context, and the kubeconfig otherwise.
+func kubernetesConfig(kubeconfig string, context string) (kubernetes.Config, error) {
+	if kubeconfig == "" && context == "" && os.Getenv("KUBERNETES_SERVICE_HOST") != "" {
+		return kubernetes.InClusterConfig()
+	}
+	if kubeconfig == "" {
+		kubeconfig = kubernetes.DefaultKubeconfig()
+	}
+	return kubernetes.LoadKubeconfig(kubeconfig, context)
+}
+
+// watchKubernetesInstances watches the resources of every Kubernetes instance, sending on
+// trigger once none has changed for debounce.
+func watchKubernetesInstances(debounce time.Duration, trigger chan<- string) {
+	changes := make(chan struct{}, 1)
+	for _, instance := range instances {
+		client, ok := instance.Source.(*kubernetes.Client)
+		if !ok {
+			continue
+		}
+		go watchKubernetes(instance.URL, client, changes)
+	}
+
+	go debounceEvents(changes, debounce, debounceMaxWait, "Kubernetes", trigger)
+}
+
+// watchKubernetes sends on changes after every change of the resources of client. It watches
+// again when the API server ends the watch.
+func watchKubernetes(instanceURL string, client *kubernetes.Client, changes chan<- struct{}) {
+	infof("Watching Kubernetes resources of %s", instanceURL)
+	for {
+		err := client.Watch(context.Background(), func() {
+			debugf("Received Kubernetes change from %s", instanceURL)
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+		})
+		if err != nil {
+			errorf("Error watching Kubernetes resources of %s, retrying in %s. %s", instanceURL, kubernetesWatchRetry, err)
+			time.Sleep(kubernetesWatchRetry)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+var logLevelNames = []string{"debug", "info", "warn", "error"}
+
+var (
+	minLogLevel = levelInfo
+	jsonLogs    bool
+	jsonLogger  = log.New(os.Stderr, "", 0)
+)
+
+type jsonLogLine struct {
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"msg"`
+}
+
+// configureLogging sets the minimum level and the format, "text" or "json", of the logs.
+func configureLogging(level string, format string) error {
+	found := false
+	for i, name := range logLevelNames {
+		if name == level {
+			minLogLevel = logLevel(i)
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("unknown log level %s. Use %s", level, strings.Join(logLevelNames, ", "))
+	}
+
+	switch format {
+	case "text":
+		jsonLogs = false
+	case "json":
+		jsonLogs = true
+	default:
+		return fmt.Errorf("unknown log format %s. Use text or json", format)
+	}
+	return nil
+}
+
+func logf(level logLevel, format string, v ...interface{}) {
+	if level < minLogLevel {
+		return
+	}
+	message := fmt.Sprintf(format, v...)
+	if !jsonLogs {
+		log.Printf("%s %s", strings.ToUpper(logLevelNames[level]), message)
+		return
+	}
+
+	line, err := json.Marshal(jsonLogLine{Time: time.Now(), Level: logLevelNames[level], Message: message})
+	if err != nil {
+		log.Printf("Error marshalling log line %s. %s", message, err)
+		return
+	}
+	jsonLogger.Println(string(line))
+}
+
+func debugf(format string, v ...interface{}) {
+	logf(levelDebug, format, v...)
+}
+
+func infof(format string, v ...interface{}) {
+	logf(levelInfo, format, v...)
+}
+
+func warnf(format string, v ...interface{}) {
+	logf(levelWarn, format, v...)
+}
+
+func errorf(format string, v ...interface{}) {
+	logf(levelError, format, v...)
+}
+
+// fatalf logs at error level and exits with status 1.
+func fatalf(format string, v ...interface{}) {
+	logf(levelError, format, v...)
+	os.Exit(1)
+}
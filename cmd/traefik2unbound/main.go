@@ -0,0 +1,476 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/syncer"
+	"github.com/dcasado/traefik2unbound/tailscale"
+)
+
+type urlList []string
+
+func (u *urlList) Set(urlsString string) error {
+	if urlsString != "" {
+		urls := strings.Split(urlsString, ",")
+		for _, url := range urls {
+			if url != "" {
+				*u = append(*u, url)
+			}
+		}
+	}
+	return nil
+}
+
+func (u *urlList) String() string {
+	builder := strings.Builder{}
+	for i, url := range *u {
+		builder.WriteString(url)
+		if i != len(*u) {
+			builder.WriteString(",")
+		}
+	}
+	return builder.String()
+}
+
+// readURLsFile appends the URLs in path, one per line, ignoring blank lines and # comments.
+func (u *urlList) readURLsFile(path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		*u = append(*u, line)
+	}
+	return nil
+}
+
+// unboundViewList is a flag that can be given several times in the format name or
+// name=subnet,subnet.
+type unboundViewList []output.UnboundView
+
+func (v *unboundViewList) Set(value string) error {
+	view, err := parseUnboundView(value)
+	if err != nil {
+		return err
+	}
+	*v = append(*v, view)
+	return nil
+}
+
+func (v *unboundViewList) String() string {
+	values := make([]string, 0, len(*v))
+	for _, view := range *v {
+		values = append(values, view.Name)
+	}
+	return strings.Join(values, ",")
+}
+
+// parseUnboundView parses a view in the format name or name=subnet,subnet.
+func parseUnboundView(value string) (output.UnboundView, error) {
+	name, subnets, _ := strings.Cut(value, "=")
+	if name == "" || strings.ContainsAny(name, " \t\"") {
+		return output.UnboundView{}, fmt.Errorf("invalid view name %q", name)
+	}
+	view := output.UnboundView{Name: name}
+	for _, subnet := range strings.Split(subnets, ",") {
+		subnet = strings.TrimSpace(subnet)
+		if subnet == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return output.UnboundView{}, fmt.Errorf("invalid subnet of view %s. %s", name, err)
+		}
+		view.Subnets = append(view.Subnets, ipNet.String())
+	}
+	return view, nil
+}
+
+// regexpList is a flag that can be given several times, one regular expression each.
+type regexpList []*regexp.Regexp
+
+func (r *regexpList) Set(expression string) error {
+	re, err := regexp.Compile(expression)
+	if err != nil {
+		return err
+	}
+	*r = append(*r, re)
+	return nil
+}
+
+func (r *regexpList) String() string {
+	expressions := make([]string, 0, len(*r))
+	for _, re := range *r {
+		expressions = append(expressions, re.String())
+	}
+	return strings.Join(expressions, " ")
+}
+
+// hostTTLs is a flag that can be given several times in the format host=ttl.
+type hostTTLs map[string]int
+
+func (h hostTTLs) Set(hostTTL string) error {
+	host, ttl, found := strings.Cut(hostTTL, "=")
+	if !found {
+		return fmt.Errorf("expected host=ttl but got %s", hostTTL)
+	}
+	seconds, err := strconv.Atoi(ttl)
+	if err != nil || seconds < 0 {
+		return fmt.Errorf("invalid TTL %s for host %s", ttl, host)
+	}
+	h[host] = seconds
+	return nil
+}
+
+func (h hostTTLs) String() string {
+	entries := make([]string, 0, len(h))
+	for host, ttl := range h {
+		entries = append(entries, fmt.Sprintf("%s=%d", host, ttl))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// ptrNames is a flag that can be given several times in the format ip=host.
+type ptrNames map[string]string
+
+func (p ptrNames) Set(ipHost string) error {
+	ip, host, found := strings.Cut(ipHost, "=")
+	if !found || net.ParseIP(ip) == nil {
+		return fmt.Errorf("expected ip=host but got %s", ipHost)
+	}
+	p[net.ParseIP(ip).String()] = host
+	return nil
+}
+
+func (p ptrNames) String() string {
+	entries := make([]string, 0, len(p))
+	for ip, host := range p {
+		entries = append(entries, ip+"="+host)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+// localZones is a flag that can be given several times in the format domain=type.
+type localZones map[string]string
+
+func (l localZones) Set(domainType string) error {
+	domain, zoneType, found := strings.Cut(domainType, "=")
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if !found || domain == "" {
+		return fmt.Errorf("expected domain=type but got %s", domainType)
+	}
+	if !containsString(output.LocalZoneTypes, zoneType) {
+		return fmt.Errorf("unknown local-zone type %s for %s. Use %s", zoneType, domain, strings.Join(output.LocalZoneTypes, ", "))
+	}
+	l[domain] = zoneType
+	return nil
+}
+
+func (l localZones) String() string {
+	entries := make([]string, 0, len(l))
+	for domain, zoneType := range l {
+		entries = append(entries, domain+"="+zoneType)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+type cidrList []*net.IPNet
+
+func (c *cidrList) Set(cidrsString string) error {
+	for _, cidr := range strings.Split(cidrsString, ",") {
+		if cidr == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return err
+		}
+		*c = append(*c, ipNet)
+	}
+	return nil
+}
+
+func (c *cidrList) String() string {
+	cidrs := make([]string, 0, len(*c))
+	for _, ipNet := range *c {
+		cidrs = append(cidrs, ipNet.String())
+	}
+	return strings.Join(cidrs, ",")
+}
+
+var (
+	traefikURLs             urlList
+	traefikServicesFilePath string
+	probeAddress            string
+	probeSampleSize         int
+	probeTimeout            time.Duration
+	notifiers               notifierList
+	notifyEvents            urlList
+	wildcardZone            bool
+	allowedCIDRs            cidrList
+	ipSelection             string
+	resolverAddress         string
+	targetInterface         string
+	addressSource           string
+	tailscaleSocket         string
+	traefikResolver         *net.Resolver
+	internalZones           urlList
+	outsideZones            string
+	traefikURLsFilePath     string
+	stateFilePath           string
+	lockFilePath            string
+	metricsTextfilePath     string
+	lockWait                time.Duration
+	changeReportPath        string
+	auditLogPath            string
+	auditLogMaxSize         int
+	auditLogBackups         int
+	showStatus              bool
+	debug                   bool
+	header                  string
+	annotate                bool
+	skipEmpty               bool
+	strict                  bool
+	debounceMaxWait         time.Duration
+	minReloadInterval       time.Duration
+	resolveInterval         time.Duration
+	skipDashboard           bool
+	daemon                  bool
+	interval                time.Duration
+	traefikUsername         string
+	traefikPassword         string
+	traefikToken            string
+	configFilePath          string
+	reloadStrategy          string
+	reloadCommand           string
+	privilegedCommand       string
+	pidfilePath             string
+	reloadContainer         string
+	reloadContainerSignal   string
+	ipFamily                string
+	includeHosts            regexpList
+	excludeHosts            regexpList
+	outputFormat            string
+	firewallCACert          string
+	firewallInsecure        bool
+	firewallHTTPClient      *http.Client
+	txtRegistry             bool
+	txtOwner                string
+	ttl                     int
+	ttlOverrides            = hostTTLs{}
+	cname                   bool
+	ptr                     bool
+	ptrCanonicalNames       = ptrNames{}
+	metricsAddress          string
+	healthAddress           string
+	dryRun                  bool
+	logLevelName            string
+	logFormat               string
+	dockerEventsEnabled     bool
+	dockerSocket            string
+	dockerDebounce          time.Duration
+	kubernetesWatch         bool
+	kubernetesDebounce      time.Duration
+	caCertPath              string
+	insecureSkipVerify      bool
+	clientCertPath          string
+	clientKeyPath           string
+	backupRetention         int
+	backends                backendList
+	concurrency             int
+	instanceTimeout         time.Duration
+	requestTimeout          time.Duration
+	retries                 int
+	retryBackoff            time.Duration
+	fetchErrorPolicy        string
+	conflictPolicy          string
+	maxStale                time.Duration
+	removalGrace            time.Duration
+	maxRemoved              int
+	maxRemovedPercent       int
+	force                   bool
+	merge                   bool
+	hostRegexpMode          string
+	regexpCandidates        urlList
+	includeProviders        urlList
+	excludeProviders        urlList
+	includeDisabled         bool
+	routerMarker            string
+	markerMiddleware        string
+	markerMode              string
+	includeEntryPoints      urlList
+	rawData                 bool
+)
+
+// registerOutputFlags registers the flags of the generated file and the DNS server it is applied to.
+func registerOutputFlags(fs *flag.FlagSet) {
+	fs.StringVar(&traefikServicesFilePath, "p", "traefik-services.conf", "Path of the file where is going to save services hosts")
+	fs.StringVar(&outputFormat, "format", formatUnbound, formatUsage())
+	registerBackendFlags(fs)
+	fs.Var(&backends, "backend", "Output backend in the format name=path, e.g. \"dnsmasq=/etc/dnsmasq.d/traefik.conf\". The path defaults to -p. Can be given several times to feed several DNS servers, each with its own file. Defaults to -format")
+	fs.StringVar(&addressSource, "address-source", syncer.AddressSourceHost, "Where the IPs of the records come from. \"host\" publishes the IPs of the Traefik hosts and \"tailscale\" the Tailscale IPs of their nodes, read from tailscaled, so the records are usable from the tailnet. The outputs of the config file can set their own with address-source, and narrow -ip-family with ip-family")
+	fs.StringVar(&tailscaleSocket, "tailscale-socket", tailscale.DefaultSocket, "Path of the socket of the local API of tailscaled for -address-source tailscale")
+	fs.BoolVar(&txtRegistry, "txt-registry", false, "Only change the hosts of the zones of -format rfc2136 and powerdns with the TXT record of -txt-owner, added with the records of the hosts that don't exist yet like the registry of external-dns, so the records created by hand or by other tools are never changed. -format technitium always does")
+	fs.StringVar(&txtOwner, "txt-owner", "default", "Owner of the TXT records of the hosts managed by this instance, like the owner id of external-dns, so several instances can share a zone")
+	fs.StringVar(&firewallCACert, "firewall-ca-cert", "", "Path of a PEM file with CA certificates to trust for the OPNsense, pfSense and RouterOS APIs, besides the system ones")
+	fs.BoolVar(&firewallInsecure, "firewall-insecure-skip-verify", false, "Don't verify the certificates of the OPNsense, pfSense and RouterOS APIs, which are usually self-signed")
+	fs.BoolVar(&merge, "merge", false, "Only manage the records between the \""+output.BeginMarker+"\" and \""+output.EndMarker+"\" lines of the file, appending them if missing, and keep the rest of the file as is")
+	fs.IntVar(&backupRetention, "backups", 3, "Number of timestamped backups of the file to keep for the rollback command, besides the .bak of the last change")
+	fs.StringVar(&reloadStrategy, "reload", "", "How to apply the changes to unbound or dnsmasq, by default the one of each backend for the OS. \"restart\" runs systemctl restart unbound, the default on Linux, \"systemd\" reloads or restarts it through the D-Bus API of systemd, without systemctl, \"service\" runs service unbound reload, for the rc.d script of FreeBSD where it is the default, \"init\" runs /etc/init.d/unbound reload, like on OpenWrt, \"unbound-control\" runs unbound-control reload, the default of unbound on Windows, keeping unbound running, \"incremental\" only adds and removes the changed records with unbound-control, keeping its cache, \"command\" runs -reload-command, \"signal\" sends SIGHUP to the process of -pidfile, \"docker\" restarts -reload-container with the Docker API and \"none\" does nothing, the default of dnsmasq on Windows. The ones of unbound-control don't apply to -format dnsmasq")
+	fs.StringVar(&privilegedCommand, "privileged-command", "", "Command prefixing the ones that reload the DNS servers, like systemctl, service, unbound-control, pihole, rndc and -reload-command, e.g. \"sudo -n\", so the sync runs unprivileged with only them allowed as root by sudoers. The directories of the files must be writable by the user, since they are replaced with a new file")
+	fs.StringVar(&reloadCommand, "reload-command", "", "Command run with sh by -reload command, e.g. \"rc-service unbound reload\"")
+	fs.StringVar(&reloadContainer, "reload-container", "", "Name or ID of the unbound container restarted by -reload docker, e.g. \"unbound\"")
+	fs.StringVar(&reloadContainerSignal, "reload-container-signal", "", "Signal sent to -reload-container instead of restarting it, e.g. \"HUP\"")
+	fs.StringVar(&dockerSocket, "docker-socket", defaultDockerSocket, "Path of the Docker socket of -docker-events and -reload docker, e.g. the mounted /var/run/docker.sock")
+	fs.StringVar(&pidfilePath, "pidfile", "", "Pid file of unbound for -reload signal, e.g. \"/var/run/unbound.pid\"")
+	fs.StringVar(&probeAddress, "probe", "", "Address of the DNS server to query after applying the changes, e.g. \"127.0.0.1:53\". A sample of the hosts must resolve to their IPs or the previous file is restored, which catches a file the DNS server doesn't include")
+	fs.IntVar(&probeSampleSize, "probe-samples", 3, "Number of hosts queried by -probe")
+	fs.DurationVar(&probeTimeout, "probe-timeout", 10*time.Second, "Time for the hosts queried by -probe to resolve, while the DNS server restarts")
+	fs.Var(&notifiers, "notify", "URL to notify of the changes and failures, prefixed by its format and =. Formats are \"webhook\", the default, which posts the JSON of the notification, \"ntfy\" with the URL of a topic, \"gotify\" with the /message URL and the token query parameter and \"slack\" with an incoming webhook, e.g. \"ntfy=https://ntfy.sh/mydns\". Can be repeated")
+	fs.Var(&notifyEvents, "notify-on", "Comma separated list of the events notified to -notify: \"change\", \"invalid\" and \"reload-failure\". Defaults to all")
+	fs.StringVar(&metricsTextfilePath, "metrics-textfile", "", "Path of the file where the metrics of the last sync are written after every run in the Prometheus text format, for the textfile collector of node_exporter, e.g. \"/var/lib/node_exporter/textfile/traefik2unbound.prom\"")
+	fs.StringVar(&lockFilePath, "lock-file", "", "Path of the lock file held while syncing, so overlapping runs like a slow cron job don't race on the files and their backups. Defaults to one next to every file, with .lock appended. \"none\" disables the lock")
+	fs.DurationVar(&lockWait, "lock-wait", 0, "Time to wait for another run holding -lock-file. The run then exits with status 7, or the sync of the daemon fails until the next one")
+	fs.StringVar(&stateFilePath, "state-file", "", "Path of the JSON file where the state of the last successful sync is saved")
+	fs.StringVar(&auditLogPath, "audit-log", "", "Path of the JSON lines file where every change of the files is appended, with the records it added, removed and repointed by instance and whether it was applied")
+	fs.IntVar(&auditLogMaxSize, "audit-log-max-size", 10, "Size in megabytes of -audit-log before it is rotated. 0 never rotates it")
+	fs.IntVar(&auditLogBackups, "audit-log-backups", 5, "Number of rotated -audit-log files to keep, as .1 to .N")
+	fs.StringVar(&changeReportPath, "change-report", "", "Path of the JSON file where the records added, removed and repointed to other IPs by the last sync that changed the files are written. Needs -state-file to compare with the previous run outside of daemon mode")
+}
+
+// registerSourceFlags registers the flags of the Traefik APIs and of the records generated from their routers.
+func registerSourceFlags(fs *flag.FlagSet) {
+	fs.Var(&traefikURLs, "u", "Comma separated list of Traefik URLs in the format \"https://traefik.io,https://localhost\". Append =IP to a URL to publish that IP instead of resolving its host, e.g. \"https://traefik.lan=192.168.1.10\". \"kubernetes://=IP\" reads the Ingresses and IngressRoutes of the Kubernetes API instead, in cluster or with the current kubeconfig context, and takes the kubeconfig, context, namespace and ingress-class query parameters. \"docker:///path/of/docker.sock=IP\" or \"docker://host:2375\" reads the Traefik labels of the running containers, and takes the exposed-by-default query parameter. \"file:///path/of/dynamic=IP\" reads a Traefik dynamic configuration or Docker Compose file, or the YAML and TOML files of a directory")
+	fs.StringVar(&traefikURLsFilePath, "urls-file", "", "Path of a file with one Traefik URL per line, appended to the URLs given with -u")
+	fs.BoolVar(&wildcardZone, "wildcard-zone", false, "Emit a local-zone redirect for wildcard hosts like *.example.com instead of skipping them")
+	fs.StringVar(&ipFamily, "ip-family", syncer.IPFamilyV4, "IP family of the records. \"v4\" writes A records, \"v6\" AAAA records and \"both\" writes both for Traefik hosts with IPv4 and IPv6")
+	fs.StringVar(&hostRegexpMode, "host-regexp", syncer.HostRegexpIgnore, "How to publish the HostRegexp and HostSNIRegexp matchers. \"ignore\" skips them, \"wildcard\" publishes the ones matching the subdomains of a domain, like {sub:[a-z]+}.example.com, as *.example.com and \"expand\" publishes the hosts of -regexp-hosts they match")
+	fs.Var(&regexpCandidates, "regexp-hosts", "Comma separated list of hosts published for the HostRegexp matchers they match with -host-regexp expand")
+	fs.Var(&includeHosts, "include", "Regular expression of the hosts to publish. Can be given several times, a host matching any of them is published")
+	fs.Var(&excludeHosts, "exclude", "Regular expression of the hosts to never publish. Can be given several times and takes precedence over -include")
+	fs.IntVar(&ttl, "ttl", 0, "TTL in seconds of the unbound and rfc2136 records. 0 leaves the default TTL of unbound, and uses "+strconv.Itoa(output.DefaultRFC2136TTL)+" with rfc2136")
+	fs.Var(ttlOverrides, "host-ttl", "TTL in seconds for a single host in the format host=ttl, overriding -ttl. Can be given several times")
+	fs.BoolVar(&cname, "cname", false, "Publish every host as a CNAME of the Traefik hostname instead of A records with its IP, so the records don't change with the Traefik IP")
+	fs.BoolVar(&ptr, "ptr", false, "Also write a local-data-ptr record for every IP so reverse lookups resolve to a service name")
+	fs.Var(ptrCanonicalNames, "ptr-name", "Host the PTR record of an IP points to in the format ip=host. Can be given several times. Defaults to the first host in alphabetical order")
+	fs.Var(&internalZones, "zones", "Comma separated list of the domains served internally, e.g. \"lan,home.arpa\". The hosts outside of them are handled with -outside-zones, so a public host in a rule doesn't hijack a real internet name")
+	fs.StringVar(&outsideZones, "outside-zones", syncer.OutsideZonesSkip, "What to do with the hosts outside of -zones. \"skip\" leaves them out and \"include\" publishes them, both with a warning")
+	fs.StringVar(&resolverAddress, "resolver", "", "DNS server that resolves the Traefik hosts, to connect to them and publish their IPs, instead of the system resolver, e.g. \"192.168.1.1:53\". Avoids depending on the unbound fed by this tool")
+	fs.StringVar(&targetInterface, "target-interface", "", "Network interface whose IPs are published for the instances without =IP, instead of resolving their hosts, e.g. \"wg0\" to only publish the services over a VPN")
+	fs.StringVar(&ipSelection, "ip-selection", syncer.IPSelectionFirst, "IPs published for a Traefik host that resolves to several of a family. \"first\" publishes the first one, \"all\" all of them for round-robin, \"prefer-private\" the first private one and a CIDR like \"192.168.1.0/24\" the first one in it, both falling back to the first one")
+	fs.Var(&allowedCIDRs, "allowed-cidrs", "Comma separated list of CIDRs the resolved Traefik IPs must belong to, e.g. \"10.0.0.0/8,192.168.0.0/16\". The other IPs are skipped with a warning, and so are the hosts of an instance without any IP left")
+	fs.BoolVar(&annotate, "annotate", false, "Append to every local-data line of the unbound files a comment with the routers, providers and Traefik instance of its host, and write the time the file was generated in its header. The time alone doesn't change the file")
+	fs.StringVar(&header, "header", "The contents of this file will be overriden to add traefik endpoints dynamically", "Comment written at the top of the generated file. Empty to omit it")
+	fs.BoolVar(&skipEmpty, "skip-empty", false, "Leave the file untouched when no hosts are extracted instead of writing a file without records")
+	fs.IntVar(&maxRemovedPercent, "max-removed-percent", 50, "Maximum percentage of the records of the previous sync a sync can remove, so a Traefik restarting with no routers doesn't wipe them. Above it the files are left untouched until -force is given. 0 removes any number of them. Needs -state-file outside of daemon mode")
+	fs.IntVar(&maxRemoved, "max-removed", 0, "Maximum number of the records of the previous sync a sync can remove, like -max-removed-percent. 0 removes any number of them")
+	fs.BoolVar(&force, "force", false, "Apply the sync even when it removes more records than -max-removed or -max-removed-percent")
+	fs.BoolVar(&rawData, "rawdata", false, "Retrieve the routers with a single request to /api/rawdata instead of one to the HTTP and another to the TCP routers")
+	fs.Var(&includeEntryPoints, "entrypoints", "Comma separated list of the Traefik entry points whose routers are published. Defaults to every entry point")
+	fs.Var(&includeProviders, "providers", "Comma separated list of the Traefik providers, like docker or file, whose routers are published. Defaults to every provider")
+	fs.Var(&excludeProviders, "exclude-providers", "Comma separated list of the Traefik providers whose routers are never published, e.g. \"internal\"")
+	fs.BoolVar(&includeDisabled, "include-disabled", false, "Also publish the routers whose status isn't enabled, like the ones Traefik disabled because of an error")
+	fs.StringVar(&routerMarker, "router-marker", "", "Prefix or suffix of the router names, without their @provider, that marks them, e.g. \"-nodns\" for myapp-nodns@docker")
+	fs.StringVar(&markerMiddleware, "marker-middleware", "", "Name of a middleware that marks the HTTP routers using it, e.g. \"nodns\" for nodns@file")
+	fs.StringVar(&markerMode, "marker-mode", syncer.MarkerOptOut, "What the routers marked with -router-marker or -marker-middleware are. \"opt-out\" never publishes them and \"opt-in\" only publishes them")
+	fs.BoolVar(&skipDashboard, "skip-dashboard", false, "Skip the routers of the Traefik dashboard and API")
+	fs.StringVar(&caCertPath, "ca-cert", "", "Path of a PEM file with the CA certificates to trust for the Traefik API, besides the system ones")
+	fs.BoolVar(&insecureSkipVerify, "insecure-skip-verify", false, "Don't verify the certificate of the Traefik API")
+	fs.StringVar(&clientCertPath, "client-cert", "", "Path of the PEM client certificate to present to the Traefik API for mutual TLS")
+	fs.StringVar(&clientKeyPath, "client-key", "", "Path of the PEM key of -client-cert")
+	fs.IntVar(&concurrency, "concurrency", 4, "Maximum number of Traefik instances fetched at the same time")
+	fs.DurationVar(&instanceTimeout, "instance-timeout", 30*time.Second, "Time to retrieve the routers of an instance before giving up on it. 0 waits forever")
+	fs.DurationVar(&requestTimeout, "timeout", 10*time.Second, "Timeout of every request to the Traefik API. 0 waits forever")
+	fs.IntVar(&retries, "retries", 2, "Number of times a request to the Traefik API failing with a connection error or a 5xx status is retried")
+	fs.DurationVar(&retryBackoff, "retry-backoff", time.Second, "Time to wait before the first retry, doubled after every attempt")
+	fs.BoolVar(&strict, "strict", false, "Abort the sync without writing the files when an instance fails, unless -on-fetch-error keep uses its last records, and fail when a file is invalid. The exit status is then 3 when an instance failed, 4 when a file is invalid and 6 when nothing changed. It is always 5 when a file couldn't be applied and 7 when another run holds -lock-file")
+	fs.StringVar(&conflictPolicy, "on-conflict", syncer.ConflictEmitAll, "What to publish for a host with different records on several instances of the same priority, the one of highest priority winning otherwise. \"emit-all\" publishes the records of every instance, \"first-wins\" and \"last-wins\" only the ones of the first or last instance of -u that has it and \"fail\" fails the sync")
+	fs.StringVar(&fetchErrorPolicy, "on-fetch-error", syncer.FetchErrorDrop, "What to publish for an instance that can't be fetched. \"drop\" publishes nothing for it and \"keep\" the records of its last successful fetch saved in -state-file")
+	fs.DurationVar(&maxStale, "max-stale", 24*time.Hour, "Maximum age of the records kept with -on-fetch-error keep. 0 keeps them forever")
+	fs.DurationVar(&removalGrace, "removal-grace", 0, "Time the records of a host whose routers disappear are still published, so restarting a container or redeploying Traefik doesn't remove them. 0 removes them right away. Needs -state-file outside of daemon mode")
+	fs.StringVar(&traefikUsername, "username", os.Getenv("TRAEFIK_USERNAME"), "Username for basic authentication against the Traefik API. Defaults to $TRAEFIK_USERNAME")
+	fs.StringVar(&traefikPassword, "password", os.Getenv("TRAEFIK_PASSWORD"), "Password for basic authentication against the Traefik API. Defaults to $TRAEFIK_PASSWORD or the contents of the file of $TRAEFIK_PASSWORD_FILE")
+	fs.StringVar(&traefikPasswordFile, "password-file", "", "Path of a file with -password, like a Docker secret, so it doesn't show up in the arguments of the process")
+	fs.StringVar(&traefikToken, "token", os.Getenv("TRAEFIK_TOKEN"), "Bearer token for the Traefik API. Defaults to $TRAEFIK_TOKEN or the contents of the file of $TRAEFIK_TOKEN_FILE")
+	fs.StringVar(&traefikTokenFile, "token-file", "", "Path of a file with -token, like a Docker secret, so it doesn't show up in the arguments of the process")
+}
+
+// registerDaemonFlags registers the flags of the daemon command.
+func registerDaemonFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&dockerEventsEnabled, "docker-events", false, "Sync in daemon mode as soon as containers start, stop or change instead of waiting for the next -interval")
+	fs.DurationVar(&dockerDebounce, "docker-debounce", 5*time.Second, "Time without Docker events to wait before syncing, so Traefik picks up the changes and bursts of events cause a single sync")
+	fs.DurationVar(&debounceMaxWait, "debounce-max-wait", time.Minute, "Maximum time a sync waits for the Docker or Kubernetes events to calm down, so constant changes still get synced. 0 waits for a quiet period however long it takes")
+	fs.DurationVar(&minReloadInterval, "min-reload-interval", 0, "Minimum time between two syncs that change the files in daemon mode, so the DNS server isn't reloaded for every burst of changes. The changes made meanwhile are synced together once it passes")
+	fs.BoolVar(&kubernetesWatch, "kubernetes-watch", false, "Sync in daemon mode as soon as the Ingresses and IngressRoutes of the Kubernetes instances change instead of waiting for the next -interval")
+	fs.DurationVar(&kubernetesDebounce, "kubernetes-debounce", 5*time.Second, "Time without Kubernetes changes to wait before syncing, so bursts of changes cause a single sync")
+	fs.StringVar(&metricsAddress, "metrics-address", "", "Address to expose Prometheus metrics on /metrics in daemon mode, e.g. \":9100\"")
+	fs.StringVar(&healthAddress, "health-address", "", "Address to expose the /healthz and /readyz endpoints on in daemon mode. Can be the same as -metrics-address")
+	fs.DurationVar(&interval, "interval", 2*time.Minute, "Time between syncs in daemon mode")
+	fs.DurationVar(&resolveInterval, "resolve-interval", 0, "Time between the resolutions of the Traefik hosts in daemon mode, syncing as soon as their IPs change instead of at the next -interval. 0 only resolves them on every sync")
+}
+
+// registerLogFlags registers the logging and config file flags shared by every command.
+func registerLogFlags(fs *flag.FlagSet) {
+	fs.StringVar(&logLevelName, "log-level", "info", "Minimum level of the logs: debug, info, warn or error")
+	fs.StringVar(&logFormat, "log-format", "text", "Format of the logs: text or json")
+	fs.BoolVar(&debug, "debug", false, "Log debug messages like the duration of each sync phase. Same as -log-level debug")
+	fs.StringVar(&configFilePath, "config", "", "Path of a YAML file with the settings, or a TOML one with the .toml extension. Flags given on the command line take precedence")
+}
+
+// registerLegacyFlags registers the flags that select the mode when no command is given, kept for
+// compatibility with the flat command line, and the renamed flags.
+func registerLegacyFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&dryRun, "dry-run", false, "Print a diff between the current file and the one that would be written, without writing it or touching the DNS server")
+	fs.BoolVar(&daemon, "daemon", false, "Keep running and sync the hosts every -interval")
+	fs.BoolVar(&showStatus, "status", false, "Print the state of the last successful sync saved in -state-file and exit")
+	registerRenamedFlags(fs)
+}
+
+// registerRenamedFlags registers the old names of the renamed flags, also accepted by the
+// commands writing the output.
+func registerRenamedFlags(fs *flag.FlagSet) {
+	fs.StringVar(&technitiumOwner, "technitium-owner", "", "Deprecated, use -txt-owner")
+}
+
+// splitIPOverride splits an entry like "https://traefik.lan=192.168.1.10" into the Traefik
+// URL and the IP to publish for it. The IP is nil when the entry has no override.
+func splitIPOverride(entry string) (string, net.IP) {
+	i := strings.LastIndex(entry, "=")
+	if i < 0 {
+		return entry, nil
+	}
+	ip := net.ParseIP(entry[i+1:])
+	if ip == nil {
+		return entry, nil
+	}
+	if ip.To4() != nil {
+		ip = ip.To4()
+	}
+	return entry[:i], ip
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
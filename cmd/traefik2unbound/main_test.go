@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/reload"
+)
+
+func TestBackendFactories(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	registerBackendFlags(fs)
+	for name, factory := range backendFactories {
+		if factory.name != name || factory.description == "" || factory.new == nil {
+			t.Errorf("backend %s is registered without its name, description or constructor", name)
+		}
+		for _, secret := range factory.secrets {
+			if fs.Lookup(secret.name) == nil {
+				t.Errorf("backend %s has the secret -%s without a flag", name, secret.name)
+			}
+		}
+	}
+}
+
+func TestBackendsWith(t *testing.T) {
+	tests := []struct {
+		name string
+		has  func(c backendCapabilities) bool
+		want string
+	}{
+		{"ptr", func(c backendCapabilities) bool { return c.ptr }, "the unbound backend"},
+		{"txt-registry", func(c backendCapabilities) bool { return c.txtRegistry }, "the powerdns and rfc2136 backends"},
+		{"reload", func(c backendCapabilities) bool { return len(c.reloadStrategies) > 0 }, "the dnsmasq and unbound backends"},
+		{"none", func(c backendCapabilities) bool { return false }, "no backend"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := backendsWith(test.has); got != test.want {
+				t.Errorf("backendsWith() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTechnitiumOwnerAlias(t *testing.T) {
+	resetListSettings()
+	fs := allFlags()
+	err := fs.Parse([]string{
+		"-backend", formatTechnitium + "=" + t.TempDir() + "/technitium.conf",
+		"-technitium-url", "http://technitium.lan:5380",
+		"-technitium-token", "token",
+		"-technitium-zone", "example.com",
+		"-technitium-owner", "foo",
+	})
+	if err != nil {
+		t.Fatalf("Parse() failed. %s", err)
+	}
+	defer func() {
+		targets = nil
+	}()
+
+	validateOutput()
+	overrides, ok := targets[0].Backend.(backend.HostOverrides)
+	if !ok {
+		t.Fatalf("got the backend %T, want backend.HostOverrides", targets[0].Backend)
+	}
+	client, ok := overrides.Client.(reload.Technitium)
+	if !ok || client.Owner != "foo" {
+		t.Errorf("got the client %+v, want the owner foo of -technitium-owner", overrides.Client)
+	}
+}
+
+func TestDefaultReload(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		wantUnbound string
+		wantDnsmasq string
+	}{
+		{"defaults", nil, defaultUnboundReload, defaultDnsmasqReload},
+		{"reload", []string{"-reload", backend.ReloadSystemd}, backend.ReloadSystemd, backend.ReloadSystemd},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			resetListSettings()
+			fs := allFlags()
+			err := fs.Parse(append([]string{
+				"-backend", formatUnbound + "=" + dir + "/unbound.conf",
+				"-backend", formatDnsmasq + "=" + dir + "/dnsmasq.conf",
+			}, test.args...))
+			if err != nil {
+				t.Fatalf("Parse() failed. %s", err)
+			}
+			defer func() {
+				targets = nil
+			}()
+
+			validateOutput()
+			unbound, _ := targets[0].Backend.(backend.Unbound)
+			dnsmasq, _ := targets[1].Backend.(backend.Dnsmasq)
+			if unbound.Reload != test.wantUnbound || dnsmasq.Reload != test.wantDnsmasq {
+				t.Errorf("got the reload strategies %s and %s, want %s and %s", unbound.Reload, dnsmasq.Reload, test.wantUnbound, test.wantDnsmasq)
+			}
+		})
+	}
+}
+
+func TestLoadConfigTOML(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traefik2unbound.toml")
+	err := os.WriteFile(path, []byte(`
+urls = ["http://traefik1.lan:8080", "http://traefik2.lan:8080"]
+max-removed = 5
+strict = true
+
+[[outputs]]
+backend = "dnsmasq"
+path = "/etc/dnsmasq.d/traefik.conf"
+`), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() failed. %s", err)
+	}
+	resetListSettings()
+	fs := allFlags()
+	defer resetListSettings()
+
+	err = loadConfig(path, fs, fs)
+	if err != nil {
+		t.Fatalf("loadConfig() failed. %s", err)
+	}
+	if len(traefikURLs) != 2 || maxRemoved != 5 || !strict {
+		t.Errorf("got the URLs %v, -max-removed %d and -strict %t, want the settings of the file", traefikURLs, maxRemoved, strict)
+	}
+	if len(configOutputs) != 1 || configOutputs[0].Backend != formatDnsmasq {
+		t.Errorf("got the outputs %+v, want the dnsmasq one", configOutputs)
+	}
+}
+
+func TestCheckConfigSecrets(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the permissions of the files can't be restricted to their owner")
+	}
+	tests := []struct {
+		name     string
+		file     string
+		contents string
+		wantErr  bool
+	}{
+		{"no secrets", "config.yml", "strict: true\n", false},
+		{"secret", "config.yml", "token: secret\n", true},
+		{"secret of an output", "config.yml", "outputs:\n  - backend: technitium\n    technitium-token: secret\n", true},
+		{"toml secret", "config.toml", "token = \"secret\"\n", true},
+		{"toml secret of an output", "config.toml", "[[outputs]]\nbackend = \"technitium\"\ntechnitium-token = \"secret\"\n", true},
+		{"toml secret of a table", "config.toml", "[nested]\npassword = \"secret\"\n", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), test.file)
+			err := os.WriteFile(path, []byte(test.contents), 0644)
+			if err != nil {
+				t.Fatalf("WriteFile() failed. %s", err)
+			}
+			resetListSettings()
+			fs := allFlags()
+			defer resetListSettings()
+
+			err = loadConfig(path, fs, fs)
+			if test.wantErr && (err == nil || !strings.Contains(err.Error(), "0600")) {
+				t.Errorf("loadConfig() = %v, want the file readable by others rejected", err)
+			}
+			if !test.wantErr && err != nil {
+				t.Errorf("loadConfig() failed. %s", err)
+			}
+		})
+	}
+}
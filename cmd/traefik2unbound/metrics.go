@@ -0,0 +1,293 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syncMetrics holds the values exposed on /metrics in the Prometheus text format.
+type syncMetrics struct {
+	mu             sync.Mutex
+	routers        map[string]int
+	apiErrors      map[string]int
+	records        int
+	syncDuration   time.Duration
+	lastSuccess    time.Time
+	reloadFailures int
+	phaseDurations map[string]time.Duration
+	lastSyncError  string
+	lastSync       time.Time
+	synced         bool
+	configValid    bool
+}
+
+var metrics = &syncMetrics{
+	routers:        map[string]int{},
+	apiErrors:      map[string]int{},
+	phaseDurations: map[string]time.Duration{},
+	configValid:    true,
+}
+
+func (m *syncMetrics) SetRouters(instance string, routers int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.routers[instance] = routers
+}
+
+func (m *syncMetrics) IncAPIErrors(instance string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.apiErrors[instance]++
+}
+
+func (m *syncMetrics) IncReloadFailures() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloadFailures++
+}
+
+func (m *syncMetrics) ObservePhase(phase string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.phaseDurations[phase] = duration
+}
+
+func (m *syncMetrics) ObserveSync(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.syncDuration = duration
+}
+
+func (m *syncMetrics) SetSuccess(records int, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = records
+	m.lastSuccess = at
+}
+
+// setSyncResult records the outcome of a sync for the health endpoints.
+func (m *syncMetrics) setSyncResult(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.synced = true
+	m.lastSync = time.Now()
+	m.lastSyncError = ""
+	if err != nil {
+		m.lastSyncError = err.Error()
+	}
+}
+
+func (m *syncMetrics) SetConfigValid(valid bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.configValid = valid
+}
+
+type healthStatus struct {
+	LastSync          *time.Time `json:"lastSuccessfulSync,omitempty"`
+	LastSyncSucceeded bool       `json:"lastSyncSucceeded"`
+	ConfigValid       bool       `json:"configValid"`
+	Error             string     `json:"error,omitempty"`
+}
+
+func (m *syncMetrics) health() healthStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := healthStatus{
+		LastSyncSucceeded: m.synced && m.lastSyncError == "",
+		ConfigValid:       m.configValid,
+		Error:             m.lastSyncError,
+	}
+	if !m.lastSuccess.IsZero() {
+		lastSuccess := m.lastSuccess
+		status.LastSync = &lastSuccess
+	}
+	return status
+}
+
+// serveHealth answers /healthz with 200 while the last sync succeeded and the generated
+// config is valid, so orchestrators can restart a wedged container.
+func serveHealth(w http.ResponseWriter, r *http.Request) {
+	status := metrics.health()
+	writeHealth(w, status, status.LastSyncSucceeded && status.ConfigValid)
+}
+
+// serveReadiness answers /readyz with 200 once a sync has succeeded.
+func serveReadiness(w http.ResponseWriter, r *http.Request) {
+	status := metrics.health()
+	writeHealth(w, status, status.LastSync != nil)
+}
+
+func writeHealth(w http.ResponseWriter, status healthStatus, healthy bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if !healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(status)
+	if err != nil {
+		errorf("Error writing health status. %s", err)
+	}
+}
+
+func (m *syncMetrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	_, err := w.Write([]byte(m.text()))
+	if err != nil {
+		errorf("Error writing metrics. %s", err)
+	}
+}
+
+// text returns the metrics in the Prometheus text format. m.mu must be held.
+func (m *syncMetrics) text() string {
+	builder := strings.Builder{}
+	writeMetric(&builder, "traefik2unbound_routers", "gauge", "Routers discovered in the last sync per Traefik instance.", "instance", intValues(m.routers))
+	writeMetric(&builder, "traefik2unbound_traefik_api_errors_total", "counter", "Errors retrieving the routers per Traefik instance.", "instance", intValues(m.apiErrors))
+	writeMetric(&builder, "traefik2unbound_records", "gauge", "Records written in the last successful sync.", "", map[string]float64{"": float64(m.records)})
+	writeMetric(&builder, "traefik2unbound_sync_duration_seconds", "gauge", "Duration of the last sync.", "", map[string]float64{"": m.syncDuration.Seconds()})
+	writeMetric(&builder, "traefik2unbound_phase_duration_seconds", "gauge", "Duration of each phase of the last sync that ran it.", "phase", durationValues(m.phaseDurations))
+	lastSuccess := 0.0
+	if !m.lastSuccess.IsZero() {
+		lastSuccess = float64(m.lastSuccess.Unix())
+	}
+	writeMetric(&builder, "traefik2unbound_last_successful_sync_timestamp_seconds", "gauge", "Unix time of the last successful sync.", "", map[string]float64{"": lastSuccess})
+	writeMetric(&builder, "traefik2unbound_reload_failures_total", "counter", "Failed reloads of the DNS server.", "", map[string]float64{"": float64(m.reloadFailures)})
+	if m.synced {
+		success := 0.0
+		if m.lastSyncError == "" {
+			success = 1
+		}
+		writeMetric(&builder, "traefik2unbound_last_sync_success", "gauge", "Whether the last sync succeeded.", "", map[string]float64{"": success})
+		writeMetric(&builder, "traefik2unbound_last_sync_timestamp_seconds", "gauge", "Unix time of the last sync.", "", map[string]float64{"": float64(m.lastSync.Unix())})
+	}
+	return builder.String()
+}
+
+// writeMetricsTextfile writes the metrics of the last sync to path for the textfile collector
+// of node_exporter, so one-shot runs from cron or a systemd timer are monitored too. Like the
+// collector expects, the file is replaced at once. The last successful sync and its records
+// are kept from the previous file when this one failed.
+func writeMetricsTextfile(path string) {
+	metrics.mu.Lock()
+	if metrics.lastSuccess.IsZero() {
+		previous := previousTextfileValues(path)
+		if timestamp, ok := previous["traefik2unbound_last_successful_sync_timestamp_seconds"]; ok && timestamp > 0 {
+			metrics.lastSuccess = time.Unix(int64(timestamp), 0)
+			metrics.records = int(previous["traefik2unbound_records"])
+		}
+	}
+	contents := metrics.text()
+	metrics.mu.Unlock()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		errorf("Error writing metrics to %s. %s", path, err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(contents)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		errorf("Error writing metrics to %s. %s", path, err)
+	}
+}
+
+// previousTextfileValues returns the unlabeled samples of the metrics file at path.
+func previousTextfileValues(path string) map[string]float64 {
+	values := map[string]float64{}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return values
+	}
+	for _, line := range strings.Split(string(contents), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[1], 64)
+		if err == nil {
+			values[fields[0]] = value
+		}
+	}
+	return values
+}
+
+// writeMetric writes a metric with one sample per label value. An empty label writes a single unlabeled sample.
+func writeMetric(builder *strings.Builder, name string, metricType string, help string, label string, values map[string]float64) {
+	builder.WriteString(fmt.Sprintf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType))
+
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if label == "" {
+			builder.WriteString(fmt.Sprintf("%s %s\n", name, strconv.FormatFloat(values[key], 'f', -1, 64)))
+		} else {
+			builder.WriteString(fmt.Sprintf("%s{%s=%q} %s\n", name, label, key, strconv.FormatFloat(values[key], 'f', -1, 64)))
+		}
+	}
+}
+
+func intValues(values map[string]int) map[string]float64 {
+	floats := make(map[string]float64, len(values))
+	for key, value := range values {
+		floats[key] = float64(value)
+	}
+	return floats
+}
+
+func durationValues(values map[string]time.Duration) map[string]float64 {
+	floats := make(map[string]float64, len(values))
+	for key, value := range values {
+		floats[key] = value.Seconds()
+	}
+	return floats
+}
+
+// serveHTTP exposes /metrics on metricsAddress and /healthz and /readyz on healthAddress in
+// the background. Both can share the same address and are disabled when empty.
+func serveHTTP(metricsAddress string, healthAddress string) {
+	muxes := map[string]*http.ServeMux{}
+	if metricsAddress != "" {
+		muxes[metricsAddress] = http.NewServeMux()
+		muxes[metricsAddress].Handle("/metrics", metrics)
+	}
+	if healthAddress != "" {
+		if muxes[healthAddress] == nil {
+			muxes[healthAddress] = http.NewServeMux()
+		}
+		muxes[healthAddress].HandleFunc("/healthz", serveHealth)
+		muxes[healthAddress].HandleFunc("/readyz", serveReadiness)
+	}
+
+	for address, mux := range muxes {
+		go func(address string, mux *http.ServeMux) {
+			infof("Listening on %s", address)
+			err := http.ListenAndServe(address, mux)
+			if err != nil {
+				errorf("Error listening on %s. %s", address, err)
+			}
+		}(address, mux)
+	}
+}
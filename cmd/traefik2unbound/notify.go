@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/notify"
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+// maxNotifiedLines is the number of added and removed lines listed in a change notification.
+const maxNotifiedLines = 20
+
+// notifierList is the list of -notify entries, the URLs to notify prefixed by their format
+// and =, like ntfy=https://ntfy.sh/mytopic. The URLs without a format get the JSON webhook.
+type notifierList []notify.Notifier
+
+func (n *notifierList) Set(value string) error {
+	format, u, found := strings.Cut(value, "=")
+	if !found || !containsString(notify.Formats, format) {
+		format, u = notify.FormatWebhook, value
+	}
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return fmt.Errorf("invalid notification URL %s", u)
+	}
+	*n = append(*n, notify.Notifier{Format: format, URL: u})
+	return nil
+}
+
+func (n *notifierList) String() string {
+	entries := make([]string, 0, len(*n))
+	for _, notifier := range *n {
+		entries = append(entries, notifier.Format+"="+notifier.URL)
+	}
+	return strings.Join(entries, ",")
+}
+
+// notifyChange notifies the lines the new contents of the file of t added and removed.
+func notifyChange(t *syncer.Target, oldContents string, newContents string) {
+	added, removed := notify.ChangedLines(oldContents, newContents)
+	text := fmt.Sprintf("%d lines added and %d removed", len(added), len(removed))
+	text += changedLinesText("+ ", added) + changedLinesText("- ", removed)
+	sendNotification(notify.Notification{
+		Event:   notify.EventChange,
+		Path:    t.Path,
+		Title:   fmt.Sprintf("Changed %s", t.Path),
+		Text:    text,
+		Added:   added,
+		Removed: removed,
+	})
+}
+
+// notifyFailure notifies that the file of t failed with err and was restored.
+func notifyFailure(t *syncer.Target, event string, err error) {
+	title := fmt.Sprintf("Error applying %s", t.Path)
+	if event == notify.EventInvalid {
+		title = fmt.Sprintf("Invalid %s", t.Path)
+	}
+	sendNotification(notify.Notification{
+		Event: event,
+		Path:  t.Path,
+		Title: title,
+		Text:  fmt.Sprintf("The previous file was restored. %s", err),
+	})
+}
+
+// sendNotification sends the notification to every notifier of -notify when its event is in
+// -notify-on, or always without it.
+func sendNotification(notification notify.Notification) {
+	if len(notifiers) == 0 || len(notifyEvents) > 0 && !containsString(notifyEvents, notification.Event) {
+		return
+	}
+	notification.Time = time.Now()
+	for _, notifier := range notifiers {
+		ctx, cancel := context.Background(), func() {}
+		if requestTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, requestTimeout)
+		}
+		err := notifier.Send(ctx, notification)
+		cancel()
+		if err != nil {
+			errorf("Error notifying %s of %s. %s", notifier.URL, notification.Title, err)
+		}
+	}
+}
+
+func changedLinesText(prefix string, lines []string) string {
+	builder := strings.Builder{}
+	for i, line := range lines {
+		if i == maxNotifiedLines {
+			fmt.Fprintf(&builder, "\n%sand %d more", prefix, len(lines)-i)
+			break
+		}
+		builder.WriteString("\n" + prefix + line)
+	}
+	return builder.String()
+}
@@ -0,0 +1,11 @@
+//go:build !windows && !freebsd && !dragonfly
+
+package main
+
+import "github.com/dcasado/traefik2unbound/backend"
+
+// Defaults of -reload for each backend, restarting their systemd service.
+const (
+	defaultUnboundReload = backend.ReloadRestart
+	defaultDnsmasqReload = backend.ReloadRestart
+)
@@ -0,0 +1,11 @@
+//go:build freebsd || dragonfly
+
+package main
+
+import "github.com/dcasado/traefik2unbound/backend"
+
+// Defaults of -reload for each backend, reloading them with their rc.d script.
+const (
+	defaultUnboundReload = backend.ReloadService
+	defaultDnsmasqReload = backend.ReloadService
+)
@@ -0,0 +1,10 @@
+package main
+
+import "github.com/dcasado/traefik2unbound/backend"
+
+// Defaults of -reload for each backend. unbound runs as a Windows service reloaded with
+// unbound-control, while dnsmasq has no Windows service to reload.
+const (
+	defaultUnboundReload = backend.ReloadUnboundControl
+	defaultDnsmasqReload = backend.ReloadNone
+)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/syncer"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// watchTraefikIPs resolves the hosts of the Traefik instances, or reads the IPs of their
+// interfaces, every interval and sends on trigger when their IPs change, e.g. after a new
+// DHCP lease or a failover, so the records follow them without waiting for the next sync.
+func watchTraefikIPs(interval time.Duration, trigger chan<- string) {
+	last := map[string]string{}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		changed := false
+		e := currentEngine()
+		for _, instance := range e.Instances {
+			client, ok := instance.Source.(*traefik.Client)
+			// With -cname only the instances of an interface publish their IPs
+			if instance.IP != nil || instance.Interface == "" && (!ok || e.CNAME) {
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			ips, err := e.InstanceIPs(ctx, instance)
+			cancel()
+			if err != nil {
+				continue
+			}
+			current := syncer.JoinIPs(ips)
+			previous, known := last[instance.URL]
+			last[instance.URL] = current
+			if known && previous != current {
+				infof("IPs of %s changed from %s to %s", instance.URL, previous, current)
+				if ok && instance.Interface == "" {
+					// The idle connections still go to the previous IPs
+					client.HTTPClient.CloseIdleConnections()
+				}
+				changed = true
+			}
+		}
+		if changed {
+			select {
+			case trigger <- "Traefik IP change":
+			default:
+			}
+		}
+		<-ticker.C
+	}
+}
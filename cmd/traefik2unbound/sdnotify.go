@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sdStatusInterval is the time between the STATUS updates sent to systemd without a watchdog.
+const sdStatusInterval = 10 * time.Second
+
+// syncLoop is when the sync in progress of the daemon started, zero between syncs, so the
+// watchdog of systemd restarts a daemon stuck in a sync.
+var syncLoop struct {
+	mu      sync.Mutex
+	started time.Time
+}
+
+func startSyncLoop() {
+	syncLoop.mu.Lock()
+	defer syncLoop.mu.Unlock()
+	syncLoop.started = time.Now()
+}
+
+func endSyncLoop() {
+	syncLoop.mu.Lock()
+	defer syncLoop.mu.Unlock()
+	syncLoop.started = time.Time{}
+}
+
+// sdNotify sends state to the notification socket of systemd, when the daemon is started by a
+// Type=notify service.
+func sdNotify(state string) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return
+	}
+	// Abstract sockets start with @
+	if strings.HasPrefix(socket, "@") {
+		socket = "\x00" + socket[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		debugf("Error notifying systemd. %s", err)
+		return
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(state))
+	if err != nil {
+		debugf("Error notifying systemd. %s", err)
+	}
+}
+
+// sdWatchdogInterval returns the interval of the watchdog of systemd set with WatchdogSec, 0
+// when it isn't enabled for this process.
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if pid := os.Getenv("WATCHDOG_PID"); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return 0
+	}
+	return time.Duration(usec) * time.Microsecond
+}
+
+// superviseWithSystemd keeps the STATUS of the service up to date with the last sync and,
+// when the watchdog is enabled, pings it twice per interval unless a sync has been running for
+// longer than it, so systemd restarts the daemon, e.g. when a request hangs.
+func superviseWithSystemd() {
+	if os.Getenv("NOTIFY_SOCKET") == "" {
+		return
+	}
+	watchdog := sdWatchdogInterval()
+	period := sdStatusInterval
+	if watchdog > 0 {
+		period = watchdog / 2
+	}
+	stuck := false
+	for range time.Tick(period) {
+		syncLoop.mu.Lock()
+		started := syncLoop.started
+		syncLoop.mu.Unlock()
+
+		state := "STATUS=" + syncStatus(started)
+		if watchdog > 0 {
+			if started.IsZero() || time.Since(started) < watchdog {
+				state += "\nWATCHDOG=1"
+				stuck = false
+			} else if !stuck {
+				stuck = true
+				errorf("Sync running for %s, longer than the systemd watchdog, which restarts the daemon", time.Since(started).Round(time.Second))
+			}
+		}
+		sdNotify(state)
+	}
+}
+
+// syncStatus describes the last syncs of the daemon for the STATUS of the service.
+func syncStatus(started time.Time) string {
+	metrics.mu.Lock()
+	records, lastSuccess, lastError := metrics.records, metrics.lastSuccess, metrics.lastSyncError
+	metrics.mu.Unlock()
+
+	status := "No successful sync yet"
+	if !lastSuccess.IsZero() {
+		status = fmt.Sprintf("Last sync %s ago, %d records", time.Since(lastSuccess).Round(time.Second), records)
+	}
+	if lastError != "" {
+		status += ". The last one failed: " + lastError
+	}
+	if !started.IsZero() {
+		status += fmt.Sprintf(". Syncing for %s", time.Since(started).Round(time.Second))
+	}
+	return status
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// secretFlag is a flag with a credential that can also be read from a file, given with its
+// -file flag or its environment variable with the _FILE suffix like the Docker secrets, so it
+// doesn't show up in the arguments of the process.
+type secretFlag struct {
+	name string
+	env  string
+	file *string
+}
+
+var (
+	traefikTokenFile    string
+	traefikPasswordFile string
+)
+
+var secretFlags = []secretFlag{
+	{name: "token", env: "TRAEFIK_TOKEN", file: &traefikTokenFile},
+	{name: "password", env: "TRAEFIK_PASSWORD", file: &traefikPasswordFile},
+}
+
+// allSecretFlags returns secretFlags and the secret flags of the backends.
+func allSecretFlags() []secretFlag {
+	all := append([]secretFlag{}, secretFlags...)
+	for _, factory := range sortedBackendFactories() {
+		all = append(all, factory.secrets...)
+	}
+	return all
+}
+
+// loadSecrets sets the secret flags of fs from their -file flag or, when neither the flag nor
+// its environment variable has a value, from the file of its _FILE environment variable.
+func loadSecrets(fs *flag.FlagSet) error {
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, secret := range allSecretFlags() {
+		f := fs.Lookup(secret.name)
+		if f == nil {
+			continue
+		}
+		path := ""
+		if secret.file != nil && *secret.file != "" {
+			if set[secret.name] {
+				return fmt.Errorf("-%s and -%s-file are mutually exclusive", secret.name, secret.name)
+			}
+			path = *secret.file
+		} else if f.Value.String() == "" {
+			path = os.Getenv(secret.env + "_FILE")
+		}
+		if path == "" {
+			continue
+		}
+		contents, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading -%s from %s. %s", secret.name, path, err)
+		}
+		err = fs.Set(secret.name, strings.TrimRight(string(contents), "\r\n"))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkConfigSecrets fails when the config file at path has secrets in settings, also in its
+// tables and lists like the outputs, but can be read by other users than its owner.
+func checkConfigSecrets(path string, settings map[string]interface{}) error {
+	names := map[string]bool{}
+	for _, secret := range allSecretFlags() {
+		names[secret.name] = true
+	}
+	keys := map[string]bool{}
+	findSecrets(settings, names, keys)
+	found := make([]string, 0, len(keys))
+	for key := range keys {
+		found = append(found, key)
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		sort.Strings(found)
+		return fmt.Errorf("it sets %s but its permissions %04o let other users read it. Restrict them to 0600", strings.Join(found, ", "), info.Mode().Perm())
+	}
+	return nil
+}
+
+// findSecrets adds to found the keys in names of the settings of value and of the tables and
+// lists inside it.
+func findSecrets(value interface{}, names map[string]bool, found map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if names[key] {
+				found[key] = true
+			}
+			findSecrets(item, names, found)
+		}
+	case []map[string]interface{}:
+		for _, item := range v {
+			findSecrets(item, names, found)
+		}
+	case []interface{}:
+		for _, item := range v {
+			findSecrets(item, names, found)
+		}
+	}
+}
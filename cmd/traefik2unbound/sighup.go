@@ -0,0 +1,185 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"reflect"
+	"sort"
+)
+
+// checkConfigEnv makes the process only check its flags and config file and exit, so the
+// daemon can validate a changed config file before reloading it.
+const checkConfigEnv = "TRAEFIK2UNBOUND_CHECK_CONFIG"
+
+// loadedSettings are the settings of the config file loaded last.
+var loadedSettings map[string]interface{}
+
+// reloadConfig reloads the changed config file and Traefik URLs file without restarting,
+// once a new process checked them so an invalid change keeps the daemon running with the
+// current settings. The new engine keeps the records of the last sync, so the hosts the new
+// settings drop are removed like any other. The watchers, the HTTP endpoints and the logging
+// are set up when the daemon starts, so their settings need a restart.
+func reloadConfig() {
+	infof("Received SIGHUP, checking the configuration")
+	if configFilePath != "" {
+		logConfigChanges(configFilePath)
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		errorf("Error reloading the configuration. %s", err)
+		return
+	}
+	check := exec.Command(executable, os.Args[1:]...)
+	check.Env = append(os.Environ(), checkConfigEnv+"=1")
+	check.Stdout = os.Stdout
+	check.Stderr = os.Stderr
+	err = check.Run()
+	if err != nil {
+		errorf("Keeping the current configuration since the new one is invalid. %s", err)
+		return
+	}
+
+	sdNotify("RELOADING=1")
+	defer sdNotify("READY=1")
+	before := restartSettings()
+	err = parseSettings()
+	if err != nil {
+		errorf("Error reloading the configuration. %s", err)
+		return
+	}
+	setupSources()
+	validateOutput()
+	next, err := engine.Reload(engineOptions())
+	if err != nil {
+		errorf("Keeping the current configuration since the new one is invalid. %s", err)
+		return
+	}
+	setEngine(next)
+	after := restartSettings()
+	for _, name := range sortedSettingNames(after) {
+		if before[name] != after[name] {
+			warnf("Setting %s changed from %s to %s, restart the daemon to apply it", name, before[name], after[name])
+		}
+	}
+	infof("Reloaded the configuration")
+}
+
+// parseSettings parses the command line, the config file and the secrets again into the
+// settings, as when the process started.
+func parseSettings() error {
+	resetListSettings()
+	known := allFlags()
+	_, args := splitCommand(os.Args[1:])
+	fs := newFlagSet(activeCommand, hasCommand, flag.ContinueOnError)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if configFilePath != "" {
+		err := loadConfig(configFilePath, fs, known)
+		if err != nil {
+			return fmt.Errorf("error loading config file %s. %s", configFilePath, err)
+		}
+	}
+	err = loadSecrets(fs)
+	if err != nil {
+		return err
+	}
+	if debug {
+		logLevelName = logLevelNames[levelDebug]
+	}
+	return nil
+}
+
+// resetListSettings empties the settings that append the values of their flags, which
+// registering the flags again doesn't reset, and the ones set from the flags.
+func resetListSettings() {
+	traefikURLs, notifyEvents, internalZones, regexpCandidates = nil, nil, nil, nil
+	includeProviders, excludeProviders, includeEntryPoints = nil, nil, nil
+	includeHosts, excludeHosts, allowedCIDRs = nil, nil, nil
+	backends, notifiers, unboundViews = nil, nil, nil
+	ttlOverrides, ptrCanonicalNames, unboundLocalZones = hostTTLs{}, ptrNames{}, localZones{}
+	configInstances, configOutputs = nil, nil
+	traefikResolver = nil
+}
+
+// restartSettings returns the values of the settings only read when the daemon starts.
+func restartSettings() map[string]string {
+	return map[string]string{
+		"log-level":           logLevelName,
+		"log-format":          logFormat,
+		"metrics-address":     metricsAddress,
+		"health-address":      healthAddress,
+		"docker-events":       fmt.Sprint(dockerEventsEnabled),
+		"docker-socket":       dockerSocket,
+		"docker-debounce":     dockerDebounce.String(),
+		"debounce-max-wait":   debounceMaxWait.String(),
+		"kubernetes-watch":    fmt.Sprint(kubernetesWatch),
+		"kubernetes-debounce": kubernetesDebounce.String(),
+		"resolve-interval":    resolveInterval.String(),
+	}
+}
+
+func sortedSettingNames(settings map[string]string) []string {
+	names := make([]string, 0, len(settings))
+	for name := range settings {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// logConfigChanges logs the settings of the config file at path that changed since the process
+// started, without the values of the secrets and lists.
+func logConfigChanges(path string) {
+	settings, err := readConfig(path)
+	if err != nil {
+		return
+	}
+	keys := []string{}
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	for key := range loadedSettings {
+		if _, ok := settings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	changes := 0
+	for _, key := range keys {
+		old, hadOld := loadedSettings[key]
+		value, hasValue := settings[key]
+		if hadOld && hasValue && reflect.DeepEqual(old, value) {
+			continue
+		}
+		changes++
+		switch {
+		case !hadOld:
+			infof("Setting %s added%s", key, settingValue(key, " with ", value))
+		case !hasValue:
+			infof("Setting %s removed", key)
+		default:
+			infof("Setting %s changed%s%s", key, settingValue(key, " from ", old), settingValue(key, " to ", value))
+		}
+	}
+	if changes == 0 {
+		infof("No setting of %s changed", path)
+	}
+}
+
+// settingValue returns prefix followed by value, or nothing for secrets and lists.
+func settingValue(key string, prefix string, value interface{}) string {
+	for _, secret := range allSecretFlags() {
+		if key == secret.name {
+			return ""
+		}
+	}
+	switch value.(type) {
+	case []interface{}, map[string]interface{}:
+		return ""
+	}
+	return fmt.Sprintf("%s%v", prefix, value)
+}
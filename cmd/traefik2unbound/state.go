@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/syncer"
+)
+
+func printStatus(path string) {
+	state, err := syncer.ReadState(path)
+	if err != nil {
+		fatalf("Error reading sync state from %s. %s", path, err)
+	}
+
+	fmt.Printf("Last successful sync: %s (%s ago)\n", state.Timestamp.Format(time.RFC3339), time.Since(state.Timestamp).Round(time.Second))
+	fmt.Printf("Hosts: %d\n", state.Hosts)
+	if len(state.Files) == 0 {
+		fmt.Printf("File hash: %s\n", state.FileHash)
+	}
+	for _, file := range state.Files {
+		fmt.Printf("File %s (%s): %s\n", file.Path, file.Backend, file.Hash)
+	}
+	fmt.Println("Sources:")
+	for _, source := range state.Sources {
+		if source.Success && len(source.Missing) > 0 {
+			fmt.Printf("  %s: ok, %d records, %d hosts kept until -removal-grace passes\n", source.URL, len(source.Records), len(source.Missing))
+		} else if source.Success {
+			fmt.Printf("  %s: ok, %d records\n", source.URL, len(source.Records))
+		} else if source.Stale {
+			fmt.Printf("  %s: failed, using the records from %s ago, %s\n", source.URL, time.Since(source.FetchedAt).Round(time.Second), source.Error)
+		} else {
+			fmt.Printf("  %s: failed, %s\n", source.URL, source.Error)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Package docker retrieves the routers Traefik builds from the labels of the Docker or
+// Podman containers, without going through the Traefik API.
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// Provider is the provider of the routers, as named by Traefik.
+const Provider = "docker"
+
+// labelEnable is the label that exposes a container to Traefik or hides it.
+const labelEnable = "traefik.enable"
+
+// routerLabelPrefixes are the prefixes of the labels of the HTTP and TCP routers, followed
+// by the router name and its option, like traefik.http.routers.myapp.rule.
+var routerLabelPrefixes = []string{"traefik.http.routers.", "traefik.tcp.routers."}
+
+// Client retrieves the routers of the containers of the Docker API at URL, which is
+// http://docker for HTTPClient talking to a unix socket.
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+	// ExposedByDefault publishes the containers without a traefik.enable label, like the
+	// setting of the Traefik Docker provider with the same name.
+	ExposedByDefault bool
+}
+
+type container struct {
+	Labels map[string]string `json:"Labels"`
+}
+
+// Routers returns the routers of the labels of the running containers, sorted by name.
+func (c *Client) Routers(ctx context.Context) ([]traefik.Router, error) {
+	containers, err := c.containers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replicas of a service define the same routers
+	byName := map[string]traefik.Router{}
+	for _, container := range containers {
+		if !IsExposed(container.Labels, c.ExposedByDefault) {
+			continue
+		}
+		for key, router := range LabelRouters(container.Labels) {
+			byName[key] = router
+		}
+	}
+
+	keys := make([]string, 0, len(byName))
+	for key := range byName {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	routers := make([]traefik.Router, 0, len(keys))
+	for _, key := range keys {
+		routers = append(routers, byName[key])
+	}
+	return routers, nil
+}
+
+func (c *Client) containers(ctx context.Context) ([]container, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.URL, "/")+"/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve the containers from Docker. %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("response from Docker not successful. Status: %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Docker response body. %s", err)
+	}
+	containers := []container{}
+	err = json.Unmarshal(body, &containers)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling Docker response body. %s", err)
+	}
+	return containers, nil
+}
+
+// IsExposed reports whether the container with labels is exposed to Traefik by its
+// traefik.enable label, or by exposedByDefault when it doesn't have one.
+func IsExposed(labels map[string]string, exposedByDefault bool) bool {
+	value, ok := labels[labelEnable]
+	if !ok {
+		return exposedByDefault
+	}
+	enabled, err := strconv.ParseBool(value)
+	return err == nil && enabled
+}
+
+// LabelRouters returns the routers with a rule of the labels of a container, keyed by their
+// prefix and name.
+func LabelRouters(labels map[string]string) map[string]traefik.Router {
+	routers := map[string]traefik.Router{}
+	for label, value := range labels {
+		for _, prefix := range routerLabelPrefixes {
+			if !strings.HasPrefix(label, prefix) {
+				continue
+			}
+			name, option, ok := strings.Cut(strings.TrimPrefix(label, prefix), ".")
+			if !ok {
+				continue
+			}
+			key := prefix + name
+			router, ok := routers[key]
+			if !ok {
+				router = traefik.Router{Name: name + "@" + Provider, Provider: Provider, Status: traefik.StatusEnabled}
+			}
+			switch strings.ToLower(option) {
+			case "rule":
+				router.Rule = value
+			case "service":
+				router.Service = value
+			case "entrypoints":
+				router.EntryPoints = splitLabel(value)
+			case "middlewares":
+				router.Middlewares = splitLabel(value)
+			}
+			routers[key] = router
+		}
+	}
+	for key, router := range routers {
+		if router.Rule == "" {
+			delete(routers, key)
+		}
+	}
+	return routers
+}
+
+// splitLabel splits the comma separated values of a label.
+func splitLabel(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
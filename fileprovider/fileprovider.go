@@ -0,0 +1,196 @@
+// Package fileprovider retrieves the routers of the Traefik file provider dynamic configuration
+// and of the labels of Docker Compose files, reading them from disk without the Traefik API.
+package fileprovider
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/dcasado/traefik2unbound/docker"
+	"github.com/dcasado/traefik2unbound/traefik"
+	"gopkg.in/yaml.v3"
+)
+
+// Provider is the provider of the routers of the dynamic configuration, as named by Traefik.
+const Provider = "file"
+
+// Source reads the routers of the file or of the files of the directory at Path. Like the
+// Traefik file provider, directories are read recursively and only their .yml, .yaml and
+// .toml files are read.
+type Source struct {
+	Path string
+}
+
+// Routers returns the routers of the files, in the order of the files.
+func (s *Source) Routers(ctx context.Context) ([]traefik.Router, error) {
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return fileRouters(s.Path)
+	}
+
+	routers := []traefik.Router{}
+	err = filepath.Walk(s.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isConfigFile(path) {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fileRouters, err := fileRouters(path)
+		if err != nil {
+			return err
+		}
+		routers = append(routers, fileRouters...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return routers, nil
+}
+
+func isConfigFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yml", ".yaml", ".toml":
+		return true
+	}
+	return false
+}
+
+// fileRouters returns the routers of the dynamic configuration in path or, for Docker Compose
+// files, the routers of the labels of their services.
+func fileRouters(path string) ([]traefik.Router, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := map[string]interface{}{}
+	if strings.ToLower(filepath.Ext(path)) == ".toml" {
+		err = toml.Unmarshal(contents, &config)
+	} else {
+		err = yaml.Unmarshal(contents, &config)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s. %s", path, err)
+	}
+
+	if lookup(config, "http") == nil && lookup(config, "tcp") == nil && lookup(config, "services") != nil {
+		return composeRouters(config), nil
+	}
+	routers := []traefik.Router{}
+	for _, protocol := range []string{"http", "tcp"} {
+		section, _ := lookup(config, protocol).(map[string]interface{})
+		named, _ := lookup(section, "routers").(map[string]interface{})
+		for _, name := range sortedKeys(named) {
+			router, _ := named[name].(map[string]interface{})
+			rule, _ := lookup(router, "rule").(string)
+			if rule == "" {
+				continue
+			}
+			service, _ := lookup(router, "service").(string)
+			routers = append(routers, traefik.Router{
+				Name:        name + "@" + Provider,
+				Rule:        rule,
+				Service:     service,
+				Provider:    Provider,
+				Status:      traefik.StatusEnabled,
+				EntryPoints: stringList(lookup(router, "entryPoints")),
+				Middlewares: stringList(lookup(router, "middlewares")),
+			})
+		}
+	}
+	return routers, nil
+}
+
+// composeRouters returns the routers of the Traefik labels of the services of a Docker Compose
+// file, exposed by default like with the Traefik Docker provider.
+func composeRouters(config map[string]interface{}) []traefik.Router {
+	routers := []traefik.Router{}
+	services, _ := lookup(config, "services").(map[string]interface{})
+	for _, name := range sortedKeys(services) {
+		service, _ := services[name].(map[string]interface{})
+		labels := composeLabels(lookup(service, "labels"))
+		deploy, _ := lookup(service, "deploy").(map[string]interface{})
+		for key, value := range composeLabels(lookup(deploy, "labels")) {
+			labels[key] = value
+		}
+		if !docker.IsExposed(labels, true) {
+			continue
+		}
+		named := docker.LabelRouters(labels)
+		keys := make([]string, 0, len(named))
+		for key := range named {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			routers = append(routers, named[key])
+		}
+	}
+	return routers
+}
+
+// composeLabels returns the labels of a service, given as a map or as a list of key=value.
+func composeLabels(value interface{}) map[string]string {
+	labels := map[string]string{}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, label := range v {
+			labels[key] = fmt.Sprint(label)
+		}
+	case []interface{}:
+		for _, item := range v {
+			label, ok := item.(string)
+			if !ok {
+				continue
+			}
+			key, value, _ := strings.Cut(label, "=")
+			labels[key] = value
+		}
+	}
+	return labels
+}
+
+// lookup returns the value of key in table, matching the keys case insensitively like Traefik.
+func lookup(table map[string]interface{}, key string) interface{} {
+	if value, ok := table[key]; ok {
+		return value
+	}
+	for k, value := range table {
+		if strings.EqualFold(k, key) {
+			return value
+		}
+	}
+	return nil
+}
+
+func stringList(value interface{}) []string {
+	items, _ := value.([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
+
+func sortedKeys(table map[string]interface{}) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,69 @@
+package fileprovider
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name string, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	err := os.WriteFile(path, []byte(contents), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFileRoutersTOML(t *testing.T) {
+	path := writeFile(t, "dynamic.toml", `
+[http.routers.whoami]
+  rule = "Host(`+"`whoami.example.com`"+`)"
+  service = "whoami"
+  entryPoints = ["web", "websecure"]
+
+[http.routers."no-rule"]
+  service = "whoami"
+
+[tcp.routers]
+  db = { rule = "HostSNI(`+"`db.example.com`"+`)", service = "db" }
+`)
+
+	routers, err := fileRouters(path)
+	if err != nil {
+		t.Fatalf("fileRouters() failed. %s", err)
+	}
+	if len(routers) != 2 {
+		t.Fatalf("fileRouters() = %v, want 2 routers", routers)
+	}
+	if routers[0].Name != "whoami@file" || routers[0].Rule != "Host(`whoami.example.com`)" || len(routers[0].EntryPoints) != 2 {
+		t.Errorf("first router = %+v, want whoami@file with its rule and 2 entry points", routers[0])
+	}
+	if routers[1].Name != "db@file" || routers[1].Service != "db" {
+		t.Errorf("second router = %+v, want db@file", routers[1])
+	}
+}
+
+func TestFileRoutersInvalidTOML(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+	}{
+		{"leading zero", "[http]\nretries = 010\n"},
+		{"octal prefix without digits", "[http]\nretries = 0o\n"},
+		{"redefined table", "[http.routers.a]\nrule = \"Host(`a`)\"\n[http.routers.a]\nservice = \"a\"\n"},
+		{"table extending an inline table", "[http]\nrouters = { a = { rule = \"Host(`a`)\" } }\n[http.routers]\nb = { rule = \"Host(`b`)\" }\n"},
+		{"duplicated key", "[http.routers.a]\nrule = \"Host(`a`)\"\nrule = \"Host(`b`)\"\n"},
+		{"unterminated string", "[http.routers.a]\nrule = \"Host(`a`)\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeFile(t, "dynamic.toml", test.contents)
+			routers, err := fileRouters(path)
+			if err == nil {
+				t.Errorf("fileRouters() = %v, want an error", routers)
+			}
+		})
+	}
+}
@@ -1,3 +1,12 @@
 module github.com/dcasado/traefik2unbound
 
 go 1.18
+
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/godbus/dbus/v5 v5.1.0
+	golang.org/x/net v0.17.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/text v0.13.0 // indirect
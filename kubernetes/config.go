@@ -0,0 +1,208 @@
+package kubernetes
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// serviceAccountDir is where the service account of a pod is mounted.
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// Config is how to connect and authenticate to the Kubernetes API.
+type Config struct {
+	Server string
+	// Token is sent as a bearer token. TokenFile is read on every request instead, since
+	// the service account tokens are rotated. Otherwise Username and Password are sent
+	// with basic authentication when Username is set.
+	Token     string
+	TokenFile string
+	Username  string
+	Password  string
+	TLS       *tls.Config
+}
+
+// InClusterConfig returns the config of the service account of the pod the process runs in.
+func InClusterConfig() (Config, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return Config{}, errors.New("not running inside a Kubernetes cluster")
+	}
+	pem, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return Config{}, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return Config{}, errors.New("no certificates found in the service account CA")
+	}
+	return Config{
+		Server:    "https://" + net.JoinHostPort(host, port),
+		TokenFile: filepath.Join(serviceAccountDir, "token"),
+		TLS:       &tls.Config{RootCAs: pool},
+	}, nil
+}
+
+// DefaultKubeconfig returns the first file of $KUBECONFIG or ~/.kube/config.
+func DefaultKubeconfig() string {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+			TLSServerName            string `yaml:"tls-server-name"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string `yaml:"token"`
+			TokenFile             string `yaml:"tokenFile"`
+			ClientCertificate     string `yaml:"client-certificate"`
+			ClientCertificateData string `yaml:"client-certificate-data"`
+			ClientKey             string `yaml:"client-key"`
+			ClientKeyData         string `yaml:"client-key-data"`
+			Username              string `yaml:"username"`
+			Password              string `yaml:"password"`
+			Exec                  *struct {
+				Command string `yaml:"command"`
+			} `yaml:"exec"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+}
+
+// LoadKubeconfig returns the config of the context of the kubeconfig file at path, or of its
+// current context when context is empty. Exec and auth provider plugins are not supported.
+func LoadKubeconfig(path string, context string) (Config, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return Config{}, err
+	}
+	file := kubeconfig{}
+	err = yaml.Unmarshal(contents, &file)
+	if err != nil {
+		return Config{}, err
+	}
+	if context == "" {
+		context = file.CurrentContext
+	}
+	// Relative paths are relative to the kubeconfig file
+	dir := filepath.Dir(path)
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	for _, c := range file.Contexts {
+		if c.Name != context {
+			continue
+		}
+		config := Config{TLS: &tls.Config{}}
+		found := false
+		for _, cluster := range file.Clusters {
+			if cluster.Name != c.Context.Cluster {
+				continue
+			}
+			found = true
+			config.Server = cluster.Cluster.Server
+			config.TLS.InsecureSkipVerify = cluster.Cluster.InsecureSkipTLSVerify
+			config.TLS.ServerName = cluster.Cluster.TLSServerName
+			pem, err := fileOrData(resolve(cluster.Cluster.CertificateAuthority), cluster.Cluster.CertificateAuthorityData)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid certificate authority of cluster %s. %s", cluster.Name, err)
+			}
+			if pem != nil {
+				pool := x509.NewCertPool()
+				if !pool.AppendCertsFromPEM(pem) {
+					return Config{}, fmt.Errorf("no certificates found in the certificate authority of cluster %s", cluster.Name)
+				}
+				config.TLS.RootCAs = pool
+			}
+		}
+		if !found {
+			return Config{}, fmt.Errorf("cluster %s of context %s not found", c.Context.Cluster, context)
+		}
+
+		for _, user := range file.Users {
+			if user.Name != c.Context.User {
+				continue
+			}
+			if user.User.Exec != nil {
+				return Config{}, fmt.Errorf("user %s authenticates with the exec plugin %s, which is not supported", user.Name, user.User.Exec.Command)
+			}
+			config.Token = user.User.Token
+			config.TokenFile = resolve(user.User.TokenFile)
+			config.Username = user.User.Username
+			config.Password = user.User.Password
+			cert, err := fileOrData(resolve(user.User.ClientCertificate), user.User.ClientCertificateData)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid client certificate of user %s. %s", user.Name, err)
+			}
+			key, err := fileOrData(resolve(user.User.ClientKey), user.User.ClientKeyData)
+			if err != nil {
+				return Config{}, fmt.Errorf("invalid client key of user %s. %s", user.Name, err)
+			}
+			if cert != nil || key != nil {
+				certificate, err := tls.X509KeyPair(cert, key)
+				if err != nil {
+					return Config{}, fmt.Errorf("invalid client certificate of user %s. %s", user.Name, err)
+				}
+				config.TLS.Certificates = []tls.Certificate{certificate}
+			}
+		}
+		return config, nil
+	}
+	return Config{}, fmt.Errorf("context %q not found in %s", context, path)
+}
+
+// fileOrData returns the contents of path, or data decoded from base64 when path is empty.
+func fileOrData(path string, data string) ([]byte, error) {
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	if data == "" {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(strings.TrimSpace(data))
+}
+
+// NewHTTPClient returns an HTTP client for the Kubernetes API of config whose requests time out
+// after timeout. A 0 timeout is needed for watches, which keep the connection open.
+func NewHTTPClient(config Config, timeout time.Duration) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = config.TLS
+	return &http.Client{Transport: transport, Timeout: timeout}
+}
@@ -0,0 +1,295 @@
+// Package kubernetes retrieves the routers Traefik builds from the Ingresses and the
+// IngressRoute and IngressRouteTCP resources of a Kubernetes cluster.
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// Providers of the routers, as named by Traefik.
+const (
+	ProviderIngress = "kubernetes"
+	ProviderCRD     = "kubernetescrd"
+)
+
+// Annotations Traefik reads from the Ingresses and the CRDs.
+const (
+	annotationIngressClass = "kubernetes.io/ingress.class"
+	annotationEntryPoints  = "traefik.ingress.kubernetes.io/router.entrypoints"
+	annotationMiddlewares  = "traefik.ingress.kubernetes.io/router.middlewares"
+)
+
+// resource is a kind of object the routers are built from.
+type resource struct {
+	// groupVersions are the API group versions serving the resource, tried in order since
+	// the Traefik CRDs moved from traefik.containo.us to traefik.io.
+	groupVersions []string
+	name          string
+	routers       func(items []json.RawMessage, ingressClass string, warnf output.Logf) ([]traefik.Router, error)
+}
+
+var resources = []resource{
+	{[]string{"networking.k8s.io/v1"}, "ingresses", ingressRouters},
+	{[]string{"traefik.io/v1alpha1", "traefik.containo.us/v1alpha1"}, "ingressroutes", ingressRouteRouters},
+	{[]string{"traefik.io/v1alpha1", "traefik.containo.us/v1alpha1"}, "ingressroutetcps", ingressRouteRouters},
+}
+
+// Client retrieves the routers of the Kubernetes API of Config.
+type Client struct {
+	Config     Config
+	HTTPClient *http.Client
+	// Namespace limits the resources to a namespace. Every namespace is read when empty.
+	Namespace string
+	// IngressClass only keeps the resources of the class, like Traefik does when its
+	// providers are given an ingressClass. Every resource is kept when empty.
+	IngressClass string
+	// Warnf is called with the hosts of the Ingresses that can't be published.
+	Warnf output.Logf
+}
+
+// objectMeta is the metadata shared by every resource.
+type objectMeta struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	Annotations map[string]string `json:"annotations"`
+}
+
+type list struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []json.RawMessage `json:"items"`
+}
+
+// Routers returns a router for every Ingress, IngressRoute and IngressRouteTCP. The Traefik
+// CRDs are skipped when they are not installed.
+func (c *Client) Routers(ctx context.Context) ([]traefik.Router, error) {
+	routers := []traefik.Router{}
+	for _, r := range resources {
+		_, items, _, err := c.list(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+		resourceRouters, err := r.routers(items, c.IngressClass, c.Warnf)
+		if err != nil {
+			return nil, fmt.Errorf("error decoding %s. %s", r.name, err)
+		}
+		routers = append(routers, resourceRouters...)
+	}
+	return routers, nil
+}
+
+// list returns the path the resource is served at, its items and the resource version of
+// the list. The path is empty when no group version serves the resource.
+func (c *Client) list(ctx context.Context, r resource) (string, []json.RawMessage, string, error) {
+	for _, groupVersion := range r.groupVersions {
+		path := c.path(groupVersion, r.name)
+		resp, err := c.do(ctx, c.HTTPClient, path)
+		if err != nil {
+			return "", nil, "", err
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			resp.Body.Close()
+			continue
+		}
+		result := list{}
+		err = decodeResponse(resp, path, &result)
+		if err != nil {
+			return "", nil, "", err
+		}
+		return path, result.Items, result.Metadata.ResourceVersion, nil
+	}
+	return "", nil, "", nil
+}
+
+func (c *Client) path(groupVersion string, name string) string {
+	if c.Namespace != "" {
+		return "/apis/" + groupVersion + "/namespaces/" + url.PathEscape(c.Namespace) + "/" + name
+	}
+	return "/apis/" + groupVersion + "/" + name
+}
+
+func (c *Client) do(ctx context.Context, client *http.Client, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(c.Config.Server, "/")+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = c.setAuthorization(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve %s from the Kubernetes API. %s", path, err)
+	}
+	return resp, nil
+}
+
+// setAuthorization adds the credentials of the config to req.
+func (c *Client) setAuthorization(req *http.Request) error {
+	token := c.Config.Token
+	if c.Config.TokenFile != "" {
+		contents, err := os.ReadFile(c.Config.TokenFile)
+		if err != nil {
+			return fmt.Errorf("error reading the Kubernetes token. %s", err)
+		}
+		token = strings.TrimSpace(string(contents))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if c.Config.Username != "" {
+		req.SetBasicAuth(c.Config.Username, c.Config.Password)
+	}
+	return nil
+}
+
+func decodeResponse(resp *http.Response, path string, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("response from the Kubernetes API for %s not successful. Status: %s", path, resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading Kubernetes response body. %s", err)
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling Kubernetes response body. %s", err)
+	}
+	return nil
+}
+
+type ingress struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		IngressClassName string `json:"ingressClassName"`
+		Rules            []struct {
+			Host string `json:"host"`
+		} `json:"rules"`
+	} `json:"spec"`
+}
+
+// ingressRouters returns a router matching the hosts of the rules of each Ingress, with the
+// entry points and middlewares of its Traefik annotations. A wildcard host like *.example.com
+// is matched as the wildcard host, like in the rule of a Docker label, and the other hosts
+// with a * or a backtick, which can't be written in a rule, are skipped.
+func ingressRouters(items []json.RawMessage, ingressClass string, warnf output.Logf) ([]traefik.Router, error) {
+	routers := make([]traefik.Router, 0, len(items))
+	for _, item := range items {
+		i := ingress{}
+		err := json.Unmarshal(item, &i)
+		if err != nil {
+			return nil, err
+		}
+		class := i.Spec.IngressClassName
+		if class == "" {
+			class = i.Metadata.Annotations[annotationIngressClass]
+		}
+		if ingressClass != "" && class != ingressClass {
+			continue
+		}
+
+		matchers := []string{}
+		for _, rule := range i.Spec.Rules {
+			if rule.Host == "" {
+				continue
+			}
+			if strings.Contains(strings.TrimPrefix(rule.Host, output.WildcardPrefix), "*") || strings.Contains(rule.Host, "`") {
+				warnf.Printf("Skipping host %s of the Ingress %s/%s. Only wildcards of a whole label like *.example.com are supported", rule.Host, i.Metadata.Namespace, i.Metadata.Name)
+				continue
+			}
+			matchers = append(matchers, "Host(`"+rule.Host+"`)")
+		}
+		if len(matchers) == 0 {
+			continue
+		}
+		routers = append(routers, traefik.Router{
+			Name:        i.Metadata.Namespace + "-" + i.Metadata.Name + "@" + ProviderIngress,
+			Rule:        strings.Join(matchers, " || "),
+			Provider:    ProviderIngress,
+			Status:      traefik.StatusEnabled,
+			EntryPoints: splitAnnotation(i.Metadata.Annotations[annotationEntryPoints]),
+			Middlewares: splitAnnotation(i.Metadata.Annotations[annotationMiddlewares]),
+		})
+	}
+	return routers, nil
+}
+
+type ingressRoute struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		EntryPoints []string `json:"entryPoints"`
+		Routes      []struct {
+			Match    string `json:"match"`
+			Services []struct {
+				Name string `json:"name"`
+			} `json:"services"`
+			Middlewares []struct {
+				Name      string `json:"name"`
+				Namespace string `json:"namespace"`
+			} `json:"middlewares"`
+		} `json:"routes"`
+	} `json:"spec"`
+}
+
+// ingressRouteRouters returns a router for every route of each IngressRoute or IngressRouteTCP.
+func ingressRouteRouters(items []json.RawMessage, ingressClass string, warnf output.Logf) ([]traefik.Router, error) {
+	routers := []traefik.Router{}
+	for _, item := range items {
+		i := ingressRoute{}
+		err := json.Unmarshal(item, &i)
+		if err != nil {
+			return nil, err
+		}
+		if ingressClass != "" && i.Metadata.Annotations[annotationIngressClass] != ingressClass {
+			continue
+		}
+
+		for _, route := range i.Spec.Routes {
+			router := traefik.Router{
+				Name:        i.Metadata.Namespace + "-" + i.Metadata.Name + "@" + ProviderCRD,
+				Rule:        route.Match,
+				Provider:    ProviderCRD,
+				Status:      traefik.StatusEnabled,
+				EntryPoints: i.Spec.EntryPoints,
+			}
+			if len(route.Services) > 0 {
+				router.Service = route.Services[0].Name
+			}
+			for _, middleware := range route.Middlewares {
+				name := middleware.Name
+				if !strings.Contains(name, "@") {
+					namespace := middleware.Namespace
+					if namespace == "" {
+						namespace = i.Metadata.Namespace
+					}
+					name = namespace + "-" + name + "@" + ProviderCRD
+				}
+				router.Middlewares = append(router.Middlewares, name)
+			}
+			routers = append(routers, router)
+		}
+	}
+	return routers, nil
+}
+
+// splitAnnotation splits the comma separated values of an annotation.
+func splitAnnotation(value string) []string {
+	values := []string{}
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestIngressRouters(t *testing.T) {
+	tests := []struct {
+		name     string
+		hosts    []string
+		wantRule string
+		warnings int
+	}{
+		{"host", []string{"whoami.example.com"}, "Host(`whoami.example.com`)", 0},
+		{"several hosts", []string{"a.example.com", "b.example.com"}, "Host(`a.example.com`) || Host(`b.example.com`)", 0},
+		{"wildcard", []string{"*.example.com"}, "Host(`*.example.com`)", 0},
+		{"wildcard inside a label", []string{"a*.example.com", "b.example.com"}, "Host(`b.example.com`)", 1},
+		{"nested wildcard", []string{"*.*.example.com"}, "", 1},
+		{"backtick", []string{"a`.example.com"}, "", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules := []map[string]string{}
+			for _, host := range test.hosts {
+				rules = append(rules, map[string]string{"host": host})
+			}
+			item, err := json.Marshal(map[string]interface{}{
+				"metadata": map[string]string{"name": "whoami", "namespace": "default"},
+				"spec":     map[string]interface{}{"rules": rules},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			warnings := []string{}
+			warnf := func(format string, args ...interface{}) {
+				warnings = append(warnings, fmt.Sprintf(format, args...))
+			}
+
+			routers, err := ingressRouters([]json.RawMessage{item}, "", warnf)
+			if err != nil {
+				t.Fatalf("ingressRouters() failed. %s", err)
+			}
+			if test.wantRule == "" {
+				if len(routers) != 0 {
+					t.Errorf("ingressRouters() = %v, want no routers", routers)
+				}
+			} else if len(routers) != 1 || routers[0].Rule != test.wantRule {
+				t.Errorf("ingressRouters() = %v, want a router with the rule %s", routers, test.wantRule)
+			}
+			if len(warnings) != test.warnings {
+				t.Errorf("got the warnings %q, want %d", warnings, test.warnings)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+)
+
+type watchEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+// Watch calls changed after every addition, modification or deletion of the resources the
+// routers are built from. It returns when ctx is done or a watch ends, which the API server
+// does every few minutes, with a nil error when the watch just timed out.
+func (c *Client) Watch(ctx context.Context, changed func()) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(resources))
+	watching := 0
+	for _, r := range resources {
+		path, _, version, err := c.list(ctx, r)
+		if err != nil {
+			return err
+		}
+		if path == "" {
+			continue
+		}
+		watching++
+		go func() {
+			errs <- c.watch(ctx, path, version, changed)
+		}()
+	}
+	if watching == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	return <-errs
+}
+
+// watch streams the events of the resource at path after version.
+func (c *Client) watch(ctx context.Context, path string, version string, changed func()) error {
+	// The timeout of the client would close the stream
+	client := *c.HTTPClient
+	client.Timeout = 0
+	resp, err := c.do(ctx, &client, path+"?watch=1&allowWatchBookmarks=true&resourceVersion="+url.QueryEscape(version))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("response from the Kubernetes API for %s not successful. Status: %s", path, resp.Status)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		event := watchEvent{}
+		err := decoder.Decode(&event)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error watching %s. %s", path, err)
+		}
+		switch event.Type {
+		case "ADDED", "MODIFIED", "DELETED":
+			changed()
+		case "ERROR":
+			return fmt.Errorf("error watching %s. %s", path, event.Object)
+		}
+	}
+}
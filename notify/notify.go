@@ -0,0 +1,155 @@
+// Package notify sends notifications about the changes and failures of the syncs to a
+// generic webhook, ntfy, Gotify or Slack.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Events of the notifications.
+const (
+	// EventChange is sent when a file changes and is applied.
+	EventChange = "change"
+	// EventInvalid is sent when a file fails its check and is restored.
+	EventInvalid = "invalid"
+	// EventReloadFailure is sent when a file can't be applied to its DNS server.
+	EventReloadFailure = "reload-failure"
+)
+
+// Events are the events notifications can be sent for.
+var Events = []string{EventChange, EventInvalid, EventReloadFailure}
+
+// Formats of the notifications.
+const (
+	// FormatWebhook posts the JSON of the Notification.
+	FormatWebhook = "webhook"
+	// FormatNtfy posts the text to the URL of an ntfy topic, with its title and priority in
+	// the headers.
+	FormatNtfy = "ntfy"
+	// FormatGotify posts a message to the /message URL of Gotify, with the token of the
+	// application in its token query parameter.
+	FormatGotify = "gotify"
+	// FormatSlack posts the text to a Slack incoming webhook, or anything compatible like
+	// Mattermost or Discord with /slack.
+	FormatSlack = "slack"
+)
+
+// Formats are the formats of the notifications.
+var Formats = []string{FormatWebhook, FormatNtfy, FormatGotify, FormatSlack}
+
+// Notification is an event of a file, e.g. the lines it added and removed.
+type Notification struct {
+	Event   string    `json:"event"`
+	Path    string    `json:"path"`
+	Title   string    `json:"title"`
+	Text    string    `json:"text"`
+	Added   []string  `json:"added,omitempty"`
+	Removed []string  `json:"removed,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// failure reports whether n is about something that needs fixing.
+func (n Notification) failure() bool {
+	return n.Event != EventChange
+}
+
+// Notifier sends notifications in Format to URL.
+type Notifier struct {
+	Format string
+	URL    string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Send sends the notification.
+func (n Notifier) Send(ctx context.Context, notification Notification) error {
+	var body []byte
+	var err error
+	headers := map[string]string{"Content-Type": "application/json"}
+	switch n.Format {
+	case FormatNtfy:
+		body = []byte(notification.Text)
+		headers = map[string]string{"Content-Type": "text/plain", "Title": notification.Title, "Tags": "traefik2unbound," + notification.Event}
+		if notification.failure() {
+			headers["Priority"] = "high"
+		}
+	case FormatGotify:
+		priority := 4
+		if notification.failure() {
+			priority = 8
+		}
+		body, err = json.Marshal(map[string]interface{}{"title": notification.Title, "message": notification.Text, "priority": priority})
+	case FormatSlack:
+		body, err = json.Marshal(map[string]string{"text": "*" + notification.Title + "*\n" + notification.Text})
+	default:
+		body, err = json.Marshal(notification)
+	}
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending the %s notification. %s", n.Format, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		response, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("response to the %s notification not successful. Status: %s, %s", n.Format, resp.Status, strings.TrimSpace(string(response)))
+	}
+	return nil
+}
+
+// ChangedLines returns the lines of newContents missing in oldContents and the ones of
+// oldContents missing in newContents, without the empty and comment lines.
+func ChangedLines(oldContents string, newContents string) (added []string, removed []string) {
+	oldLines := contentLines(oldContents)
+	newLines := contentLines(newContents)
+	for _, line := range newLines.order {
+		if !oldLines.set[line] {
+			added = append(added, line)
+		}
+	}
+	for _, line := range oldLines.order {
+		if !newLines.set[line] {
+			removed = append(removed, line)
+		}
+	}
+	return added, removed
+}
+
+type lines struct {
+	order []string
+	set   map[string]bool
+}
+
+func contentLines(contents string) lines {
+	l := lines{set: map[string]bool{}}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || l.set[line] {
+			continue
+		}
+		l.order = append(l.order, line)
+		l.set[line] = true
+	}
+	return l
+}
@@ -0,0 +1,41 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AdguardRewrite is a DNS rewrite of the AdGuard Home API.
+type AdguardRewrite struct {
+	Domain string `json:"domain"`
+	Answer string `json:"answer"`
+}
+
+// Adguard writes one "domain answer" line per record. The file keeps track of the rewrites
+// that are managed so the removed ones can be deleted from AdGuard Home.
+type Adguard struct{}
+
+// Write writes the unique records.
+func (Adguard) Write(builder *strings.Builder, records []Record) {
+	records = UniqueRecords(records)
+
+	for i, r := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("# Endpoints extracted from %s\n", r.Value))
+		}
+		builder.WriteString(fmt.Sprintf("%s %s\n", r.Host, strings.TrimSuffix(r.Value, ".")))
+	}
+}
+
+// ParseAdguardRewrites returns the rewrites of a file written by Adguard.
+func ParseAdguardRewrites(contents string) map[AdguardRewrite]bool {
+	rewrites := map[AdguardRewrite]bool{}
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		rewrites[AdguardRewrite{Domain: fields[0], Answer: fields[1]}] = true
+	}
+	return rewrites
+}
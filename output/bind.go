@@ -0,0 +1,143 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultBindTTL is the TTL of the zone file, and of its records when none is given.
+const DefaultBindTTL = 300
+
+// Bind writes the records of Zone in the format of a BIND zone file. Write only writes the
+// records, and WriteZone puts them in a zone with its SOA and NS records.
+type Bind struct {
+	Zone string
+	// Nameserver is the host of the NS record, ns1 of the zone by default. NameserverIP is
+	// published as its A or AAAA record when the nameserver is inside the zone.
+	Nameserver   string
+	NameserverIP string
+	// Hostmaster is the email of the SOA record with its @ replaced by a dot, hostmaster of
+	// the zone by default.
+	Hostmaster string
+	// TTL in seconds of the zone and the records. 0 uses DefaultBindTTL.
+	TTL int
+	// HostTTLs overrides TTL for single hosts.
+	HostTTLs map[string]int
+	// Warnf is called with the hosts outside of Zone, which are skipped.
+	Warnf Logf
+}
+
+// Write writes the unique records of the hosts of Zone, relative to it.
+func (b Bind) Write(builder *strings.Builder, records []Record) {
+	records = UniqueRecords(records)
+	zone := strings.TrimSuffix(b.Zone, ".")
+
+	for i, r := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("; Endpoints extracted from %s\n", r.Value))
+		}
+		if r.Host != zone && !strings.HasSuffix(r.Host, "."+zone) {
+			b.Warnf.Printf("Skipping host %s outside of the zone %s", r.Host, zone)
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%s %d IN %s %s\n", b.relative(r.Host), b.ttl(r.Host), r.Type, r.Value))
+	}
+}
+
+// WriteZone returns the zone file with the comment header and body, the records written by
+// Write. The serial of the SOA record of current, the previous zone file, is kept when nothing
+// else changed and bumped otherwise, to today's date followed by a two digit counter when it
+// is greater.
+func (b Bind) WriteZone(header string, body string, current string, now time.Time) string {
+	serial, ok := soaSerial(current)
+	if ok {
+		if contents := b.zone(header, body, serial); WithoutTimestamp(contents) == WithoutTimestamp(current) {
+			return contents
+		}
+	}
+	// The counter of the date has two digits, so after the 99th change of a day the serial goes
+	// on with serial+1, the first one of the next day, which the secondaries still see as newer
+	next := serial + 1
+	if dated, err := strconv.ParseUint(now.Format("20060102")+"00", 10, 32); err == nil && uint32(dated) > next {
+		next = uint32(dated)
+	}
+	return b.zone(header, body, next)
+}
+
+func (b Bind) zone(header string, body string, serial uint32) string {
+	zone := strings.TrimSuffix(b.Zone, ".")
+	ttl := b.TTL
+	if ttl <= 0 {
+		ttl = DefaultBindTTL
+	}
+	nameserver := strings.TrimSuffix(b.Nameserver, ".")
+	if nameserver == "" {
+		nameserver = "ns1." + zone
+	}
+	hostmaster := b.Hostmaster
+	if hostmaster == "" {
+		hostmaster = "hostmaster." + zone
+	}
+
+	builder := strings.Builder{}
+	if header != "" {
+		for _, line := range strings.Split(header, "\n") {
+			builder.WriteString(fmt.Sprintf("; %s\n", line))
+		}
+	}
+	builder.WriteString(fmt.Sprintf("$ORIGIN %s.\n", zone))
+	builder.WriteString(fmt.Sprintf("$TTL %d\n", ttl))
+	builder.WriteString(fmt.Sprintf("@ IN SOA %s %s (\n", absolute(nameserver), absolute(hostmaster)))
+	builder.WriteString(fmt.Sprintf("\t%d ; serial\n", serial))
+	builder.WriteString("\t3600 ; refresh\n")
+	builder.WriteString("\t600 ; retry\n")
+	builder.WriteString("\t604800 ; expire\n")
+	builder.WriteString(fmt.Sprintf("\t%d ) ; negative caching TTL\n", ttl))
+	builder.WriteString(fmt.Sprintf("@ IN NS %s\n", absolute(nameserver)))
+	if b.NameserverIP != "" && (nameserver == zone || strings.HasSuffix(nameserver, "."+zone)) {
+		recordType := "A"
+		if strings.Contains(b.NameserverIP, ":") {
+			recordType = "AAAA"
+		}
+		builder.WriteString(fmt.Sprintf("%s IN %s %s\n", b.relative(nameserver), recordType, b.NameserverIP))
+	}
+	builder.WriteString(body)
+	return builder.String()
+}
+
+// soaSerial returns the serial of the SOA record of a zone file written by WriteZone.
+func soaSerial(contents string) (uint32, bool) {
+	for _, line := range strings.Split(contents, "\n") {
+		value, comment, found := strings.Cut(line, ";")
+		if !found || strings.TrimSpace(comment) != "serial" {
+			continue
+		}
+		serial, err := strconv.ParseUint(strings.TrimSpace(value), 10, 32)
+		return uint32(serial), err == nil
+	}
+	return 0, false
+}
+
+func (b Bind) relative(host string) string {
+	zone := strings.TrimSuffix(b.Zone, ".")
+	if host == zone {
+		return "@"
+	}
+	return strings.TrimSuffix(host, "."+zone)
+}
+
+func (b Bind) ttl(host string) int {
+	if ttl, ok := b.HostTTLs[host]; ok {
+		return ttl
+	}
+	if b.TTL > 0 {
+		return b.TTL
+	}
+	return DefaultBindTTL
+}
+
+func absolute(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
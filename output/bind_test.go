@@ -0,0 +1,68 @@
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBindWriteZone(t *testing.T) {
+	b := Bind{Zone: "example.com.", NameserverIP: "192.168.1.2", HostTTLs: map[string]int{"b.example.com": 60}}
+	builder := strings.Builder{}
+	b.Write(&builder, []Record{
+		{Host: "example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "b.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "a.example.org", Type: "A", Value: "192.168.1.10"},
+	})
+
+	got := b.WriteZone("generated", builder.String(), "", time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC))
+	want := "; generated\n" +
+		"$ORIGIN example.com.\n" +
+		"$TTL 300\n" +
+		"@ IN SOA ns1.example.com. hostmaster.example.com. (\n" +
+		"\t2024051700 ; serial\n" +
+		"\t3600 ; refresh\n" +
+		"\t600 ; retry\n" +
+		"\t604800 ; expire\n" +
+		"\t300 ) ; negative caching TTL\n" +
+		"@ IN NS ns1.example.com.\n" +
+		"ns1 IN A 192.168.1.2\n" +
+		"; Endpoints extracted from 192.168.1.10\n" +
+		"b 60 IN A 192.168.1.10\n" +
+		"@ 300 IN A 192.168.1.10\n"
+	if got != want {
+		t.Errorf("WriteZone() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestBindWriteZoneSerial(t *testing.T) {
+	b := Bind{Zone: "example.com"}
+	now := time.Date(2024, 5, 17, 12, 0, 0, 0, time.UTC)
+	body := "a 300 IN A 192.168.1.10\n"
+	tests := []struct {
+		name    string
+		current uint32
+		body    string
+		want    uint32
+	}{
+		{"new zone", 0, body, 2024051700},
+		{"unchanged", 2024051003, body, 2024051003},
+		{"changed on another day", 2024051003, "a 300 IN A 192.168.1.11\n", 2024051700},
+		{"changed again the same day", 2024051700, "a 300 IN A 192.168.1.11\n", 2024051701},
+		{"99th change of the day", 2024051799, "a 300 IN A 192.168.1.11\n", 2024051800},
+		{"serial ahead of the date", 2030010100, "a 300 IN A 192.168.1.11\n", 2030010101},
+		{"serial not in date format", 42, "a 300 IN A 192.168.1.11\n", 2024051700},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			current := ""
+			if test.current > 0 {
+				current = b.zone("", body, test.current)
+			}
+			got := b.WriteZone("", test.body, current, now)
+			if want := b.zone("", test.body, test.want); got != want {
+				t.Errorf("WriteZone() =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Blocky writes the records as the customDNS mapping of the configuration of Blocky, a YAML
+// file merged with the rest of its configuration directory. A Blocky mapping also answers for
+// every subdomain, so wildcards only need their domain.
+type Blocky struct {
+	// Warnf is called with the hosts that are skipped.
+	Warnf Logf
+}
+
+// Write writes a mapping entry per host of the unique records, with its IPs separated by
+// commas or its CNAME target.
+func (b Blocky) Write(builder *strings.Builder, records []Record) {
+	hosts := []string{}
+	ips := map[string][]string{}
+	cnames := map[string]string{}
+	for _, r := range UniqueRecords(records) {
+		host := strings.TrimPrefix(r.Host, WildcardPrefix)
+		if strings.Contains(host, "*") {
+			b.Warnf.Printf("Skipping host %s. Only leading wildcards like *.example.com are supported", r.Host)
+			continue
+		}
+		if _, ok := ips[host]; !ok {
+			if _, ok := cnames[host]; !ok {
+				hosts = append(hosts, host)
+			}
+		}
+		if r.Type == "CNAME" {
+			cnames[host] = strings.TrimSuffix(r.Value, ".")
+			continue
+		}
+		ips[host] = append(ips[host], r.Value)
+	}
+	sort.Strings(hosts)
+
+	for _, host := range hosts {
+		if cname, ok := cnames[host]; ok {
+			if len(ips[host]) > 0 {
+				b.Warnf.Printf("Skipping the IPs of host %s. A Blocky mapping can't have both IPs and a CNAME", host)
+			}
+			builder.WriteString(fmt.Sprintf("    %s: %s\n", host, cname))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("    %s: %s\n", host, strings.Join(ips[host], ",")))
+	}
+}
+
+// WriteConfig returns the customDNS section with the comment header and the records of every
+// set merged in a single mapping.
+func (b Blocky) WriteConfig(header string, recordSets [][]Record) string {
+	records := []Record{}
+	for _, set := range recordSets {
+		records = append(records, set...)
+	}
+	mapping := strings.Builder{}
+	b.Write(&mapping, records)
+
+	builder := strings.Builder{}
+	WriteHeader(&builder, header)
+	builder.WriteString("customDNS:\n")
+	if mapping.Len() == 0 {
+		builder.WriteString("  mapping: {}\n")
+		return builder.String()
+	}
+	builder.WriteString("  mapping:\n")
+	builder.WriteString(mapping.String())
+	return builder.String()
+}
@@ -0,0 +1,34 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Dnsmasq writes the records as dnsmasq address or cname lines. dnsmasq addresses also
+// answer for every subdomain, so wildcards only need their domain.
+type Dnsmasq struct {
+	// Warnf is called with the hosts that are skipped.
+	Warnf Logf
+}
+
+// Write writes the unique records.
+func (d Dnsmasq) Write(builder *strings.Builder, records []Record) {
+	records = UniqueRecords(records)
+
+	for i, r := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("# Endpoints extracted from %s\n", r.Value))
+		}
+		host := strings.TrimPrefix(r.Host, WildcardPrefix)
+		if strings.Contains(host, "*") {
+			d.Warnf.Printf("Skipping host %s. Only leading wildcards like *.example.com are supported", r.Host)
+			continue
+		}
+		if r.Type == "CNAME" {
+			builder.WriteString(fmt.Sprintf("cname=%s,%s\n", host, strings.TrimSuffix(r.Value, ".")))
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("address=/%s/%s\n", host, r.Value))
+	}
+}
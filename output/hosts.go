@@ -0,0 +1,52 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Hosts writes the records in the format of /etc/hosts, also used by the Pi-hole custom.list.
+// Wildcards can't be expressed in a hosts file and are skipped.
+type Hosts struct {
+	// HostsPerLine groups up to that many hosts of the same IP on a line. 0 or 1 writes a
+	// line per host.
+	HostsPerLine int
+	// Warnf is called with the hosts that are skipped.
+	Warnf Logf
+}
+
+// Write writes the unique records.
+func (h Hosts) Write(builder *strings.Builder, records []Record) {
+	records = UniqueRecords(records)
+
+	// The IPs keep the order of their first host
+	ips := []string{}
+	hosts := map[string][]string{}
+	for i, r := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("# Endpoints extracted from %s\n", r.Value))
+		}
+		if strings.Contains(r.Host, "*") {
+			h.Warnf.Printf("Skipping wildcard host %s. Hosts files don't support wildcards", r.Host)
+			continue
+		}
+		if h.HostsPerLine <= 1 {
+			builder.WriteString(fmt.Sprintf("%s %s\n", r.Value, r.Host))
+			continue
+		}
+		if _, ok := hosts[r.Value]; !ok {
+			ips = append(ips, r.Value)
+		}
+		hosts[r.Value] = append(hosts[r.Value], r.Host)
+	}
+
+	for _, ip := range ips {
+		for start := 0; start < len(hosts[ip]); start += h.HostsPerLine {
+			end := start + h.HostsPerLine
+			if end > len(hosts[ip]) {
+				end = len(hosts[ip])
+			}
+			builder.WriteString(fmt.Sprintf("%s %s\n", ip, strings.Join(hosts[ip][start:end], " ")))
+		}
+	}
+}
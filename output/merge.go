@@ -0,0 +1,50 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markers of the section of a file holding the generated records in merge mode.
+const (
+	BeginMarker = "# BEGIN traefik2unbound"
+	EndMarker   = "# END traefik2unbound"
+)
+
+// Merge replaces the section between BeginMarker and EndMarker of existing with generated,
+// leaving the rest of the file untouched. The section is appended when existing has none.
+func Merge(existing string, generated string) (string, error) {
+	lines := strings.SplitAfter(existing, "\n")
+	begin, end := -1, -1
+	for i, line := range lines {
+		switch strings.TrimSpace(line) {
+		case BeginMarker:
+			if begin >= 0 {
+				return "", fmt.Errorf("found %s twice", BeginMarker)
+			}
+			begin = i
+		case EndMarker:
+			if begin < 0 || end >= 0 {
+				return "", fmt.Errorf("found %s without %s before it", EndMarker, BeginMarker)
+			}
+			end = i
+		}
+	}
+	if begin >= 0 && end < 0 {
+		return "", fmt.Errorf("found %s without %s after it", BeginMarker, EndMarker)
+	}
+
+	section := BeginMarker + "\n" + generated
+	if generated != "" && !strings.HasSuffix(generated, "\n") {
+		section += "\n"
+	}
+	section += EndMarker + "\n"
+
+	if begin < 0 {
+		if existing != "" && !strings.HasSuffix(existing, "\n") {
+			existing += "\n"
+		}
+		return existing + section, nil
+	}
+	return strings.Join(lines[:begin], "") + section + strings.Join(lines[end+1:], ""), nil
+}
@@ -0,0 +1,51 @@
+package output
+
+import "testing"
+
+func TestMerge(t *testing.T) {
+	section := BeginMarker + "\nnew\n" + EndMarker + "\n"
+	tests := []struct {
+		name      string
+		existing  string
+		generated string
+		want      string
+	}{
+		{"empty", "", "new\n", section},
+		{"append", "server:\n  verbosity: 1", "new\n", "server:\n  verbosity: 1\n" + section},
+		{"replace", "before\n" + BeginMarker + "\nold\n" + EndMarker + "\nafter\n", "new\n", "before\n" + section + "after\n"},
+		{"indented markers", "before\n  " + BeginMarker + "\nold\n  " + EndMarker + "\n", "new", "before\n" + section},
+		{"no records", BeginMarker + "\nold\n" + EndMarker + "\n", "", BeginMarker + "\n" + EndMarker + "\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Merge(test.existing, test.generated)
+			if err != nil {
+				t.Fatalf("Merge() failed. %s", err)
+			}
+			if got != test.want {
+				t.Errorf("Merge() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergeMarkers(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string
+	}{
+		{"begin twice", BeginMarker + "\n" + BeginMarker + "\n" + EndMarker + "\n"},
+		{"end twice", BeginMarker + "\n" + EndMarker + "\n" + EndMarker + "\n"},
+		{"end first", EndMarker + "\n" + BeginMarker + "\n"},
+		{"missing end", "before\n" + BeginMarker + "\nold\n"},
+		{"missing begin", "before\n" + EndMarker + "\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Merge(test.existing, "new\n")
+			if err == nil {
+				t.Errorf("Merge() succeeded, want an error")
+			}
+		})
+	}
+}
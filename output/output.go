@@ -0,0 +1,136 @@
+// Package output renders the records published for the Traefik hosts in the configuration
+// formats of the supported DNS servers.
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// WildcardPrefix is the prefix of the hosts that match every subdomain of a domain.
+const WildcardPrefix = "*."
+
+// TimestampHeader starts the line of the header with the time the file was generated, which
+// WithoutTimestamp leaves out so it doesn't change the file on every sync.
+const TimestampHeader = "Generated by traefik2unbound at "
+
+// Record is a single DNS answer published for a host, e.g. an A record pointing to the Traefik IP.
+type Record struct {
+	Host  string `json:"host"`
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+// Writer renders records in the format of a DNS server.
+type Writer interface {
+	Write(builder *strings.Builder, records []Record)
+}
+
+// Logf is called with the records a Writer skips because its format can't express them.
+type Logf func(format string, args ...interface{})
+
+// Printf calls l unless it is nil.
+func (l Logf) Printf(format string, args ...interface{}) {
+	if l != nil {
+		l(format, args...)
+	}
+}
+
+// WriteHeader writes every line of header as a comment.
+func WriteHeader(builder *strings.Builder, header string) {
+	if header == "" {
+		return
+	}
+	for _, line := range strings.Split(header, "\n") {
+		builder.WriteString(fmt.Sprintf("# %s\n", line))
+	}
+}
+
+// WithoutTimestamp returns contents without the comment lines of the header with
+// TimestampHeader.
+func WithoutTimestamp(contents string) string {
+	if !strings.Contains(contents, TimestampHeader) {
+		return contents
+	}
+	lines := strings.SplitAfter(contents, "\n")
+	kept := make([]string, 0, len(lines))
+	for _, line := range lines {
+		comment := strings.TrimLeft(line, "#; \t")
+		if len(comment) < len(line) && strings.HasPrefix(comment, TimestampHeader) {
+			continue
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "")
+}
+
+// WithoutComment returns line without its trailing comment, from the first # outside of
+// quotes.
+func WithoutComment(line string) string {
+	quoted := false
+	for i, c := range line {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case c == '#' && !quoted:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// Entries returns the entries of a generated file without its comments, empty lines and
+// duplicates, sorted inside each clause like server: or view:, so two files answering the
+// same records compare equal whatever the order of the Traefik instances or their comments.
+// The clauses keep their order since it matters to the DNS server.
+func Entries(contents string) string {
+	entries := []string{}
+	clause := []string{}
+	endClause := func() {
+		sort.Strings(clause)
+		for i, entry := range clause {
+			if i == 0 || entry != clause[i-1] {
+				entries = append(entries, entry)
+			}
+		}
+		clause = nil
+	}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(WithoutComment(line))
+		if line == "" {
+			continue
+		}
+		if strings.HasSuffix(line, ":") && !strings.ContainsAny(line, " \t\"") {
+			endClause()
+			entries = append(entries, line)
+			continue
+		}
+		clause = append(clause, line)
+	}
+	endClause()
+	return strings.Join(entries, "\n")
+}
+
+// UniqueRecords returns the records sorted by host, type and value, keeping only
+// one of each, since several routers can share the same host.
+func UniqueRecords(records []Record) []Record {
+	seen := make(map[Record]bool, len(records))
+	unique := make([]Record, 0, len(records))
+	for _, r := range records {
+		if !seen[r] {
+			seen[r] = true
+			unique = append(unique, r)
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		if unique[i].Host != unique[j].Host {
+			return unique[i].Host < unique[j].Host
+		}
+		if unique[i].Type != unique[j].Type {
+			return unique[i].Type < unique[j].Type
+		}
+		return unique[i].Value < unique[j].Value
+	})
+	return unique
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestUniqueRecords(t *testing.T) {
+	records := []Record{
+		{Host: "b.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "a.example.com", Type: "AAAA", Value: "fd00::10"},
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "b.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.11"},
+	}
+	want := []Record{
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.11"},
+		{Host: "a.example.com", Type: "AAAA", Value: "fd00::10"},
+		{Host: "b.example.com", Type: "A", Value: "192.168.1.10"},
+	}
+	if got := UniqueRecords(records); !reflect.DeepEqual(got, want) {
+		t.Errorf("UniqueRecords() = %v, want %v", got, want)
+	}
+}
+
+func TestUnboundWriteDuplicatedRecords(t *testing.T) {
+	// The records of an HTTP and a TCP router of the same host
+	records := []Record{
+		{Host: "whoami.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "whoami.example.com", Type: "A", Value: "192.168.1.10"},
+	}
+	builder := strings.Builder{}
+	Unbound{}.Write(&builder, records)
+	want := "local-data: \"whoami.example.com A 192.168.1.10\"\n"
+	if got := builder.String(); strings.Count(got, "local-data:") != 1 || !strings.HasSuffix(got, want) {
+		t.Errorf("Write() = %q, want the single line %q", got, want)
+	}
+}
+
+func TestUnboundWriteRecordSetsWildcardZone(t *testing.T) {
+	// The same wildcard host published by two instances
+	recordSets := [][]Record{
+		{{Host: "*.example.com", Type: "A", Value: "192.168.1.10"}},
+		{{Host: "*.example.com", Type: "A", Value: "192.168.1.11"}, {Host: "*.lab.example.com", Type: "A", Value: "192.168.1.11"}},
+	}
+	u := Unbound{WildcardZone: true}
+	builder := strings.Builder{}
+	u.WriteRecordSets(&builder, recordSets)
+	got := builder.String()
+	if strings.Count(got, "local-zone: \"example.com.\" redirect\n") != 1 || strings.Count(got, "local-zone: \"lab.example.com.\" redirect\n") != 1 {
+		t.Errorf("WriteRecordSets() =\n%s\nwant a single local-zone of every wildcard", got)
+	}
+	if !strings.Contains(got, "local-data: \"example.com. A 192.168.1.10\"\n") || !strings.Contains(got, "local-data: \"example.com. A 192.168.1.11\"\n") {
+		t.Errorf("WriteRecordSets() =\n%s\nwant the records of both instances", got)
+	}
+
+	u.Views = []UnboundView{{Name: "lan"}}
+	builder = strings.Builder{}
+	u.WriteViews(&builder, recordSets)
+	if got := builder.String(); strings.Count(got, "local-zone: \"example.com.\" redirect\n") != 1 {
+		t.Errorf("WriteViews() =\n%s\nwant a single local-zone of the wildcard in the view", got)
+	}
+}
@@ -0,0 +1,49 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HostOverrides writes one "host type value" line per record, the host overrides of the unbound
+// of OPNsense and pfSense. The file keeps track of the overrides that are managed.
+type HostOverrides struct {
+	// Zone skips the hosts outside of it when set, for the APIs managing the records of a zone.
+	Zone string
+	// Warnf is called with the records that are skipped.
+	Warnf Logf
+}
+
+// Write writes the unique A and AAAA records. Host overrides can't be CNAMEs.
+func (h HostOverrides) Write(builder *strings.Builder, records []Record) {
+	records = UniqueRecords(records)
+	zone := strings.TrimSuffix(h.Zone, ".")
+
+	for i, r := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("# Endpoints extracted from %s\n", r.Value))
+		}
+		if r.Type != "A" && r.Type != "AAAA" {
+			h.Warnf.Printf("Skipping %s record of %s. Host overrides only support A and AAAA records", r.Type, r.Host)
+			continue
+		}
+		if zone != "" && r.Host != zone && !strings.HasSuffix(r.Host, "."+zone) {
+			h.Warnf.Printf("Skipping host %s outside of the zone %s", r.Host, zone)
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%s %s %s\n", r.Host, r.Type, r.Value))
+	}
+}
+
+// ParseHostOverrides returns the records of a file written by HostOverrides.
+func ParseHostOverrides(contents string) []Record {
+	records := []Record{}
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		records = append(records, Record{Host: fields[0], Type: fields[1], Value: fields[2]})
+	}
+	return records
+}
@@ -0,0 +1,73 @@
+package output
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DefaultRFC2136TTL is the TTL of the records sent with dynamic updates when none is set,
+// since an update can't leave it to the server.
+const DefaultRFC2136TTL = 300
+
+// RFC2136Record is a record of a zone kept up to date with RFC 2136 dynamic updates.
+type RFC2136Record struct {
+	Record
+	TTL int
+}
+
+// RFC2136 writes one "host ttl type value" line per record of Zone. The file keeps track of
+// the records that are managed so the removed ones can be deleted from the zone.
+type RFC2136 struct {
+	Zone string
+	// TTL in seconds of the records. 0 uses DefaultRFC2136TTL.
+	TTL int
+	// HostTTLs overrides TTL for single hosts.
+	HostTTLs map[string]int
+	// Warnf is called with the hosts outside of Zone, which are skipped.
+	Warnf Logf
+}
+
+// Write writes the unique records of the hosts of Zone.
+func (r RFC2136) Write(builder *strings.Builder, records []Record) {
+	records = UniqueRecords(records)
+	zone := strings.TrimSuffix(r.Zone, ".")
+
+	for i, record := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("# Endpoints extracted from %s\n", record.Value))
+		}
+		if record.Host != zone && !strings.HasSuffix(record.Host, "."+zone) {
+			r.Warnf.Printf("Skipping host %s outside of the zone %s", record.Host, zone)
+			continue
+		}
+		builder.WriteString(fmt.Sprintf("%s %d %s %s\n", record.Host, r.ttl(record.Host), record.Type, record.Value))
+	}
+}
+
+func (r RFC2136) ttl(host string) int {
+	if ttl, ok := r.HostTTLs[host]; ok {
+		return ttl
+	}
+	if r.TTL > 0 {
+		return r.TTL
+	}
+	return DefaultRFC2136TTL
+}
+
+// ParseRFC2136Records returns the records of a file written by RFC2136.
+func ParseRFC2136Records(contents string) map[RFC2136Record]bool {
+	records := map[RFC2136Record]bool{}
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		ttl, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		records[RFC2136Record{Record: Record{Host: fields[0], Type: fields[2], Value: fields[3]}, TTL: ttl}] = true
+	}
+	return records
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// TemplateData is what the templates of Template are executed with.
+type TemplateData struct {
+	// Header is the comment header of the file, without comment markers.
+	Header string
+	// Records are the unique records of every instance, sorted by host, type and value.
+	Records []Record
+	// Hosts are the records of each host. Templates range over them sorted by host.
+	Hosts map[string][]Record
+}
+
+// TemplateFuncs are the functions of the templates besides the builtin ones. The string
+// comes last, so they can be used in pipelines like {{ .Value | trimSuffix "." }}.
+var TemplateFuncs = template.FuncMap{
+	"join":       func(sep string, values []string) string { return strings.Join(values, sep) },
+	"lower":      strings.ToLower,
+	"upper":      strings.ToUpper,
+	"replace":    func(old string, new string, s string) string { return strings.ReplaceAll(s, old, new) },
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+	"isWildcard": func(host string) bool { return strings.HasPrefix(host, WildcardPrefix) },
+}
+
+// ParseTemplate parses the Go text/template file at path with TemplateFuncs.
+func ParseTemplate(path string) (*template.Template, error) {
+	return template.New(filepath.Base(path)).Funcs(TemplateFuncs).ParseFiles(path)
+}
+
+// Template renders the records with a user supplied Go text/template, for the formats and
+// DNS servers without a writer of their own.
+type Template struct {
+	Template *template.Template
+}
+
+// Write executes the template with the header and the unique records.
+func (t Template) Write(builder *strings.Builder, header string, records []Record) error {
+	data := TemplateData{Header: header, Records: UniqueRecords(records), Hosts: map[string][]Record{}}
+	for _, r := range data.Records {
+		data.Hosts[r.Host] = append(data.Hosts[r.Host], r)
+	}
+	return t.Template.Execute(builder, data)
+}
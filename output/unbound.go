@@ -0,0 +1,289 @@
+package output
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LocalZoneTypes are the types of the local-zone of unbound.
+var LocalZoneTypes = []string{"transparent", "typetransparent", "static", "redirect", "deny", "refuse", "inform", "inform_deny", "inform_redirect", "always_transparent", "always_refuse", "always_nxdomain", "always_nodata", "always_deny", "always_null", "noview", "nodefault"}
+
+// Unbound writes the records as unbound local-data entries.
+type Unbound struct {
+	// TTL in seconds of the records. 0 leaves the default TTL of unbound.
+	TTL int
+	// HostTTLs overrides TTL for single hosts.
+	HostTTLs map[string]int
+	// PTR also writes a local-data-ptr record for every IP.
+	PTR bool
+	// PTRNames is the host the PTR record of an IP points to. Defaults to the first host in
+	// alphabetical order.
+	PTRNames map[string]string
+	// WildcardZone writes wildcard hosts like *.example.com as a redirect zone instead of
+	// skipping them.
+	WildcardZone bool
+	// LocalZones are the types of the local-zone of domains, written before the records.
+	LocalZones map[string]string
+	// DomainInsecure writes a domain-insecure for the parent domain of every host, so a
+	// validating unbound accepts the records overriding public domains signed with DNSSEC.
+	DomainInsecure bool
+	// Views writes the records inside these view clauses instead of the server one, so they
+	// only answer the clients of the views.
+	Views []UnboundView
+	// ViewFirst answers the names without records in the views with the local data of the
+	// server clause.
+	ViewFirst bool
+	// Comment returns the comment appended to the local-data lines of a host, like the routers
+	// it comes from. Nil appends none.
+	Comment func(host string) string
+	// Warnf is called with the hosts that are skipped.
+	Warnf Logf
+}
+
+// UnboundView is a view of unbound and the subnets of its clients, bound to it with
+// access-control-view. A view without subnets has its clients set elsewhere.
+type UnboundView struct {
+	Name    string
+	Subnets []string
+}
+
+// Write writes the unique records, and their PTR records with PTR.
+func (u Unbound) Write(builder *strings.Builder, records []Record) {
+	u.write(builder, records, u.writtenZones())
+}
+
+// WriteRecordSets writes the records of every set like Write, with a single redirect zone for
+// the wildcard hosts published by several of them since unbound rejects a duplicated
+// local-zone.
+func (u Unbound) WriteRecordSets(builder *strings.Builder, recordSets [][]Record) {
+	zones := u.writtenZones()
+	for _, records := range recordSets {
+		u.write(builder, records, zones)
+	}
+}
+
+// writtenZones returns the domains of the local-zones written by WriteLocalZones.
+func (u Unbound) writtenZones() map[string]bool {
+	zones := map[string]bool{}
+	for domain := range u.LocalZones {
+		zones[domain] = true
+	}
+	return zones
+}
+
+// write writes the records like Write, adding the redirect zones it writes to zones.
+func (u Unbound) write(builder *strings.Builder, records []Record, zones map[string]bool) {
+	records = UniqueRecords(records)
+	explicit := map[Record]bool{}
+	for _, r := range records {
+		if !strings.Contains(r.Host, "*") {
+			explicit[r] = true
+		}
+	}
+
+	for i, r := range records {
+		if i == 0 {
+			builder.WriteString(fmt.Sprintf("# Endpoints extracted from %s\n", r.Value))
+		}
+		if strings.Contains(r.Host, "*") {
+			u.writeWildcardHost(builder, r, zones, explicit)
+			continue
+		}
+		if zone, ok := u.shadowingZone(r, records); ok {
+			u.Warnf.Printf("Host %s is inside the redirect zone of %s%s and is answered with its records instead of its own", r.Host, WildcardPrefix, zone)
+		}
+		builder.WriteString(fmt.Sprintf("local-data: \"%s %s%s %s\"%s\n", r.Host, u.ttlAndClass(r.Host), r.Type, r.Value, u.comment(r.Host)))
+	}
+
+	if u.PTR {
+		u.writePTRRecords(builder, records)
+	}
+}
+
+// comment returns the trailing comment of the lines of host.
+func (u Unbound) comment(host string) string {
+	if u.Comment == nil {
+		return ""
+	}
+	if comment := u.Comment(host); comment != "" {
+		return " # " + comment
+	}
+	return ""
+}
+
+// WriteLocalZones writes the local-zone of every domain of LocalZones.
+func (u Unbound) WriteLocalZones(builder *strings.Builder) {
+	domains := make([]string, 0, len(u.LocalZones))
+	for domain := range u.LocalZones {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		builder.WriteString(fmt.Sprintf("local-zone: \"%s.\" %s\n", domain, u.LocalZones[domain]))
+	}
+}
+
+// WriteDomainInsecure writes a domain-insecure for every parent domain of the hosts of
+// recordSets when DomainInsecure is set. The hosts of a domain with a single label and the
+// domains of wildcard hosts are their own parent, and the domains inside another one are left
+// out since it covers them.
+func (u Unbound) WriteDomainInsecure(builder *strings.Builder, recordSets [][]Record) {
+	if !u.DomainInsecure {
+		return
+	}
+	parents := map[string]bool{}
+	for _, records := range recordSets {
+		for _, r := range records {
+			domain := r.Host
+			if strings.HasPrefix(domain, WildcardPrefix) {
+				domain = strings.TrimPrefix(domain, WildcardPrefix)
+			} else if _, parent, ok := strings.Cut(domain, "."); ok && strings.Contains(parent, ".") {
+				domain = parent
+			}
+			parents[domain] = true
+		}
+	}
+	domains := make([]string, 0, len(parents))
+	for domain := range parents {
+		covered := false
+		for other := range parents {
+			if strings.HasSuffix(domain, "."+other) {
+				covered = true
+			}
+		}
+		if !covered {
+			domains = append(domains, domain)
+		}
+	}
+	sort.Strings(domains)
+	for _, domain := range domains {
+		builder.WriteString(fmt.Sprintf("domain-insecure: \"%s.\"\n", domain))
+	}
+}
+
+// WriteViews writes the records of every set inside each view clause, followed by a server
+// clause with their domain-insecure and the access-control-view of their subnets. The server clause also ends the views
+// when the file is included in the middle of the server clause of unbound.conf.
+func (u Unbound) WriteViews(builder *strings.Builder, recordSets [][]Record) {
+	for _, view := range u.Views {
+		builder.WriteString("view:\n")
+		builder.WriteString(fmt.Sprintf("\tname: \"%s\"\n", view.Name))
+		if u.ViewFirst {
+			builder.WriteString("\tview-first: yes\n")
+		}
+		records := strings.Builder{}
+		u.WriteLocalZones(&records)
+		u.WriteRecordSets(&records, recordSets)
+		for _, line := range strings.SplitAfter(records.String(), "\n") {
+			if line != "" {
+				builder.WriteString("\t" + line)
+			}
+		}
+	}
+	builder.WriteString("server:\n")
+	insecure := strings.Builder{}
+	u.WriteDomainInsecure(&insecure, recordSets)
+	for _, line := range strings.SplitAfter(insecure.String(), "\n") {
+		if line != "" {
+			builder.WriteString("\t" + line)
+		}
+	}
+	for _, view := range u.Views {
+		for _, subnet := range view.Subnets {
+			builder.WriteString(fmt.Sprintf("\taccess-control-view: %s %s\n", subnet, view.Name))
+		}
+	}
+}
+
+// writePTRRecords writes one local-data-ptr per IP of the sorted records. When an
+// IP is shared by several hosts, the one in PTRNames or else the first one is used.
+func (u Unbound) writePTRRecords(builder *strings.Builder, records []Record) {
+	ptrHosts := map[string]string{}
+	ips := []string{}
+	for _, r := range records {
+		if (r.Type != "A" && r.Type != "AAAA") || strings.Contains(r.Host, "*") {
+			continue
+		}
+		if _, ok := ptrHosts[r.Value]; !ok {
+			ptrHosts[r.Value] = r.Host
+			ips = append(ips, r.Value)
+		}
+	}
+	sort.Strings(ips)
+
+	for _, ip := range ips {
+		host := ptrHosts[ip]
+		if canonical, ok := u.PTRNames[ip]; ok {
+			host = canonical
+		}
+		builder.WriteString(fmt.Sprintf("local-data-ptr: \"%s %s%s\"\n", ip, u.ptrTTL(host), host))
+	}
+}
+
+// ttlAndClass returns the "TTL IN " prefix of the record type for host, or nothing
+// when no TTL is configured so unbound uses its default.
+func (u Unbound) ttlAndClass(host string) string {
+	seconds, ok := u.HostTTLs[host]
+	if !ok {
+		seconds = u.TTL
+	}
+	if seconds == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d IN ", seconds)
+}
+
+// ptrTTL returns the "TTL " prefix of the PTR record for host. local-data-ptr doesn't take a class.
+func (u Unbound) ptrTTL(host string) string {
+	return strings.TrimSuffix(u.ttlAndClass(host), "IN ")
+}
+
+// writeWildcardHost writes a wildcard host as a redirect zone, which is
+// the only way unbound can answer for every subdomain with a single record.
+// Hosts that can't be expressed that way are skipped. zones tracks the zones already
+// written so hosts with several records get a single local-zone. The apex record is skipped
+// when the domain is also published with the same record.
+func (u Unbound) writeWildcardHost(builder *strings.Builder, r Record, zones map[string]bool, explicit map[Record]bool) {
+	domain := strings.TrimPrefix(r.Host, WildcardPrefix)
+	if !strings.HasPrefix(r.Host, WildcardPrefix) || strings.Contains(domain, "*") {
+		u.Warnf.Printf("Skipping host %s. Only leading wildcards like *.example.com are supported", r.Host)
+		return
+	}
+	if !u.WildcardZone {
+		u.Warnf.Printf("Skipping wildcard host %s. Use -wildcard-zone to publish it as a redirect zone", r.Host)
+		return
+	}
+	if zoneType, ok := u.LocalZones[domain]; ok && zoneType != "redirect" {
+		u.Warnf.Printf("Wildcard host %s is published in the %s local-zone of %s instead of a redirect one, so it only answers for %s", r.Host, zoneType, domain, domain)
+	}
+	if !zones[domain] {
+		zones[domain] = true
+		builder.WriteString(fmt.Sprintf("local-zone: \"%s.\" redirect\n", domain))
+	}
+	if explicit[Record{Host: domain, Type: r.Type, Value: r.Value}] {
+		return
+	}
+	builder.WriteString(fmt.Sprintf("local-data: \"%s. %s%s %s\"%s\n", domain, u.ttlAndClass(r.Host), r.Type, r.Value, u.comment(r.Host)))
+}
+
+// shadowingZone returns the domain of the wildcard written as a redirect zone that the host of
+// r is a subdomain of, when the wildcard doesn't have the same record. unbound answers every
+// name of a redirect zone with the records of its apex.
+func (u Unbound) shadowingZone(r Record, records []Record) (string, bool) {
+	if !u.WildcardZone {
+		return "", false
+	}
+	shadowing := ""
+	for _, w := range records {
+		domain := strings.TrimPrefix(w.Host, WildcardPrefix)
+		if !strings.HasPrefix(w.Host, WildcardPrefix) || strings.Contains(domain, "*") || !strings.HasSuffix(r.Host, "."+domain) {
+			continue
+		}
+		if w.Type == r.Type && w.Value == r.Value {
+			return "", false
+		}
+		shadowing = domain
+	}
+	return shadowing, shadowing != ""
+}
@@ -0,0 +1,109 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// Adguard syncs DNS rewrites with the AdGuard Home API at URL.
+type Adguard struct {
+	URL      string
+	Username string
+	Password string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Reconcile adds the rewrites of the new file contents missing in AdGuard Home and deletes
+// the ones of the old contents that are no longer in the new ones. Rewrites created by hand
+// are never touched. It returns the rewrites it added and deleted.
+func (a Adguard) Reconcile(oldContents string, newContents string) (added []output.AdguardRewrite, deleted []output.AdguardRewrite, err error) {
+	current, err := a.List()
+	if err != nil {
+		return nil, nil, err
+	}
+	existing := map[output.AdguardRewrite]bool{}
+	for _, rewrite := range current {
+		existing[rewrite] = true
+	}
+
+	managed := output.ParseAdguardRewrites(oldContents)
+	wanted := output.ParseAdguardRewrites(newContents)
+	for rewrite := range managed {
+		if !wanted[rewrite] && existing[rewrite] {
+			err := a.post("/control/rewrite/delete", rewrite)
+			if err != nil {
+				return added, deleted, err
+			}
+			deleted = append(deleted, rewrite)
+		}
+	}
+	for rewrite := range wanted {
+		if !existing[rewrite] {
+			err := a.post("/control/rewrite/add", rewrite)
+			if err != nil {
+				return added, deleted, err
+			}
+			added = append(added, rewrite)
+		}
+	}
+	return added, deleted, nil
+}
+
+// List returns the rewrites of AdGuard Home.
+func (a Adguard) List() ([]output.AdguardRewrite, error) {
+	body, err := a.do(http.MethodGet, "/control/rewrite/list", nil)
+	if err != nil {
+		return nil, err
+	}
+	rewrites := []output.AdguardRewrite{}
+	err = json.Unmarshal(body, &rewrites)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling AdGuard Home rewrites. %s", err)
+	}
+	return rewrites, nil
+}
+
+func (a Adguard) post(path string, rewrite output.AdguardRewrite) error {
+	contents, err := json.Marshal(rewrite)
+	if err != nil {
+		return err
+	}
+	_, err = a.do(http.MethodPost, path, contents)
+	return err
+}
+
+func (a Adguard) do(method string, path string, contents []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(a.URL, "/")+path, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if a.Username != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+
+	client := a.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("response from AdGuard Home %s not successful. Status: %s, %s", path, resp.Status, body)
+	}
+	return body, nil
+}
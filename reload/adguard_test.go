@@ -0,0 +1,36 @@
+package reload
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestAdguardReconcile(t *testing.T) {
+	api, url := newFakeAPI(t, map[string]string{
+		"GET /control/rewrite/list": `[{"domain":"a.example.com","answer":"192.168.1.10"},{"domain":"manual.example.com","answer":"192.168.1.2"}]`,
+	}, "")
+	a := Adguard{URL: url}
+
+	added, deleted, err := a.Reconcile("a.example.com 192.168.1.10\nb.example.com 192.168.1.11\n", "b.example.com 192.168.1.11\nc.example.com 192.168.1.12\n")
+	if err != nil {
+		t.Fatalf("Reconcile() failed. %s", err)
+	}
+	// b is managed but missing, so it is added again. The manual rewrite is never touched
+	wantAdded := []output.AdguardRewrite{{Domain: "b.example.com", Answer: "192.168.1.11"}, {Domain: "c.example.com", Answer: "192.168.1.12"}}
+	wantDeleted := []output.AdguardRewrite{{Domain: "a.example.com", Answer: "192.168.1.10"}}
+	sort.Slice(added, func(i, j int) bool { return added[i].Domain < added[j].Domain })
+	if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Reconcile() = %v, %v, want %v, %v", added, deleted, wantAdded, wantDeleted)
+	}
+	want := []string{
+		`POST /control/rewrite/add {"domain":"b.example.com","answer":"192.168.1.11"}`,
+		`POST /control/rewrite/add {"domain":"c.example.com","answer":"192.168.1.12"}`,
+		`POST /control/rewrite/delete {"domain":"a.example.com","answer":"192.168.1.10"}`,
+	}
+	if got := sortedRequests(api.sent("GET")); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile() sent %q, want %q", got, want)
+	}
+}
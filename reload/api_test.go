@@ -0,0 +1,57 @@
+package reload
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeAPI answers the requests of the API clients with the body of responses for their method
+// and path, or defaultResponse, and keeps the requests it received as "method URI body".
+type fakeAPI struct {
+	responses       map[string]string
+	defaultResponse string
+	mu              sync.Mutex
+	requests        []string
+}
+
+func newFakeAPI(t *testing.T, responses map[string]string, defaultResponse string) (*fakeAPI, string) {
+	t.Helper()
+	api := &fakeAPI{responses: responses, defaultResponse: defaultResponse}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		api.mu.Lock()
+		api.requests = append(api.requests, strings.TrimSpace(r.Method+" "+r.URL.RequestURI()+" "+string(body)))
+		api.mu.Unlock()
+		response, ok := api.responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			response = api.defaultResponse
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return api, server.URL
+}
+
+// sent returns the requests received except the ones with the method.
+func (a *fakeAPI) sent(except string) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	requests := []string{}
+	for _, request := range a.requests {
+		if !strings.HasPrefix(request, except+" ") {
+			requests = append(requests, request)
+		}
+	}
+	return requests
+}
+
+// sortedRequests sorts the requests sent in the order of the iteration of a map.
+func sortedRequests(requests []string) []string {
+	sort.Strings(requests)
+	return requests
+}
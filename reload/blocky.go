@@ -0,0 +1,37 @@
+package reload
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Blocky refreshes Blocky with its API at URL.
+type Blocky struct {
+	URL string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+// Refresh makes Blocky reload its lists.
+func (b Blocky) Refresh() error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(b.URL, "/")+"/api/lists/refresh", nil)
+	if err != nil {
+		return err
+	}
+	client := b.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error refreshing Blocky. %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("response from Blocky /api/lists/refresh not successful. Status: %s, %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
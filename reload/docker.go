@@ -0,0 +1,47 @@
+package reload
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// DockerContainer restarts the container Name, or sends it Signal, through the Docker API
+// at URL, which is http://docker for HTTPClient talking to a unix socket.
+type DockerContainer struct {
+	URL        string
+	HTTPClient *http.Client
+	Name       string
+	// Signal, e.g. HUP, is sent to the container instead of restarting it when set.
+	Signal string
+}
+
+// Reload restarts the container or sends it the signal.
+func (d DockerContainer) Reload() error {
+	path := "/containers/" + url.PathEscape(d.Name) + "/restart"
+	action := "restarting"
+	if d.Signal != "" {
+		path = "/containers/" + url.PathEscape(d.Name) + "/kill?signal=" + url.QueryEscape(d.Signal)
+		action = "sending " + d.Signal + " to"
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(d.URL, "/")+path, nil)
+	if err != nil {
+		return err
+	}
+	client := d.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error %s the container %s. %s", action, d.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error %s the container %s. Status: %s, %s", action, d.Name, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
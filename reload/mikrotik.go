@@ -0,0 +1,158 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// Mikrotik syncs the static DNS entries of RouterOS with its REST API at URL. The managed
+// entries have the comment HostOverrideDescription, the other ones are never touched.
+type Mikrotik struct {
+	URL      string
+	Username string
+	Password string
+	// TTL of the added entries. Zero uses the default of RouterOS.
+	TTL int
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type mikrotikEntry struct {
+	ID       string `json:".id,omitempty"`
+	Name     string `json:"name,omitempty"`
+	Regexp   string `json:"regexp,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Address  string `json:"address,omitempty"`
+	TTL      string `json:"ttl,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+	Disabled string `json:"disabled,omitempty"`
+}
+
+// record returns the record of the entry. RouterOS leaves out the type of the A entries and
+// matches the wildcard hosts with a regexp.
+func (e mikrotikEntry) record() output.Record {
+	recordType := e.Type
+	if recordType == "" {
+		recordType = "A"
+	}
+	host := e.Name
+	if e.Regexp != "" {
+		host = "*." + strings.ReplaceAll(strings.TrimSuffix(strings.TrimPrefix(e.Regexp, `^.*\.`), "$"), `\.`, ".")
+	}
+	return output.Record{Host: host, Type: recordType, Value: e.Address}
+}
+
+// wildcardRegexp returns the regexp matching the subdomains of the wildcard host.
+func wildcardRegexp(host string) string {
+	return `^.*\.` + strings.ReplaceAll(strings.TrimPrefix(host, "*."), ".", `\.`) + "$"
+}
+
+// Reconcile adds the records missing in RouterOS and deletes the managed static entries that
+// are no longer in records. It returns the records it added and deleted.
+func (m Mikrotik) Reconcile(records []output.Record) (added []output.Record, deleted []output.Record, err error) {
+	body, err := m.do(http.MethodGet, "/rest/ip/dns/static", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := []mikrotikEntry{}
+	err = json.Unmarshal(body, &entries)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling RouterOS static DNS entries. %s", err)
+	}
+
+	wanted := map[output.Record]bool{}
+	for _, r := range records {
+		wanted[r] = true
+	}
+	existing := map[output.Record]bool{}
+	for _, entry := range entries {
+		if entry.Comment != HostOverrideDescription {
+			continue
+		}
+		r := entry.record()
+		if wanted[r] && entry.Disabled != "true" && !existing[r] {
+			existing[r] = true
+			continue
+		}
+		_, err := m.do(http.MethodDelete, "/rest/ip/dns/static/"+entry.ID, nil)
+		if err != nil {
+			return added, deleted, err
+		}
+		deleted = append(deleted, r)
+	}
+	for _, r := range records {
+		if existing[r] {
+			continue
+		}
+		entry := mikrotikEntry{Name: r.Host, Address: r.Value, Comment: HostOverrideDescription}
+		if strings.HasPrefix(r.Host, "*.") {
+			entry.Name = ""
+			entry.Regexp = wildcardRegexp(r.Host)
+		}
+		if r.Type != "A" {
+			entry.Type = r.Type
+		}
+		if m.TTL > 0 {
+			entry.TTL = strconv.Itoa(m.TTL) + "s"
+		}
+		contents, err := json.Marshal(entry)
+		if err != nil {
+			return added, deleted, err
+		}
+		_, err = m.do(http.MethodPut, "/rest/ip/dns/static", contents)
+		if err != nil {
+			return added, deleted, err
+		}
+		existing[r] = true
+		added = append(added, r)
+	}
+	return added, deleted, nil
+}
+
+// Reconfigure does nothing since RouterOS serves the static entries as soon as they are added.
+func (m Mikrotik) Reconfigure() error {
+	return nil
+}
+
+func (m Mikrotik) do(method string, path string, contents []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(m.URL, "/")+path, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if contents != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.SetBasicAuth(m.Username, m.Password)
+
+	client := m.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		// Errors have their reason in the detail of the body
+		result := struct {
+			Detail string `json:"detail"`
+		}{}
+		if json.Unmarshal(body, &result) == nil && result.Detail != "" {
+			body = []byte(result.Detail)
+		}
+		return nil, fmt.Errorf("response from RouterOS %s not successful. Status: %s, %s", path, resp.Status, body)
+	}
+	return body, nil
+}
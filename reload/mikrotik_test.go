@@ -0,0 +1,42 @@
+package reload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestMikrotikReconcile(t *testing.T) {
+	api, url := newFakeAPI(t, map[string]string{
+		"GET /rest/ip/dns/static": `[` +
+			`{".id":"*1","name":"a.example.com","address":"192.168.1.10","comment":"` + HostOverrideDescription + `"},` +
+			`{".id":"*2","name":"b.example.com","address":"192.168.1.11","comment":"` + HostOverrideDescription + `"},` +
+			`{".id":"*3","name":"router.example.com","address":"192.168.1.1"},` +
+			`{".id":"*4","regexp":"^.*\\.apps\\.example\\.com$","address":"192.168.1.20","comment":"` + HostOverrideDescription + `"}]`,
+	}, "{}")
+	m := Mikrotik{URL: url, TTL: 300}
+
+	added, deleted, err := m.Reconcile([]output.Record{
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "*.apps.example.com", Type: "A", Value: "192.168.1.20"},
+		{Host: "c.example.com", Type: "AAAA", Value: "fd00::13"},
+		{Host: "*.lab.example.com", Type: "A", Value: "192.168.1.30"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() failed. %s", err)
+	}
+	wantAdded := []output.Record{{Host: "c.example.com", Type: "AAAA", Value: "fd00::13"}, {Host: "*.lab.example.com", Type: "A", Value: "192.168.1.30"}}
+	wantDeleted := []output.Record{{Host: "b.example.com", Type: "A", Value: "192.168.1.11"}}
+	if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Reconcile() = %v, %v, want %v, %v", added, deleted, wantAdded, wantDeleted)
+	}
+	want := []string{
+		`DELETE /rest/ip/dns/static/*2`,
+		`PUT /rest/ip/dns/static {"name":"c.example.com","type":"AAAA","address":"fd00::13","ttl":"300s","comment":"` + HostOverrideDescription + `"}`,
+		`PUT /rest/ip/dns/static {"regexp":"^.*\\.lab\\.example\\.com$","address":"192.168.1.30","ttl":"300s","comment":"` + HostOverrideDescription + `"}`,
+	}
+	if got := api.sent("GET"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile() sent %q, want %q", got, want)
+	}
+}
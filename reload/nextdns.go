@@ -0,0 +1,138 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// DefaultNextDNSURL is the URL of the NextDNS API.
+const DefaultNextDNSURL = "https://api.nextdns.io"
+
+// NextDNS syncs the rewrites of Profile with the NextDNS API at URL.
+type NextDNS struct {
+	URL     string
+	APIKey  string
+	Profile string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type nextdnsRewrite struct {
+	ID      string `json:"id,omitempty"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
+// nextdnsName returns the name of the rewrite of domain. The rewrites of NextDNS apply to the
+// subdomains of their name, so wildcards are rewritten as their domain.
+func nextdnsName(domain string) string {
+	return strings.TrimPrefix(domain, "*.")
+}
+
+// Reconcile adds the rewrites of the new file contents missing in NextDNS and deletes the
+// ones of the old contents that are no longer in the new ones. Rewrites created by hand are
+// never touched. It returns the rewrites it added and deleted.
+func (n NextDNS) Reconcile(oldContents string, newContents string) (added []output.AdguardRewrite, deleted []output.AdguardRewrite, err error) {
+	body, err := n.do(http.MethodGet, "", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := struct {
+		Data []nextdnsRewrite `json:"data"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling NextDNS rewrites. %s", err)
+	}
+	existing := map[nextdnsRewrite]string{}
+	for _, rewrite := range result.Data {
+		id := rewrite.ID
+		rewrite.ID = ""
+		existing[rewrite] = id
+	}
+
+	managed := output.ParseAdguardRewrites(oldContents)
+	wanted := output.ParseAdguardRewrites(newContents)
+	wantedNames := map[nextdnsRewrite]bool{}
+	for rewrite := range wanted {
+		wantedNames[nextdnsRewrite{Name: nextdnsName(rewrite.Domain), Content: rewrite.Answer}] = true
+	}
+	for rewrite := range managed {
+		key := nextdnsRewrite{Name: nextdnsName(rewrite.Domain), Content: rewrite.Answer}
+		id, ok := existing[key]
+		if wantedNames[key] || !ok {
+			continue
+		}
+		_, err := n.do(http.MethodDelete, "/"+url.PathEscape(id), nil)
+		if err != nil {
+			return added, deleted, err
+		}
+		delete(existing, key)
+		deleted = append(deleted, rewrite)
+	}
+	for rewrite := range wanted {
+		key := nextdnsRewrite{Name: nextdnsName(rewrite.Domain), Content: rewrite.Answer}
+		if _, ok := existing[key]; ok {
+			continue
+		}
+		contents, err := json.Marshal(key)
+		if err != nil {
+			return added, deleted, err
+		}
+		_, err = n.do(http.MethodPost, "", contents)
+		if err != nil {
+			return added, deleted, err
+		}
+		existing[key] = ""
+		added = append(added, rewrite)
+	}
+	return added, deleted, nil
+}
+
+// do sends the request to path under the rewrites of the profile.
+func (n NextDNS) do(method string, path string, contents []byte) ([]byte, error) {
+	path = "/profiles/" + url.PathEscape(n.Profile) + "/rewrites" + path
+	req, err := http.NewRequest(method, strings.TrimSuffix(n.URL, "/")+path, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if contents != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Api-Key", n.APIKey)
+
+	client := n.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("response from NextDNS %s not successful. Status: %s, %s", path, resp.Status, body)
+	}
+	// Validation errors can come with a 200 status
+	result := struct {
+		Errors []struct {
+			Code   string `json:"code"`
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}{}
+	if json.Unmarshal(body, &result) == nil && len(result.Errors) > 0 {
+		return nil, fmt.Errorf("response from NextDNS %s not successful. %s %s", path, result.Errors[0].Code, result.Errors[0].Detail)
+	}
+	return body, nil
+}
@@ -0,0 +1,49 @@
+package reload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestNextDNSReconcile(t *testing.T) {
+	api, url := newFakeAPI(t, map[string]string{
+		"GET /profiles/abc123/rewrites": `{"data":[` +
+			`{"id":"1","name":"a.example.com","content":"192.168.1.10"},` +
+			`{"id":"2","name":"apps.example.com","content":"192.168.1.20"},` +
+			`{"id":"3","name":"manual.example.com","content":"192.168.1.2"}]}`,
+	}, `{"data":{}}`)
+	n := NextDNS{URL: url, Profile: "abc123"}
+
+	added, deleted, err := n.Reconcile(
+		"a.example.com 192.168.1.10\n*.apps.example.com 192.168.1.20\nmanual.example.com 192.168.1.3\n",
+		"*.apps.example.com 192.168.1.20\n*.lab.example.com 192.168.1.30\n",
+	)
+	if err != nil {
+		t.Fatalf("Reconcile() failed. %s", err)
+	}
+	wantAdded := []output.AdguardRewrite{{Domain: "*.lab.example.com", Answer: "192.168.1.30"}}
+	wantDeleted := []output.AdguardRewrite{{Domain: "a.example.com", Answer: "192.168.1.10"}}
+	if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Reconcile() = %v, %v, want %v, %v", added, deleted, wantAdded, wantDeleted)
+	}
+	// The wildcard is rewritten as its domain, and the rewrite created by hand is never touched
+	want := []string{
+		`DELETE /profiles/abc123/rewrites/1`,
+		`POST /profiles/abc123/rewrites {"name":"lab.example.com","content":"192.168.1.30"}`,
+	}
+	if got := api.sent("GET"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile() sent %q, want %q", got, want)
+	}
+}
+
+func TestNextDNSError(t *testing.T) {
+	_, url := newFakeAPI(t, nil, `{"errors":[{"code":"notFound","detail":"Profile not found"}]}`)
+	n := NextDNS{URL: url, Profile: "abc123"}
+
+	_, _, err := n.Reconcile("", "a.example.com 192.168.1.10\n")
+	if err == nil {
+		t.Errorf("Reconcile() succeeded, want the error reported with a 200 status")
+	}
+}
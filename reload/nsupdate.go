@@ -0,0 +1,100 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// Nsupdate sends RFC 2136 dynamic updates of Zone to Server with the nsupdate executable at
+// Path, signed with TSIG when KeyFile or Key is set.
+type Nsupdate struct {
+	Path string
+	// Server is the host of the primary of the zone, with an optional port.
+	Server string
+	Zone   string
+	// KeyFile is the path of a TSIG key file, passed to nsupdate with -k.
+	KeyFile string
+	// Key is a TSIG key in the format [hmac:]name:secret. It is sent on the standard input
+	// so the secret doesn't show in the process list.
+	Key string
+	// Owner enables the TXT registry, querying Server for the TXT records of OwnerTXT of the
+	// hosts before changing them.
+	Owner string
+}
+
+// Update deletes and adds the records in a single update, so it is applied atomically.
+func (n Nsupdate) Update(deleted []output.RFC2136Record, added []output.RFC2136Record) error {
+	_, err := runInput(n.script(deleted, added), n.Path, n.args()...)
+	if err != nil {
+		return fmt.Errorf("error updating the zone %s. %s", n.Zone, err)
+	}
+	return nil
+}
+
+// Owned queries Server for whether host has the TXT record of OwnerTXT of Owner, and whether
+// it has any address, so the hosts created by hand or by other tools are left as they are.
+func (n Nsupdate) Owned(host string) (owned bool, exists bool, err error) {
+	// Queried as absolute names, so the search domains of resolv.conf are never tried
+	name := fqdn(host)
+	if strings.HasPrefix(host, "*.") {
+		name = fqdn(registryLabel + host[1:])
+	}
+	resolver := newResolver(n.Server)
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	texts, err := resolver.LookupTXT(ctx, name)
+	if err != nil && !notFound(err) {
+		return false, false, fmt.Errorf("error querying the owner of %s. %s", host, err)
+	}
+	for _, text := range texts {
+		if text == OwnerTXT(n.Owner) {
+			owned = true
+		}
+	}
+	addresses, err := resolver.LookupHost(ctx, name)
+	if err != nil && !notFound(err) {
+		return false, false, fmt.Errorf("error querying the records of %s. %s", host, err)
+	}
+	return owned, len(addresses) > 0, nil
+}
+
+func (n Nsupdate) args() []string {
+	if n.KeyFile != "" {
+		return []string{"-k", n.KeyFile}
+	}
+	return nil
+}
+
+func (n Nsupdate) script(deleted []output.RFC2136Record, added []output.RFC2136Record) string {
+	builder := strings.Builder{}
+	host, port, err := net.SplitHostPort(n.Server)
+	if err != nil {
+		host, port = n.Server, ""
+	}
+	builder.WriteString(strings.TrimSpace("server "+host+" "+port) + "\n")
+	builder.WriteString("zone " + fqdn(n.Zone) + "\n")
+	if n.Key != "" {
+		// nsupdate takes the key as [hmac:]name secret
+		i := strings.LastIndex(n.Key, ":")
+		if i >= 0 {
+			builder.WriteString("key " + n.Key[:i] + " " + n.Key[i+1:] + "\n")
+		}
+	}
+	for _, r := range deleted {
+		builder.WriteString(fmt.Sprintf("update delete %s %s %s\n", fqdn(r.Host), r.Type, r.Value))
+	}
+	for _, r := range added {
+		builder.WriteString(fmt.Sprintf("update add %s %d %s %s\n", fqdn(r.Host), r.TTL, r.Type, r.Value))
+	}
+	builder.WriteString("send\n")
+	return builder.String()
+}
+
+func fqdn(name string) string {
+	return strings.TrimSuffix(name, ".") + "."
+}
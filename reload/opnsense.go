@@ -0,0 +1,158 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// HostOverrideDescription is the description of the host overrides that are managed, and the
+// comment of the managed RouterOS static DNS entries, so the stale ones can be deleted without
+// touching the ones created by hand.
+const HostOverrideDescription = "Managed by traefik2unbound"
+
+// Opnsense syncs the unbound host overrides with the OPNsense API at URL.
+type Opnsense struct {
+	URL string
+	// Key and Secret are the credentials of the API key of an OPNsense user.
+	Key    string
+	Secret string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type opnsenseOverride struct {
+	UUID        string `json:"uuid,omitempty"`
+	Enabled     string `json:"enabled"`
+	Hostname    string `json:"hostname"`
+	Domain      string `json:"domain"`
+	RR          string `json:"rr"`
+	Server      string `json:"server"`
+	Description string `json:"description"`
+}
+
+func (o opnsenseOverride) record() output.Record {
+	return output.Record{Host: joinHost(o.Hostname, o.Domain), Type: o.RR, Value: o.Server}
+}
+
+// Reconcile adds the records missing in OPNsense and deletes the managed host overrides that
+// are no longer in records. It returns the records it added and deleted.
+func (o Opnsense) Reconcile(records []output.Record) (added []output.Record, deleted []output.Record, err error) {
+	body, err := o.do(http.MethodGet, "/api/unbound/settings/searchHostOverride", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := struct {
+		Rows []opnsenseOverride `json:"rows"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling OPNsense host overrides. %s", err)
+	}
+
+	wanted := map[output.Record]bool{}
+	for _, r := range records {
+		wanted[r] = true
+	}
+	existing := map[output.Record]bool{}
+	for _, override := range result.Rows {
+		if override.Description != HostOverrideDescription {
+			continue
+		}
+		r := override.record()
+		if wanted[r] && override.Enabled == "1" && !existing[r] {
+			existing[r] = true
+			continue
+		}
+		_, err := o.do(http.MethodPost, "/api/unbound/settings/delHostOverride/"+override.UUID, []byte("{}"))
+		if err != nil {
+			return added, deleted, err
+		}
+		deleted = append(deleted, r)
+	}
+	for _, r := range records {
+		if existing[r] {
+			continue
+		}
+		hostname, domain := splitHost(r.Host)
+		contents, err := json.Marshal(map[string]opnsenseOverride{"host": {
+			Enabled:     "1",
+			Hostname:    hostname,
+			Domain:      domain,
+			RR:          r.Type,
+			Server:      r.Value,
+			Description: HostOverrideDescription,
+		}})
+		if err != nil {
+			return added, deleted, err
+		}
+		_, err = o.do(http.MethodPost, "/api/unbound/settings/addHostOverride", contents)
+		if err != nil {
+			return added, deleted, err
+		}
+		existing[r] = true
+		added = append(added, r)
+	}
+	return added, deleted, nil
+}
+
+// Reconfigure makes unbound use the current host overrides.
+func (o Opnsense) Reconfigure() error {
+	_, err := o.do(http.MethodPost, "/api/unbound/service/reconfigure", []byte("{}"))
+	return err
+}
+
+func (o Opnsense) do(method string, path string, contents []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(o.URL, "/")+path, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if contents != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.SetBasicAuth(o.Key, o.Secret)
+
+	client := o.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("response from OPNsense %s not successful. Status: %s, %s", path, resp.Status, body)
+	}
+	// Validation errors are reported with a 200 status
+	result := struct {
+		Result      string                 `json:"result"`
+		Validations map[string]interface{} `json:"validations"`
+	}{}
+	if json.Unmarshal(body, &result) == nil && result.Result == "failed" {
+		return nil, fmt.Errorf("response from OPNsense %s not successful. %v", path, result.Validations)
+	}
+	return body, nil
+}
+
+// splitHost splits host into its first label and its domain, the way host overrides store it.
+func splitHost(host string) (string, string) {
+	hostname, domain, _ := strings.Cut(host, ".")
+	return hostname, domain
+}
+
+func joinHost(hostname string, domain string) string {
+	if domain == "" {
+		return hostname
+	}
+	return hostname + "." + domain
+}
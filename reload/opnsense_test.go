@@ -0,0 +1,50 @@
+package reload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestOpnsenseReconcile(t *testing.T) {
+	api, url := newFakeAPI(t, map[string]string{
+		"GET /api/unbound/settings/searchHostOverride": `{"rows":[` +
+			`{"uuid":"1","enabled":"1","hostname":"a","domain":"example.com","rr":"A","server":"192.168.1.10","description":"` + HostOverrideDescription + `"},` +
+			`{"uuid":"2","enabled":"0","hostname":"b","domain":"example.com","rr":"A","server":"192.168.1.11","description":"` + HostOverrideDescription + `"},` +
+			`{"uuid":"3","enabled":"1","hostname":"router","domain":"example.com","rr":"A","server":"192.168.1.1","description":""}]}`,
+	}, `{"result":"saved"}`)
+	o := Opnsense{URL: url}
+
+	added, deleted, err := o.Reconcile([]output.Record{
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.10"},
+		{Host: "b.example.com", Type: "A", Value: "192.168.1.11"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() failed. %s", err)
+	}
+	// The disabled override of b is replaced by an enabled one
+	wantAdded := []output.Record{{Host: "b.example.com", Type: "A", Value: "192.168.1.11"}}
+	if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(deleted, wantAdded) {
+		t.Errorf("Reconcile() = %v, %v, want %v added and deleted", added, deleted, wantAdded)
+	}
+	want := []string{
+		`POST /api/unbound/settings/delHostOverride/2 {}`,
+		`POST /api/unbound/settings/addHostOverride {"host":{"enabled":"1","hostname":"b","domain":"example.com","rr":"A","server":"192.168.1.11","description":"` + HostOverrideDescription + `"}}`,
+	}
+	if got := api.sent("GET"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile() sent %q, want %q", got, want)
+	}
+}
+
+func TestOpnsenseValidationFailure(t *testing.T) {
+	_, url := newFakeAPI(t, map[string]string{
+		"GET /api/unbound/settings/searchHostOverride": `{"rows":[]}`,
+	}, `{"result":"failed","validations":{"host.server":"A valid IP address is required"}}`)
+	o := Opnsense{URL: url}
+
+	added, _, err := o.Reconcile([]output.Record{{Host: "a.example.com", Type: "A", Value: "a"}})
+	if err == nil || len(added) != 0 {
+		t.Errorf("Reconcile() = %v, %v, want the validation error reported with a 200 status", added, err)
+	}
+}
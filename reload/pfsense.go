@@ -0,0 +1,147 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// Pfsense syncs the DNS resolver host overrides with the REST API package of pfSense at URL.
+type Pfsense struct {
+	URL string
+	// Key is an API key of the REST API package.
+	Key string
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type pfsenseOverride struct {
+	ID          int      `json:"id,omitempty"`
+	Host        string   `json:"host"`
+	Domain      string   `json:"domain"`
+	IP          []string `json:"ip"`
+	Description string   `json:"descr"`
+}
+
+// Reconcile adds the records missing in pfSense and deletes the managed host overrides that
+// are no longer in records. A pfSense host override has every IP of its host. It returns the
+// records it added and deleted.
+func (p Pfsense) Reconcile(records []output.Record) (added []output.Record, deleted []output.Record, err error) {
+	body, err := p.do(http.MethodGet, "/api/v2/services/dns_resolver/host_overrides", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	result := struct {
+		Data []pfsenseOverride `json:"data"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error unmarshalling pfSense host overrides. %s", err)
+	}
+
+	wanted := map[string][]string{}
+	for _, r := range records {
+		wanted[r.Host] = append(wanted[r.Host], r.Value)
+	}
+	for _, ips := range wanted {
+		sort.Strings(ips)
+	}
+
+	// The IDs are the positions of the overrides, so the last ones are deleted first
+	sort.Slice(result.Data, func(i, j int) bool { return result.Data[i].ID > result.Data[j].ID })
+	existing := map[string]bool{}
+	for _, override := range result.Data {
+		if override.Description != HostOverrideDescription {
+			continue
+		}
+		host := joinHost(override.Host, override.Domain)
+		ips := append([]string{}, override.IP...)
+		sort.Strings(ips)
+		if equalStrings(ips, wanted[host]) && !existing[host] {
+			existing[host] = true
+			continue
+		}
+		_, err := p.do(http.MethodDelete, "/api/v2/services/dns_resolver/host_override?id="+strconv.Itoa(override.ID), nil)
+		if err != nil {
+			return added, deleted, err
+		}
+		deleted = append(deleted, pfsenseRecords(host, ips)...)
+	}
+
+	hosts := make([]string, 0, len(wanted))
+	for host := range wanted {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	for _, host := range hosts {
+		if existing[host] {
+			continue
+		}
+		hostname, domain := splitHost(host)
+		contents, err := json.Marshal(pfsenseOverride{Host: hostname, Domain: domain, IP: wanted[host], Description: HostOverrideDescription})
+		if err != nil {
+			return added, deleted, err
+		}
+		_, err = p.do(http.MethodPost, "/api/v2/services/dns_resolver/host_override", contents)
+		if err != nil {
+			return added, deleted, err
+		}
+		added = append(added, pfsenseRecords(host, wanted[host])...)
+	}
+	return added, deleted, nil
+}
+
+func pfsenseRecords(host string, ips []string) []output.Record {
+	records := make([]output.Record, 0, len(ips))
+	for _, ip := range ips {
+		recordType := "A"
+		if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+			recordType = "AAAA"
+		}
+		records = append(records, output.Record{Host: host, Type: recordType, Value: ip})
+	}
+	return records
+}
+
+// Reconfigure makes the DNS resolver use the current host overrides.
+func (p Pfsense) Reconfigure() error {
+	_, err := p.do(http.MethodPost, "/api/v2/services/dns_resolver/apply", []byte("{}"))
+	return err
+}
+
+func (p Pfsense) do(method string, path string, contents []byte) ([]byte, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(p.URL, "/")+path, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if contents != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-API-Key", p.Key)
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("response from pfSense %s not successful. Status: %s, %s", path, resp.Status, body)
+	}
+	return body, nil
+}
@@ -0,0 +1,43 @@
+package reload
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestPfsenseReconcile(t *testing.T) {
+	api, url := newFakeAPI(t, map[string]string{
+		"GET /api/v2/services/dns_resolver/host_overrides": `{"data":[` +
+			`{"id":0,"host":"a","domain":"example.com","ip":["192.168.1.10"],"descr":"` + HostOverrideDescription + `"},` +
+			`{"id":1,"host":"router","domain":"example.com","ip":["192.168.1.1"],"descr":""},` +
+			`{"id":2,"host":"b","domain":"example.com","ip":["192.168.1.11"],"descr":"` + HostOverrideDescription + `"},` +
+			`{"id":3,"host":"c","domain":"example.com","ip":["fd00::12","192.168.1.12"],"descr":"` + HostOverrideDescription + `"}]}`,
+	}, "{}")
+	p := Pfsense{URL: url}
+
+	added, deleted, err := p.Reconcile([]output.Record{
+		{Host: "c.example.com", Type: "A", Value: "192.168.1.12"},
+		{Host: "c.example.com", Type: "AAAA", Value: "fd00::12"},
+		{Host: "d.example.com", Type: "A", Value: "192.168.1.13"},
+		{Host: "d.example.com", Type: "AAAA", Value: "fd00::13"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() failed. %s", err)
+	}
+	wantAdded := []output.Record{{Host: "d.example.com", Type: "A", Value: "192.168.1.13"}, {Host: "d.example.com", Type: "AAAA", Value: "fd00::13"}}
+	wantDeleted := []output.Record{{Host: "b.example.com", Type: "A", Value: "192.168.1.11"}, {Host: "a.example.com", Type: "A", Value: "192.168.1.10"}}
+	if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Reconcile() = %v, %v, want %v, %v", added, deleted, wantAdded, wantDeleted)
+	}
+	// The overrides are deleted from the last one so the ids of the others keep their position
+	want := []string{
+		`DELETE /api/v2/services/dns_resolver/host_override?id=2`,
+		`DELETE /api/v2/services/dns_resolver/host_override?id=0`,
+		`POST /api/v2/services/dns_resolver/host_override {"host":"d","domain":"example.com","ip":["192.168.1.13","fd00::13"],"descr":"` + HostOverrideDescription + `"}`,
+	}
+	if got := api.sent("GET"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile() sent %q, want %q", got, want)
+	}
+}
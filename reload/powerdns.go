@@ -0,0 +1,230 @@
+package reload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// PowerDNSRRset is the set of records of a name and type of a PowerDNS zone. An RRset without
+// contents is deleted.
+type PowerDNSRRset struct {
+	Name     string
+	Type     string
+	TTL      int
+	Contents []string
+}
+
+// PowerDNS changes the RRsets of Zone of Server of the PowerDNS Authoritative Server at URL
+// with its API.
+type PowerDNS struct {
+	URL    string
+	APIKey string
+	// Server is the id of the server in the API, localhost unless running several.
+	Server string
+	Zone   string
+	// Owner enables the TXT registry, changing only the hosts with the TXT record of OwnerTXT
+	// of Owner.
+	Owner string
+	// Warnf is called with the hosts of other owners, which are skipped.
+	Warnf output.Logf
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type powerdnsRecord struct {
+	Content  string `json:"content"`
+	Disabled bool   `json:"disabled"`
+}
+
+type powerdnsRRset struct {
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	TTL        int              `json:"ttl,omitempty"`
+	ChangeType string           `json:"changetype"`
+	Records    []powerdnsRecord `json:"records"`
+}
+
+// Patch replaces the RRsets with their contents, or deletes the ones without them, in a single
+// request, so the zone is changed atomically and its serial increased once. With Owner, it only
+// changes the hosts of the TXT registry. It returns the RRsets it changed.
+func (p PowerDNS) Patch(rrsets []PowerDNSRRset) ([]PowerDNSRRset, error) {
+	if p.Owner != "" {
+		var err error
+		rrsets, err = p.register(rrsets)
+		if err != nil {
+			return nil, err
+		}
+		if len(rrsets) == 0 {
+			return nil, nil
+		}
+	}
+
+	patch := struct {
+		RRsets []powerdnsRRset `json:"rrsets"`
+	}{RRsets: []powerdnsRRset{}}
+	for _, rrset := range rrsets {
+		change := powerdnsRRset{Name: fqdn(rrset.Name), Type: rrset.Type, ChangeType: "DELETE", Records: []powerdnsRecord{}}
+		if len(rrset.Contents) > 0 {
+			change.TTL = rrset.TTL
+			change.ChangeType = "REPLACE"
+		}
+		for _, content := range rrset.Contents {
+			if rrset.Type == "CNAME" {
+				content = fqdn(content)
+			}
+			change.Records = append(change.Records, powerdnsRecord{Content: content})
+		}
+		patch.RRsets = append(patch.RRsets, change)
+	}
+	contents, err := json.Marshal(patch)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.do(http.MethodPatch, contents)
+	if err != nil {
+		return nil, err
+	}
+	return rrsets, nil
+}
+
+// register keeps the RRsets of the hosts with the TXT record of OwnerTXT of Owner and of the
+// new hosts, adding the TXT record to the new hosts and removing it from the hosts left
+// without records.
+func (p PowerDNS) register(rrsets []PowerDNSRRset) ([]PowerDNSRRset, error) {
+	body, err := p.do(http.MethodGet, nil)
+	if err != nil {
+		return nil, err
+	}
+	zone := struct {
+		RRsets []powerdnsRRset `json:"rrsets"`
+	}{}
+	err = json.Unmarshal(body, &zone)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling the PowerDNS zone %s. %s", p.Zone, err)
+	}
+	marker := strconv.Quote(OwnerTXT(p.Owner))
+	types := map[string]map[string]bool{}
+	texts := map[string][]string{}
+	textTTLs := map[string]int{}
+	for _, rrset := range zone.RRsets {
+		name := strings.ToLower(rrset.Name)
+		if rrset.Type == "TXT" {
+			textTTLs[name] = rrset.TTL
+			for _, record := range rrset.Records {
+				texts[name] = append(texts[name], record.Content)
+			}
+			continue
+		}
+		if types[name] == nil {
+			types[name] = map[string]bool{}
+		}
+		types[name][rrset.Type] = true
+	}
+
+	kept := []PowerDNSRRset{}
+	names := []string{}
+	ttls := map[string]int{}
+	skipped := map[string]bool{}
+	for _, rrset := range rrsets {
+		name := fqdn(rrset.Name)
+		if _, ok := ttls[name]; !ok {
+			names = append(names, name)
+			ttls[name] = rrset.TTL
+		}
+		owned := containsString(texts[name], marker)
+		if !owned && len(types[name]) > 0 {
+			if !skipped[name] {
+				p.Warnf.Printf("Skipping host %s of the zone %s with records not owned by traefik2unbound", rrset.Name, p.Zone)
+				skipped[name] = true
+			}
+			continue
+		}
+		if !owned && len(rrset.Contents) == 0 {
+			continue
+		}
+		if types[name] == nil {
+			types[name] = map[string]bool{}
+		}
+		if len(rrset.Contents) == 0 {
+			delete(types[name], rrset.Type)
+		} else {
+			types[name][rrset.Type] = true
+		}
+		kept = append(kept, rrset)
+	}
+
+	for _, name := range names {
+		owned := containsString(texts[name], marker)
+		if skipped[name] || owned == (len(types[name]) > 0) {
+			continue
+		}
+		others := []string{}
+		for _, text := range texts[name] {
+			if text != marker {
+				others = append(others, text)
+			}
+		}
+		ttl := ttls[name]
+		if textTTLs[name] > 0 {
+			ttl = textTTLs[name]
+		}
+		if !owned {
+			others = append(others, marker)
+		}
+		kept = append(kept, PowerDNSRRset{Name: strings.TrimSuffix(name, "."), Type: "TXT", TTL: ttl, Contents: others})
+	}
+	return kept, nil
+}
+
+// do sends the request to the zone and returns the body of the response.
+func (p PowerDNS) do(method string, contents []byte) ([]byte, error) {
+	path := "/api/v1/servers/" + url.PathEscape(p.Server) + "/zones/" + url.PathEscape(fqdn(p.Zone))
+	req, err := http.NewRequest(method, strings.TrimSuffix(p.URL, "/")+path, bytes.NewReader(contents))
+	if err != nil {
+		return nil, err
+	}
+	if contents != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-API-Key", p.APIKey)
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error requesting the PowerDNS zone %s. %s", p.Zone, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		result := struct {
+			Error string `json:"error"`
+		}{}
+		if json.Unmarshal(body, &result) == nil && result.Error != "" {
+			body = []byte(result.Error)
+		}
+		return nil, fmt.Errorf("response from PowerDNS %s not successful. Status: %s, %s", path, resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package reload
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestPowerDNSPatch(t *testing.T) {
+	api, url := newFakeAPI(t, nil, "")
+	p := PowerDNS{URL: url, APIKey: "key", Server: "localhost", Zone: "example.com"}
+
+	changed, err := p.Patch([]PowerDNSRRset{
+		{Name: "a.example.com", Type: "A", TTL: 60, Contents: []string{"192.168.1.10"}},
+		{Name: "b.example.com", Type: "CNAME", TTL: 60, Contents: []string{"a.example.com"}},
+		{Name: "c.example.com", Type: "A"},
+	})
+	if err != nil {
+		t.Fatalf("Patch() failed. %s", err)
+	}
+	if len(changed) != 3 {
+		t.Errorf("Patch() = %v, want the 3 RRsets", changed)
+	}
+	want := []string{`PATCH /api/v1/servers/localhost/zones/example.com. {"rrsets":[` +
+		`{"name":"a.example.com.","type":"A","ttl":60,"changetype":"REPLACE","records":[{"content":"192.168.1.10","disabled":false}]},` +
+		`{"name":"b.example.com.","type":"CNAME","ttl":60,"changetype":"REPLACE","records":[{"content":"a.example.com.","disabled":false}]},` +
+		`{"name":"c.example.com.","type":"A","changetype":"DELETE","records":[]}]}`}
+	if got := api.sent(""); !reflect.DeepEqual(got, want) {
+		t.Errorf("Patch() sent %q, want %q", got, want)
+	}
+}
+
+func TestPowerDNSPatchOwner(t *testing.T) {
+	marker, _ := json.Marshal(strconv.Quote(OwnerTXT("home")))
+	api, url := newFakeAPI(t, map[string]string{
+		"GET /api/v1/servers/localhost/zones/example.com.": fmt.Sprintf(`{"rrsets":[`+
+			`{"name":"a.example.com.","type":"A","ttl":60,"records":[{"content":"192.168.1.10"}]},`+
+			`{"name":"a.example.com.","type":"TXT","ttl":60,"records":[{"content":%s}]},`+
+			`{"name":"b.example.com.","type":"A","ttl":60,"records":[{"content":"192.168.1.2"}]}]}`, marker),
+	}, "")
+	p := PowerDNS{URL: url, Server: "localhost", Zone: "example.com", Owner: "home"}
+
+	changed, err := p.Patch([]PowerDNSRRset{
+		{Name: "a.example.com", Type: "A", TTL: 60, Contents: []string{"192.168.1.11"}},
+		{Name: "b.example.com", Type: "A", TTL: 60, Contents: []string{"192.168.1.12"}},
+		{Name: "c.example.com", Type: "A", TTL: 60, Contents: []string{"192.168.1.13"}},
+	})
+	if err != nil {
+		t.Fatalf("Patch() failed. %s", err)
+	}
+	// b has records of someone else, and c is new so it gets the TXT record of the owner
+	want := []PowerDNSRRset{
+		{Name: "a.example.com", Type: "A", TTL: 60, Contents: []string{"192.168.1.11"}},
+		{Name: "c.example.com", Type: "A", TTL: 60, Contents: []string{"192.168.1.13"}},
+		{Name: "c.example.com", Type: "TXT", TTL: 60, Contents: []string{strconv.Quote(OwnerTXT("home"))}},
+	}
+	if !reflect.DeepEqual(changed, want) {
+		t.Errorf("Patch() = %v, want %v", changed, want)
+	}
+	if got := api.sent("GET"); len(got) != 1 {
+		t.Errorf("Patch() sent %q, want a single PATCH", got)
+	}
+}
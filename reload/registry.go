@@ -0,0 +1,44 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+)
+
+// OwnerPrefix starts the text of the TXT records marking the hosts owned by traefik2unbound in
+// a zone, like the registry of external-dns.
+const OwnerPrefix = "heritage=traefik2unbound,traefik2unbound/owner="
+
+// registryTimeout bounds the queries of the ownership of a host.
+const registryTimeout = 10 * time.Second
+
+// registryLabel replaces the * of the wildcard hosts in the queries of their ownership, which
+// can't ask for them directly but get the records of the wildcard for any name it matches.
+const registryLabel = "traefik2unbound-registry"
+
+// OwnerTXT returns the text of the TXT record marking the hosts owned by owner.
+func OwnerTXT(owner string) string {
+	return OwnerPrefix + owner
+}
+
+// newResolver returns a resolver that queries the DNS server at address, on port 53 unless it
+// has one.
+func newResolver(address string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+}
+
+// notFound reports whether err is the answer of a name without records.
+func notFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,119 @@
+// Package reload applies a generated file to the DNS server that reads it.
+package reload
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Privileged prefixes the commands reloading the DNS servers, like sudo -n, so the sync can
+// run unprivileged with only them allowed as root. Empty runs them directly.
+var Privileged []string
+
+// Systemctl restarts the systemd service, e.g. unbound or dnsmasq.
+func Systemctl(service string) error {
+	_, err := run("systemctl", "restart", service)
+	if err != nil {
+		return fmt.Errorf("error restarting %s. %s", service, err)
+	}
+	return nil
+}
+
+// Service reloads the service with the service command, like the rc.d scripts of FreeBSD or
+// the init scripts of OpenRC and sysvinit.
+func Service(service string) error {
+	_, err := run("service", service, "reload")
+	if err != nil {
+		return fmt.Errorf("error reloading %s. %s", service, err)
+	}
+	return nil
+}
+
+// Init reloads the service with its init script in /etc/init.d, like on OpenWrt, which has
+// no service command.
+func Init(service string) error {
+	_, err := run("/etc/init.d/"+service, "reload")
+	if err != nil {
+		return fmt.Errorf("error reloading %s. %s", service, err)
+	}
+	return nil
+}
+
+// Pihole reloads the DNS of Pi-hole with the pihole executable at path.
+func Pihole(path string) error {
+	_, err := run(path, "restartdns", "reload")
+	if err != nil {
+		return fmt.Errorf("error reloading pihole. %s", err)
+	}
+	return nil
+}
+
+// Rndc reloads the zone of BIND with the rndc executable at path.
+func Rndc(path string, zone string) error {
+	_, err := run(path, "reload", zone)
+	if err != nil {
+		return fmt.Errorf("error reloading the zone %s. %s", zone, err)
+	}
+	return nil
+}
+
+// Command runs command with the shell, sh or cmd on Windows, e.g. "rc-service unbound reload".
+func Command(command string) error {
+	_, err := run(shell[0], append(shell[1:], command)...)
+	if err != nil {
+		return fmt.Errorf("error running %s. %s", command, err)
+	}
+	return nil
+}
+
+// Signal sends SIGHUP to the process whose pid is in pidfile, which makes unbound and dnsmasq
+// reload their configuration.
+func Signal(pidfile string) error {
+	contents, err := os.ReadFile(pidfile)
+	if err != nil {
+		return fmt.Errorf("error reading the pid file. %s", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(contents)))
+	if err != nil || pid <= 0 {
+		return fmt.Errorf("invalid pid in %s", pidfile)
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("error finding process %d. %s", pid, err)
+	}
+	err = process.Signal(syscall.SIGHUP)
+	if err != nil {
+		return fmt.Errorf("error sending SIGHUP to process %d. %s", pid, err)
+	}
+	return nil
+}
+
+// run runs the command with Privileged and returns its output. The error of a failed command
+// has its output and errors.
+func run(name string, args ...string) (string, error) {
+	if len(Privileged) > 0 {
+		args = append(append(append([]string{}, Privileged[1:]...), name), args...)
+		name = Privileged[0]
+	}
+	return runInput("", name, args...)
+}
+
+// runInput runs the command like run, writing input to its standard input.
+func runInput(input string, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = strings.NewReader(input)
+	var outb, errb bytes.Buffer
+	cmd.Stdout = &outb
+	cmd.Stderr = &errb
+	err := cmd.Run()
+
+	if err != nil {
+		return outb.String(), fmt.Errorf("%s, %s", outb.String(), errb.String())
+	}
+	return outb.String(), nil
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package reload
+
+// shell runs the commands of Command.
+var shell = []string{"sh", "-c"}
@@ -0,0 +1,4 @@
+package reload
+
+// shell runs the commands of Command.
+var shell = []string{"cmd", "/C"}
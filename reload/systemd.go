@@ -0,0 +1,49 @@
+package reload
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultSystemBus is the socket of the D-Bus system bus when $DBUS_SYSTEM_BUS_ADDRESS
+// doesn't set another.
+const DefaultSystemBus = "/run/dbus/system_bus_socket"
+
+// DefaultSystemdTimeout is how long to wait for systemd to reload or restart a unit.
+const DefaultSystemdTimeout = 90 * time.Second
+
+// Systemd reloads or restarts units through the D-Bus API of systemd instead of running
+// systemctl, which works without systemctl and only needs the polkit rights to manage them.
+type Systemd struct {
+	// Bus is the socket of the system bus, DefaultSystemBus or $DBUS_SYSTEM_BUS_ADDRESS
+	// by default.
+	Bus string
+	// Timeout defaults to DefaultSystemdTimeout.
+	Timeout time.Duration
+}
+
+// ReloadOrRestart reloads the unit, e.g. unbound.service, or restarts it if it can't be
+// reloaded, and waits for systemd to finish.
+func (s Systemd) ReloadOrRestart(unit string) error {
+	err := s.reloadOrRestart(unit)
+	if err != nil {
+		return fmt.Errorf("error reloading %s with systemd. %s", unit, err)
+	}
+	return nil
+}
+
+// bus returns the path of the socket of the system bus.
+func (s Systemd) bus() string {
+	if s.Bus != "" {
+		return s.Bus
+	}
+	// The address is like unix:path=/run/dbus/system_bus_socket
+	for _, address := range strings.Split(os.Getenv("DBUS_SYSTEM_BUS_ADDRESS"), ";") {
+		if strings.HasPrefix(address, "unix:path=") {
+			return strings.Split(strings.TrimPrefix(address, "unix:path="), ",")[0]
+		}
+	}
+	return DefaultSystemBus
+}
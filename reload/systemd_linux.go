@@ -0,0 +1,69 @@
+package reload
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/godbus/dbus/v5"
+)
+
+func (s Systemd) reloadOrRestart(unit string) error {
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultSystemdTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	conn, err := dbus.Connect("unix:path="+s.bus(), dbus.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// The JobRemoved signal tells how the job ended
+	err = conn.AddMatchSignalContext(ctx, dbus.WithMatchSender("org.freedesktop.systemd1"), dbus.WithMatchInterface("org.freedesktop.systemd1.Manager"), dbus.WithMatchMember("JobRemoved"))
+	if err != nil {
+		return err
+	}
+	signals := make(chan *dbus.Signal, 16)
+	conn.Signal(signals)
+	systemd := conn.Object("org.freedesktop.systemd1", "/org/freedesktop/systemd1")
+	err = systemd.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.Subscribe", 0).Err
+	if err != nil {
+		return err
+	}
+	var job dbus.ObjectPath
+	err = systemd.CallWithContext(ctx, "org.freedesktop.systemd1.Manager.ReloadOrRestartUnit", 0, unit, "replace").Store(&job)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("error waiting for job %s. %s", job, ctx.Err())
+		case signal, ok := <-signals:
+			if !ok {
+				return fmt.Errorf("error waiting for job %s. The connection to the bus was closed", job)
+			}
+			if signal.Name != "org.freedesktop.systemd1.Manager.JobRemoved" {
+				continue
+			}
+			// JobRemoved has the id, path, unit and result of the job
+			var id uint32
+			var path dbus.ObjectPath
+			var name, result string
+			err = dbus.Store(signal.Body, &id, &path, &name, &result)
+			if err != nil {
+				return fmt.Errorf("invalid JobRemoved signal. %s", err)
+			}
+			if path != job {
+				continue
+			}
+			if result != "done" {
+				return fmt.Errorf("the job finished with result %s", result)
+			}
+			return nil
+		}
+	}
+}
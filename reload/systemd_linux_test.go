@@ -0,0 +1,138 @@
+package reload
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// fakeSystemd answers the calls of the manager of systemd, emitting the JobRemoved signal of
+// every job with result, or a signal without the types of JobRemoved when result is
+// "malformed".
+type fakeSystemd struct {
+	conn   *dbus.Conn
+	result string
+	mu     sync.Mutex
+	units  []string
+}
+
+func (f *fakeSystemd) Subscribe() *dbus.Error {
+	return nil
+}
+
+func (f *fakeSystemd) ReloadOrRestartUnit(unit string, mode string) (dbus.ObjectPath, *dbus.Error) {
+	f.mu.Lock()
+	f.units = append(f.units, unit)
+	id := uint32(len(f.units))
+	f.mu.Unlock()
+	job := dbus.ObjectPath(fmt.Sprintf("/org/freedesktop/systemd1/job/%d", id))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		// A job of another unit first, which must be ignored
+		f.conn.Emit("/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager.JobRemoved", uint32(99), dbus.ObjectPath("/org/freedesktop/systemd1/job/99"), "other.service", "failed")
+		if f.result == "malformed" {
+			f.conn.Emit("/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager.JobRemoved", string(job), unit)
+			return
+		}
+		f.conn.Emit("/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager.JobRemoved", id, job, unit, f.result)
+	}()
+	return job, nil
+}
+
+// startBus starts a private bus with dbus-daemon and returns the path of its socket.
+func startBus(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("dbus-daemon"); err != nil {
+		t.Skip("dbus-daemon is not installed")
+	}
+	socket := filepath.Join(t.TempDir(), "bus")
+	cmd := exec.Command("dbus-daemon", "--session", "--nofork", "--print-address", "--address=unix:path="+socket)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = cmd.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	// The address is printed once the bus is listening
+	_, err = bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("error starting dbus-daemon. %s", err)
+	}
+	return socket
+}
+
+func exportSystemd(t *testing.T, socket string, result string) *fakeSystemd {
+	t.Helper()
+	conn, err := dbus.Connect("unix:path=" + socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	systemd := &fakeSystemd{conn: conn, result: result}
+	err = conn.Export(systemd, "/org/freedesktop/systemd1", "org.freedesktop.systemd1.Manager")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName("org.freedesktop.systemd1", dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("error requesting the name of systemd. %v", err)
+	}
+	return systemd
+}
+
+func TestSystemdReloadOrRestart(t *testing.T) {
+	socket := startBus(t)
+	systemd := exportSystemd(t, socket, "done")
+
+	err := Systemd{Bus: socket, Timeout: 5 * time.Second}.ReloadOrRestart("unbound.service")
+	if err != nil {
+		t.Fatalf("ReloadOrRestart() failed. %s", err)
+	}
+	systemd.mu.Lock()
+	defer systemd.mu.Unlock()
+	if len(systemd.units) != 1 || systemd.units[0] != "unbound.service" {
+		t.Errorf("systemd got the units %v, want unbound.service", systemd.units)
+	}
+}
+
+func TestSystemdReloadOrRestartFailedJob(t *testing.T) {
+	socket := startBus(t)
+	exportSystemd(t, socket, "failed")
+
+	err := Systemd{Bus: socket, Timeout: 5 * time.Second}.ReloadOrRestart("unbound.service")
+	if err == nil || !strings.Contains(err.Error(), "result failed") {
+		t.Errorf("ReloadOrRestart() error = %v, want the failed result", err)
+	}
+}
+
+func TestSystemdReloadOrRestartMalformedSignal(t *testing.T) {
+	socket := startBus(t)
+	exportSystemd(t, socket, "malformed")
+
+	err := Systemd{Bus: socket, Timeout: 5 * time.Second}.ReloadOrRestart("unbound.service")
+	if err == nil || !strings.Contains(err.Error(), "invalid JobRemoved signal") {
+		t.Errorf("ReloadOrRestart() error = %v, want the invalid signal", err)
+	}
+}
+
+func TestSystemdReloadOrRestartWithoutSystemd(t *testing.T) {
+	socket := startBus(t)
+
+	err := Systemd{Bus: socket, Timeout: 5 * time.Second}.ReloadOrRestart("unbound.service")
+	if err == nil {
+		t.Error("ReloadOrRestart() succeeded without systemd on the bus")
+	}
+}
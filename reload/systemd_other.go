@@ -0,0 +1,9 @@
+//go:build !linux
+
+package reload
+
+import "errors"
+
+func (s Systemd) reloadOrRestart(unit string) error {
+	return errors.New("systemd is only available on Linux")
+}
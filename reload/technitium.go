@@ -0,0 +1,190 @@
+package reload
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// Technitium manages the A and AAAA records of Zone of the Technitium DNS Server at URL with
+// its API. Only the hosts with the TXT record of OwnerTXT of Owner are changed, so the records
+// added by hand or by other instances are left as they are.
+type Technitium struct {
+	URL   string
+	Token string
+	Zone  string
+	// Owner tells apart the instances of traefik2unbound sharing the zone.
+	Owner string
+	// TTL of the added records. Zero uses the default of the zone.
+	TTL int
+	// Warnf is called with the hosts that have records not owned by Owner, which are skipped.
+	Warnf output.Logf
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+}
+
+type technitiumRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	RData struct {
+		IPAddress string `json:"ipAddress"`
+		Text      string `json:"text"`
+	} `json:"rData"`
+}
+
+// Reconcile makes the A and AAAA records of the owned hosts of the zone match records, adding
+// the ownership record to the hosts it creates and deleting it from the hosts it empties.
+func (t Technitium) Reconcile(records []output.Record) (added []output.Record, deleted []output.Record, err error) {
+	existing, err := t.records()
+	if err != nil {
+		return nil, nil, err
+	}
+	owned := map[string]bool{}
+	addresses := map[string][]output.Record{}
+	for _, r := range existing {
+		host := strings.ToLower(r.Name)
+		switch r.Type {
+		case "TXT":
+			if r.RData.Text == OwnerTXT(t.Owner) {
+				owned[host] = true
+			}
+		case "A", "AAAA":
+			addresses[host] = append(addresses[host], output.Record{Host: host, Type: r.Type, Value: r.RData.IPAddress})
+		}
+	}
+
+	wanted := map[output.Record]bool{}
+	wantedHosts := map[string]bool{}
+	for _, r := range records {
+		wanted[r] = true
+		wantedHosts[r.Host] = true
+	}
+	for host := range owned {
+		for _, r := range addresses[host] {
+			if wanted[r] {
+				continue
+			}
+			err = t.do("/api/zones/records/delete", url.Values{"domain": {host}, "type": {r.Type}, "ipAddress": {r.Value}})
+			if err != nil {
+				return added, deleted, err
+			}
+			deleted = append(deleted, r)
+		}
+		if !wantedHosts[host] {
+			err = t.do("/api/zones/records/delete", url.Values{"domain": {host}, "type": {"TXT"}, "text": {OwnerTXT(t.Owner)}})
+			if err != nil {
+				return added, deleted, err
+			}
+			delete(owned, host)
+		}
+	}
+
+	present := map[output.Record]bool{}
+	for _, rs := range addresses {
+		for _, r := range rs {
+			present[r] = true
+		}
+	}
+	skipped := map[string]bool{}
+	for _, r := range records {
+		if present[r] && owned[r.Host] || skipped[r.Host] {
+			continue
+		}
+		if !owned[r.Host] {
+			if len(addresses[r.Host]) > 0 {
+				t.Warnf.Printf("Skipping host %s with records in the Technitium zone %s not owned by traefik2unbound", r.Host, t.Zone)
+				skipped[r.Host] = true
+				continue
+			}
+			err = t.do("/api/zones/records/add", t.withTTL(url.Values{"domain": {r.Host}, "type": {"TXT"}, "text": {OwnerTXT(t.Owner)}}))
+			if err != nil {
+				return added, deleted, err
+			}
+			owned[r.Host] = true
+		}
+		err = t.do("/api/zones/records/add", t.withTTL(url.Values{"domain": {r.Host}, "type": {r.Type}, "ipAddress": {r.Value}}))
+		if err != nil {
+			return added, deleted, err
+		}
+		present[r] = true
+		added = append(added, r)
+	}
+	return added, deleted, nil
+}
+
+// Reconfigure does nothing since Technitium serves the records as soon as they are added.
+func (t Technitium) Reconfigure() error {
+	return nil
+}
+
+// records returns every record of the zone.
+func (t Technitium) records() ([]technitiumRecord, error) {
+	result := struct {
+		Response struct {
+			Records []technitiumRecord `json:"records"`
+		} `json:"response"`
+	}{}
+	body, err := t.request("/api/zones/records/get", url.Values{"domain": {t.Zone}, "listZone": {"true"}})
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the records of the Technitium zone %s. %s", t.Zone, err)
+	}
+	return result.Response.Records, nil
+}
+
+func (t Technitium) withTTL(values url.Values) url.Values {
+	if t.TTL > 0 {
+		values.Set("ttl", strconv.Itoa(t.TTL))
+	}
+	return values
+}
+
+func (t Technitium) do(path string, values url.Values) error {
+	_, err := t.request(path, values)
+	return err
+}
+
+// request posts the form values with the token and the zone to path and returns the body of
+// the response.
+func (t Technitium) request(path string, values url.Values) ([]byte, error) {
+	values.Set("token", t.Token)
+	values.Set("zone", t.Zone)
+	client := t.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.PostForm(strings.TrimSuffix(t.URL, "/")+path, values)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("response from Technitium %s not successful. Status: %s, %s", path, resp.Status, body)
+	}
+	// Errors are reported with a 200 status
+	result := struct {
+		Status       string `json:"status"`
+		ErrorMessage string `json:"errorMessage"`
+	}{}
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing the response from Technitium %s. %s", path, err)
+	}
+	if result.Status != "ok" {
+		return nil, fmt.Errorf("response from Technitium %s not successful. Status: %s, %s", path, result.Status, result.ErrorMessage)
+	}
+	return body, nil
+}
@@ -0,0 +1,60 @@
+package reload
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestTechnitiumReconcile(t *testing.T) {
+	api, server := newFakeAPI(t, map[string]string{
+		"POST /api/zones/records/get": fmt.Sprintf(`{"status":"ok","response":{"records":[`+
+			`{"name":"a.example.com","type":"A","rData":{"ipAddress":"192.168.1.10"}},`+
+			`{"name":"a.example.com","type":"TXT","rData":{"text":%q}},`+
+			`{"name":"b.example.com","type":"A","rData":{"ipAddress":"192.168.1.2"}}]}}`, OwnerTXT("home")),
+	}, `{"status":"ok"}`)
+	tech := Technitium{URL: server, Token: "token", Zone: "example.com", Owner: "home"}
+
+	added, deleted, err := tech.Reconcile([]output.Record{
+		{Host: "a.example.com", Type: "A", Value: "192.168.1.11"},
+		{Host: "b.example.com", Type: "A", Value: "192.168.1.12"},
+		{Host: "c.example.com", Type: "A", Value: "192.168.1.13"},
+	})
+	if err != nil {
+		t.Fatalf("Reconcile() failed. %s", err)
+	}
+	wantAdded := []output.Record{{Host: "a.example.com", Type: "A", Value: "192.168.1.11"}, {Host: "c.example.com", Type: "A", Value: "192.168.1.13"}}
+	wantDeleted := []output.Record{{Host: "a.example.com", Type: "A", Value: "192.168.1.10"}}
+	if !reflect.DeepEqual(added, wantAdded) || !reflect.DeepEqual(deleted, wantDeleted) {
+		t.Errorf("Reconcile() = %v, %v, want %v, %v", added, deleted, wantAdded, wantDeleted)
+	}
+
+	form := func(path string, values url.Values) string {
+		values.Set("token", "token")
+		values.Set("zone", "example.com")
+		return "POST " + path + " " + values.Encode()
+	}
+	want := []string{
+		form("/api/zones/records/delete", url.Values{"domain": {"a.example.com"}, "type": {"A"}, "ipAddress": {"192.168.1.10"}}),
+		form("/api/zones/records/add", url.Values{"domain": {"a.example.com"}, "type": {"A"}, "ipAddress": {"192.168.1.11"}}),
+		form("/api/zones/records/add", url.Values{"domain": {"c.example.com"}, "type": {"TXT"}, "text": {OwnerTXT("home")}}),
+		form("/api/zones/records/add", url.Values{"domain": {"c.example.com"}, "type": {"A"}, "ipAddress": {"192.168.1.13"}}),
+	}
+	got := api.sent("")[1:]
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Reconcile() sent %q, want %q", got, want)
+	}
+}
+
+func TestTechnitiumError(t *testing.T) {
+	_, server := newFakeAPI(t, nil, `{"status":"error","errorMessage":"Invalid token"}`)
+	tech := Technitium{URL: server, Zone: "example.com", Owner: "home"}
+
+	_, _, err := tech.Reconcile(nil)
+	if err == nil {
+		t.Errorf("Reconcile() succeeded, want the error reported with a 200 status")
+	}
+}
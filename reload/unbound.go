@@ -0,0 +1,182 @@
+package reload
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// UnboundControl runs the unbound-control executable at Path.
+type UnboundControl struct {
+	Path string
+}
+
+// Run runs unbound-control with args.
+func (u UnboundControl) Run(args ...string) error {
+	_, err := run(u.Path, args...)
+	if err != nil {
+		return fmt.Errorf("error running unbound-control %s. %s", strings.Join(args, " "), err)
+	}
+	return nil
+}
+
+// Reload reloads the configuration of the running unbound.
+func (u UnboundControl) Reload() error {
+	return u.Run("reload")
+}
+
+// ApplyIncrementally pushes only the differences between the old and new file contents to
+// the running unbound, keeping its cache, and returns the names it updated.
+func (u UnboundControl) ApplyIncrementally(oldContents string, newContents string) ([]string, error) {
+	commands, updated := incrementalCommands(ParseUnboundEntries(oldContents), ParseUnboundEntries(newContents))
+	for _, args := range commands {
+		err := u.Run(args...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// incrementalCommands returns the unbound-control commands that turn oldEntries into
+// newEntries, removing the local zones and data before adding them, and the names whose data
+// they update. Names whose data changed are removed and added again since local_data_remove
+// drops every record of a name, and the data under a zone whose type changed is added again
+// since local_zone_remove drops every local-data in the zone.
+func incrementalCommands(oldEntries UnboundEntries, newEntries UnboundEntries) ([][]string, []string) {
+	commands := [][]string{}
+	for name := range oldEntries.Insecure {
+		if !newEntries.Insecure[name] {
+			commands = append(commands, []string{"insecure_remove", name})
+		}
+	}
+	for name := range newEntries.Insecure {
+		if !oldEntries.Insecure[name] {
+			commands = append(commands, []string{"insecure_add", name})
+		}
+	}
+
+	removedZones := []string{}
+	for name, zoneType := range oldEntries.Zones {
+		if newEntries.Zones[name] != zoneType {
+			commands = append(commands, []string{"local_zone_remove", name})
+			removedZones = append(removedZones, name)
+		}
+	}
+	for name, data := range oldEntries.Data {
+		if !equalStrings(data, newEntries.Data[name]) && !inZones(name, removedZones) {
+			commands = append(commands, []string{"local_data_remove", name})
+		}
+	}
+
+	for name, zoneType := range newEntries.Zones {
+		if oldEntries.Zones[name] != zoneType {
+			commands = append(commands, []string{"local_zone", name, zoneType})
+		}
+	}
+	updated := []string{}
+	for name, data := range newEntries.Data {
+		if equalStrings(data, oldEntries.Data[name]) && !inZones(name, removedZones) {
+			continue
+		}
+		for _, d := range data {
+			commands = append(commands, []string{"local_data", d})
+		}
+		updated = append(updated, name)
+	}
+	sort.Strings(updated)
+	return commands, updated
+}
+
+// inZones reports whether name is any of the zones or a name under them.
+func inZones(name string, zones []string) bool {
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, zone := range zones {
+		zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnboundEntries are the local-data, local-zone and domain-insecure entries of a generated
+// file, keyed by the name they belong to.
+type UnboundEntries struct {
+	Data     map[string][]string
+	Zones    map[string]string
+	Insecure map[string]bool
+}
+
+// ParseUnboundEntries extracts the local-data, local-zone and domain-insecure entries from the
+// contents of a generated file. local-data-ptr entries are converted to the PTR local-data
+// unbound-control takes.
+func ParseUnboundEntries(contents string) UnboundEntries {
+	entries := UnboundEntries{Data: map[string][]string{}, Zones: map[string]string{}, Insecure: map[string]bool{}}
+	for _, line := range strings.Split(contents, "\n") {
+		line = strings.TrimSpace(output.WithoutComment(line))
+		switch {
+		case strings.HasPrefix(line, "local-data:"):
+			data := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "local-data:")), "\"")
+			fields := strings.Fields(data)
+			if len(fields) > 0 {
+				entries.Data[fields[0]] = append(entries.Data[fields[0]], data)
+			}
+		case strings.HasPrefix(line, "local-data-ptr:"):
+			fields := strings.Fields(strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "local-data-ptr:")), "\""))
+			if len(fields) < 2 {
+				continue
+			}
+			name := ReverseName(net.ParseIP(fields[0]))
+			if name == "" {
+				continue
+			}
+			data := strings.Join(append([]string{name}, fields[1:len(fields)-1]...), " ") + " PTR " + fields[len(fields)-1]
+			entries.Data[name] = append(entries.Data[name], data)
+		case strings.HasPrefix(line, "local-zone:"):
+			fields := strings.Fields(strings.TrimPrefix(line, "local-zone:"))
+			if len(fields) == 2 {
+				entries.Zones[strings.Trim(fields[0], "\"")] = fields[1]
+			}
+		case strings.HasPrefix(line, "domain-insecure:"):
+			name := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "domain-insecure:")), "\"")
+			if name != "" {
+				entries.Insecure[name] = true
+			}
+		}
+	}
+	for name := range entries.Data {
+		sort.Strings(entries.Data[name])
+	}
+	return entries
+}
+
+// ReverseName returns the in-addr.arpa or ip6.arpa name of the PTR record of ip.
+func ReverseName(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ip4[3], ip4[2], ip4[1], ip4[0])
+	}
+	nibbles := make([]string, 0, 32)
+	for i := len(ip) - 1; i >= 0; i-- {
+		nibbles = append(nibbles, fmt.Sprintf("%x.%x", ip[i]&0x0f, ip[i]>>4))
+	}
+	return strings.Join(nibbles, ".") + ".ip6.arpa."
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package reload
+
+import (
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestParseUnboundEntries(t *testing.T) {
+	contents := `server:
+  # generated
+  local-zone: "example.com." transparent
+  local-data: "b.example.com. IN A 192.168.1.11"
+  local-data: "a.example.com. IN A 192.168.1.10" # whoami@docker
+  local-data: "a.example.com. IN AAAA fd00::10"
+  local-data-ptr: "192.168.1.10 a.example.com."
+  local-data-ptr: "192.168.1.12 300 c.example.com."
+  local-data-ptr: "not-an-ip a.example.com."
+  domain-insecure: "example.com."
+`
+	want := UnboundEntries{
+		Data: map[string][]string{
+			"a.example.com.":             {"a.example.com. IN A 192.168.1.10", "a.example.com. IN AAAA fd00::10"},
+			"b.example.com.":             {"b.example.com. IN A 192.168.1.11"},
+			"10.1.168.192.in-addr.arpa.": {"10.1.168.192.in-addr.arpa. PTR a.example.com."},
+			"12.1.168.192.in-addr.arpa.": {"12.1.168.192.in-addr.arpa. 300 PTR c.example.com."},
+		},
+		Zones:    map[string]string{"example.com.": "transparent"},
+		Insecure: map[string]bool{"example.com.": true},
+	}
+	if got := ParseUnboundEntries(contents); !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseUnboundEntries() = %+v, want %+v", got, want)
+	}
+}
+
+func TestReverseName(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want string
+	}{
+		{"192.168.1.10", "10.1.168.192.in-addr.arpa."},
+		{"::ffff:10.0.0.1", "1.0.0.10.in-addr.arpa."},
+		{"fd00::1", "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.d.f.ip6.arpa."},
+		{"", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			if got := ReverseName(net.ParseIP(test.ip)); got != test.want {
+				t.Errorf("ReverseName(%s) = %s, want %s", test.ip, got, test.want)
+			}
+		})
+	}
+}
+
+func TestIncrementalCommands(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldContents string
+		newContents string
+		want        []string
+		wantUpdated []string
+	}{
+		{
+			name:        "unchanged",
+			oldContents: `local-data: "a.example.com. IN A 192.168.1.10"`,
+			newContents: `local-data: "a.example.com. IN A 192.168.1.10"`,
+			want:        []string{},
+			wantUpdated: []string{},
+		},
+		{
+			name:        "changed data",
+			oldContents: "local-data: \"a.example.com. IN A 192.168.1.10\"\nlocal-data: \"b.example.com. IN A 192.168.1.11\"",
+			newContents: "local-data: \"a.example.com. IN A 192.168.1.20\"\nlocal-data: \"b.example.com. IN A 192.168.1.11\"",
+			want:        []string{"local_data_remove a.example.com.", "local_data a.example.com. IN A 192.168.1.20"},
+			wantUpdated: []string{"a.example.com."},
+		},
+		{
+			name:        "removed name",
+			oldContents: `local-data: "a.example.com. IN A 192.168.1.10"`,
+			newContents: "",
+			want:        []string{"local_data_remove a.example.com."},
+			wantUpdated: []string{},
+		},
+		{
+			name:        "changed zone type",
+			oldContents: "local-zone: \"example.com.\" transparent\nlocal-data: \"a.example.com. IN A 192.168.1.10\"\nlocal-data: \"b.example.org. IN A 192.168.1.11\"",
+			newContents: "local-zone: \"example.com.\" static\nlocal-data: \"a.example.com. IN A 192.168.1.10\"\nlocal-data: \"b.example.org. IN A 192.168.1.11\"",
+			want:        []string{"local_zone_remove example.com.", "local_zone example.com. static", "local_data a.example.com. IN A 192.168.1.10"},
+			wantUpdated: []string{"a.example.com."},
+		},
+		{
+			name:        "insecure",
+			oldContents: `domain-insecure: "example.com."`,
+			newContents: `domain-insecure: "example.org."`,
+			want:        []string{"insecure_remove example.com.", "insecure_add example.org."},
+			wantUpdated: []string{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			commands, updated := incrementalCommands(ParseUnboundEntries(test.oldContents), ParseUnboundEntries(test.newContents))
+			got := make([]string, 0, len(commands))
+			for _, args := range commands {
+				got = append(got, strings.Join(args, " "))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("incrementalCommands() = %q, want %q", got, test.want)
+			}
+			if !reflect.DeepEqual(updated, test.wantUpdated) {
+				t.Errorf("incrementalCommands() updated %v, want %v", updated, test.wantUpdated)
+			}
+		})
+	}
+}
+
+func TestIncrementalCommandsRemoveBeforeAdd(t *testing.T) {
+	oldContents := "local-zone: \"a.example.com.\" redirect\nlocal-data: \"a.example.com. IN A 192.168.1.10\"\nlocal-data: \"b.example.com. IN A 192.168.1.11\"\nlocal-data: \"c.example.com. IN A 192.168.1.12\""
+	newContents := "local-zone: \"b.example.com.\" redirect\nlocal-data: \"b.example.com. IN A 192.168.1.21\"\nlocal-data: \"c.example.com. IN A 192.168.1.22\"\nlocal-data: \"d.example.com. IN A 192.168.1.23\""
+	commands, _ := incrementalCommands(ParseUnboundEntries(oldContents), ParseUnboundEntries(newContents))
+
+	lastRemoval, firstAddition := -1, len(commands)
+	names := []string{}
+	for i, args := range commands {
+		names = append(names, strings.Join(args, " "))
+		if strings.HasSuffix(args[0], "_remove") {
+			lastRemoval = i
+		} else if i < firstAddition {
+			firstAddition = i
+		}
+	}
+	if lastRemoval > firstAddition {
+		t.Errorf("incrementalCommands() = %q, want every removal before the additions", names)
+	}
+	sort.Strings(names)
+	want := []string{
+		"local_data b.example.com. IN A 192.168.1.21",
+		"local_data c.example.com. IN A 192.168.1.22",
+		"local_data d.example.com. IN A 192.168.1.23",
+		"local_data_remove b.example.com.",
+		"local_data_remove c.example.com.",
+		"local_zone b.example.com. redirect",
+		"local_zone_remove a.example.com.",
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("incrementalCommands() = %q, want %q", names, want)
+	}
+}
@@ -0,0 +1,75 @@
+package rules
+
+import (
+	"regexp"
+	"strings"
+)
+
+// templateVariable matches the variables of the Traefik v2 HostRegexp templates, {name} or
+// {name:pattern}. Regular expression repetitions like {2} or {1,3} don't match.
+var templateVariable = regexp.MustCompile(`\{[A-Za-z_][A-Za-z0-9_]*(:[^{}]*(\{[^{}]*\}[^{}]*)*)?\}`)
+
+// literalDomain matches hosts without wildcards or regular expression syntax.
+var literalDomain = regexp.MustCompile(`^[A-Za-z0-9-]+(\.[A-Za-z0-9-]+)+$`)
+
+// anyLabel matches the patterns that match any subdomain, like .+ or [a-z0-9-]+.
+var anyLabel = regexp.MustCompile(`^(\.|\[[^\]]+\]|\[\^\.\])[+*]$`)
+
+// CompileHostRegexp compiles the argument of a HostRegexp matcher, either a Traefik v2
+// template like {sub:[a-z]+}.example.com or a Traefik v3 regular expression, into a regular
+// expression matching the whole host.
+func CompileHostRegexp(expression string) (*regexp.Regexp, error) {
+	if templateVariable.MatchString(expression) {
+		return regexp.Compile("^" + templateToRegexp(expression) + "$")
+	}
+	return regexp.Compile("^(?:" + strings.TrimSuffix(strings.TrimPrefix(expression, "^"), "$") + ")$")
+}
+
+// templateToRegexp quotes the literal parts of a Traefik v2 template and replaces its
+// variables with their patterns, [^.]+ when they have none.
+func templateToRegexp(template string) string {
+	builder := strings.Builder{}
+	last := 0
+	for _, match := range templateVariable.FindAllStringIndex(template, -1) {
+		builder.WriteString(regexp.QuoteMeta(template[last:match[0]]))
+		variable := template[match[0]+1 : match[1]-1]
+		pattern := "[^.]+"
+		if _, p, found := strings.Cut(variable, ":"); found {
+			pattern = p
+		}
+		builder.WriteString("(?:" + pattern + ")")
+		last = match[1]
+	}
+	builder.WriteString(regexp.QuoteMeta(template[last:]))
+	return builder.String()
+}
+
+// WildcardDomain returns the domain of an expression that matches all its subdomains, like the
+// template {sub:[a-z]+}.example.com or the regular expression ^.+\.example\.com$, so it can be
+// published as *.example.com. It reports false for any other expression.
+func WildcardDomain(expression string) (string, bool) {
+	var label, domain string
+	if match := templateVariable.FindStringIndex(expression); match != nil && match[0] == 0 {
+		label, domain = "[^.]+", expression[match[1]:]
+		if _, pattern, found := strings.Cut(expression[1:match[1]-1], ":"); found {
+			label = pattern
+		}
+		if !strings.HasPrefix(domain, ".") {
+			return "", false
+		}
+		domain = domain[1:]
+	} else {
+		expression = strings.TrimSuffix(strings.TrimPrefix(expression, "^"), "$")
+		i := strings.Index(expression, `\.`)
+		if i <= 0 {
+			return "", false
+		}
+		label = expression[:i]
+		domain = strings.ReplaceAll(expression[i+2:], `\.`, ".")
+		domain = strings.ReplaceAll(domain, `\-`, "-")
+	}
+	if !literalDomain.MatchString(domain) || !anyLabel.MatchString(label) {
+		return "", false
+	}
+	return domain, true
+}
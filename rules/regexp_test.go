@@ -0,0 +1,126 @@
+package rules
+
+import "testing"
+
+func TestCompileHostRegexp(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		matches    []string
+		rejects    []string
+	}{
+		{
+			name:       "template",
+			expression: "{sub:[a-z]+}.example.com",
+			matches:    []string{"a.example.com", "abc.example.com"},
+			rejects:    []string{"a1.example.com", "a.b.example.com", "aexample.com", "a.example.com.evil"},
+		},
+		{
+			name:       "template without pattern",
+			expression: "{sub}.example.com",
+			matches:    []string{"a1.example.com"},
+			rejects:    []string{"a.b.example.com", "example.com"},
+		},
+		{
+			name:       "template with repetition",
+			expression: "{sub:[a-z]{2}}.example.com",
+			matches:    []string{"ab.example.com"},
+			rejects:    []string{"a.example.com", "abc.example.com"},
+		},
+		{
+			name:       "several variables",
+			expression: "{sub}.{env:dev|prod}.example.com",
+			matches:    []string{"a.dev.example.com", "a.prod.example.com"},
+			rejects:    []string{"a.test.example.com"},
+		},
+		{
+			name:       "v3 regexp",
+			expression: `^.+\.example\.com$`,
+			matches:    []string{"a.example.com", "a.b.example.com"},
+			rejects:    []string{"example.com", "a.example.org"},
+		},
+		{
+			name:       "v3 regexp without anchors",
+			expression: `[a-z]+\.example\.com`,
+			matches:    []string{"a.example.com"},
+			rejects:    []string{"a.example.com.evil", "1.a.example.com"},
+		},
+		{
+			name:       "v3 alternation",
+			expression: `^a\.example\.com|b\.example\.com$`,
+			matches:    []string{"a.example.com", "b.example.com"},
+			rejects:    []string{"a.example.com.evil", "evil.b.example.com"},
+		},
+		{
+			name:       "v3 repetition",
+			expression: `^[a-z]{1,3}\.example\.com$`,
+			matches:    []string{"abc.example.com"},
+			rejects:    []string{"abcd.example.com"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			re, err := CompileHostRegexp(test.expression)
+			if err != nil {
+				t.Fatalf("CompileHostRegexp(%s) failed. %s", test.expression, err)
+			}
+			for _, host := range test.matches {
+				if !re.MatchString(host) {
+					t.Errorf("%s doesn't match %s, want a match", re, host)
+				}
+			}
+			for _, host := range test.rejects {
+				if re.MatchString(host) {
+					t.Errorf("%s matches %s, want no match", re, host)
+				}
+			}
+		})
+	}
+}
+
+func TestCompileHostRegexpInvalid(t *testing.T) {
+	for _, expression := range []string{`^(.+\.example\.com$`, "{sub:[a-z}.example.com", `[z-a]+\.example\.com`} {
+		t.Run(expression, func(t *testing.T) {
+			_, err := CompileHostRegexp(expression)
+			if err == nil {
+				t.Errorf("CompileHostRegexp(%s) succeeded, want an error", expression)
+			}
+		})
+	}
+}
+
+func TestWildcardDomain(t *testing.T) {
+	tests := []struct {
+		expression string
+		want       string
+		ok         bool
+	}{
+		{"{sub:[a-z0-9-]+}.example.com", "example.com", true},
+		{"{sub}.example.com", "example.com", true},
+		{"{sub:.+}.apps.example.com", "apps.example.com", true},
+		{`^.+\.example\.com$`, "example.com", true},
+		{`.*\.example\.com`, "example.com", true},
+		{`^[^.]+\.my\-apps\.example\.com$`, "my-apps.example.com", true},
+		{`^[a-z]+\.example\.com$`, "example.com", true},
+		// Rejected since they don't match any subdomain, or aren't a subdomain of a literal domain
+		{"{sub:[a-z]{2}}.example.com", "", false},
+		{"{sub:a|b}.example.com", "", false},
+		{"a.{sub}.example.com", "", false},
+		{"{sub}example.com", "", false},
+		{"{sub}.{env}.example.com", "", false},
+		{`^a\.example\.com$`, "", false},
+		{`^.+\.example\.(com|org)$`, "", false},
+		{`^.+\.example\.com|.+\.example\.org$`, "", false},
+		{`^.+\.com$`, "", false},
+		{`^.+$`, "", false},
+		{`\.example\.com`, "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			got, ok := WildcardDomain(test.expression)
+			if got != test.want || ok != test.ok {
+				t.Errorf("WildcardDomain(%s) = %s, %t, want %s, %t", test.expression, got, ok, test.want, test.ok)
+			}
+		})
+	}
+}
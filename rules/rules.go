@@ -0,0 +1,270 @@
+// Package rules extracts the hosts of Traefik router rules.
+package rules
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type ruleTokenKind int
+
+const (
+	tokenMatcher ruleTokenKind = iota
+	tokenString
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpenParen
+	tokenCloseParen
+	tokenComma
+	tokenEnd
+)
+
+// hostMatchers are the matchers whose arguments are hostnames. HostHeader is the
+// Traefik v2 alias of Host.
+var hostMatchers = map[string]bool{
+	"Host":       true,
+	"HostHeader": true,
+	"HostSNI":    true,
+}
+
+// hostRegexpMatchers are the matchers whose arguments are host regular expressions.
+var hostRegexpMatchers = map[string]bool{
+	"HostRegexp":    true,
+	"HostSNIRegexp": true,
+}
+
+// pathMatchers are the matchers whose arguments are request paths.
+var pathMatchers = map[string]bool{
+	"Path":       true,
+	"PathPrefix": true,
+}
+
+// catchAllSNI is the HostSNI argument TCP routers use to match every connection.
+const catchAllSNI = "*"
+
+type ruleToken struct {
+	kind  ruleTokenKind
+	value string
+}
+
+// Result are the hosts a rule matches.
+type Result struct {
+	// Hosts are the arguments of the Host and HostSNI matchers.
+	Hosts []string
+	// Regexps are the arguments of the HostRegexp and HostSNIRegexp matchers, Traefik v2
+	// templates like {sub:[a-z]+}.example.com or Traefik v3 regular expressions.
+	Regexps []string
+	// Paths are the arguments of the Path and PathPrefix matchers.
+	Paths []string
+}
+
+// Parse returns the hosts of the host matchers of a Traefik rule like
+// "Host(`a.example.com`, `b.example.com`) || Host(`c.example.com`) && !ClientIP(`10.0.0.0/8`)".
+// Matchers inside negated expressions are ignored because the router doesn't serve those hosts.
+func Parse(rule string) (Result, error) {
+	tokens, err := tokenizeRule(rule)
+	if err != nil {
+		return Result{}, err
+	}
+	p := ruleParser{tokens: tokens}
+	err = p.parseExpression(false)
+	if err != nil {
+		return Result{}, err
+	}
+	if p.peek().kind != tokenEnd {
+		return Result{}, fmt.Errorf("unexpected %q at the end of rule %s", p.peek().value, rule)
+	}
+	return p.result, nil
+}
+
+// Hosts returns the hosts of the Host and HostSNI matchers of a Traefik rule.
+func Hosts(rule string) ([]string, error) {
+	result, err := Parse(rule)
+	return result.Hosts, err
+}
+
+func tokenizeRule(rule string) ([]ruleToken, error) {
+	tokens := []ruleToken{}
+	for i := 0; i < len(rule); {
+		c := rule[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case strings.HasPrefix(rule[i:], "&&"):
+			tokens = append(tokens, ruleToken{kind: tokenAnd, value: "&&"})
+			i += 2
+		case strings.HasPrefix(rule[i:], "||"):
+			tokens = append(tokens, ruleToken{kind: tokenOr, value: "||"})
+			i += 2
+		case c == '!':
+			tokens = append(tokens, ruleToken{kind: tokenNot, value: "!"})
+			i++
+		case c == '(':
+			tokens = append(tokens, ruleToken{kind: tokenOpenParen, value: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, ruleToken{kind: tokenCloseParen, value: ")"})
+			i++
+		case c == ',':
+			tokens = append(tokens, ruleToken{kind: tokenComma, value: ","})
+			i++
+		case c == '`':
+			end := strings.IndexByte(rule[i+1:], '`')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string in rule %s", rule)
+			}
+			tokens = append(tokens, ruleToken{kind: tokenString, value: rule[i+1 : i+1+end]})
+			i += end + 2
+		case c == '"':
+			end := i + 1
+			for end < len(rule) && rule[end] != '"' {
+				if rule[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rule) {
+				return nil, fmt.Errorf("unterminated string in rule %s", rule)
+			}
+			value, err := strconv.Unquote(rule[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string %s in rule %s. %s", rule[i:end+1], rule, err)
+			}
+			tokens = append(tokens, ruleToken{kind: tokenString, value: value})
+			i = end + 1
+		case unicode.IsLetter(rune(c)):
+			end := i
+			for end < len(rule) && (unicode.IsLetter(rune(rule[end])) || unicode.IsDigit(rune(rule[end]))) {
+				end++
+			}
+			tokens = append(tokens, ruleToken{kind: tokenMatcher, value: rule[i:end]})
+			i = end
+		default:
+			return nil, fmt.Errorf("unexpected character %q in rule %s", c, rule)
+		}
+	}
+	return append(tokens, ruleToken{kind: tokenEnd, value: "end of rule"}), nil
+}
+
+// ruleParser is a recursive descent parser for the grammar
+//
+//	expression = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | "(" expression ")" | matcher
+//	matcher    = name "(" [ string { "," string } ] ")"
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+	result Result
+}
+
+func (p *ruleParser) peek() ruleToken {
+	return p.tokens[p.pos]
+}
+
+func (p *ruleParser) next() ruleToken {
+	token := p.tokens[p.pos]
+	if token.kind != tokenEnd {
+		p.pos++
+	}
+	return token
+}
+
+func (p *ruleParser) expect(kind ruleTokenKind, description string) (ruleToken, error) {
+	token := p.next()
+	if token.kind != kind {
+		return token, fmt.Errorf("expected %s but found %q", description, token.value)
+	}
+	return token, nil
+}
+
+func (p *ruleParser) parseExpression(negated bool) error {
+	err := p.parseAnd(negated)
+	for err == nil && p.peek().kind == tokenOr {
+		p.next()
+		err = p.parseAnd(negated)
+	}
+	return err
+}
+
+func (p *ruleParser) parseAnd(negated bool) error {
+	err := p.parseUnary(negated)
+	for err == nil && p.peek().kind == tokenAnd {
+		p.next()
+		err = p.parseUnary(negated)
+	}
+	return err
+}
+
+func (p *ruleParser) parseUnary(negated bool) error {
+	switch p.peek().kind {
+	case tokenNot:
+		p.next()
+		return p.parseUnary(!negated)
+	case tokenOpenParen:
+		p.next()
+		err := p.parseExpression(negated)
+		if err != nil {
+			return err
+		}
+		_, err = p.expect(tokenCloseParen, "\")\"")
+		return err
+	default:
+		return p.parseMatcher(negated)
+	}
+}
+
+func (p *ruleParser) parseMatcher(negated bool) error {
+	name, err := p.expect(tokenMatcher, "a matcher")
+	if err != nil {
+		return err
+	}
+	_, err = p.expect(tokenOpenParen, "\"(\" after "+name.value)
+	if err != nil {
+		return err
+	}
+
+	args := []string{}
+	if p.peek().kind != tokenCloseParen {
+		for {
+			arg, err := p.expect(tokenString, "a string argument for "+name.value)
+			if err != nil {
+				return err
+			}
+			args = append(args, arg.value)
+			if p.peek().kind != tokenComma {
+				break
+			}
+			p.next()
+		}
+	}
+	_, err = p.expect(tokenCloseParen, "\")\" after the arguments of "+name.value)
+	if err != nil {
+		return err
+	}
+
+	if negated {
+		return nil
+	}
+	if pathMatchers[name.value] {
+		p.result.Paths = append(p.result.Paths, args...)
+		return nil
+	}
+	if hostRegexpMatchers[name.value] {
+		p.result.Regexps = append(p.result.Regexps, args...)
+		return nil
+	}
+	if !hostMatchers[name.value] {
+		return nil
+	}
+	for _, arg := range args {
+		if name.value == "HostSNI" && arg == catchAllSNI {
+			continue
+		}
+		p.result.Hosts = append(p.result.Hosts, arg)
+	}
+	return nil
+}
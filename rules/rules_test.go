@@ -0,0 +1,142 @@
+package rules
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+		want Result
+	}{
+		{
+			name: "host",
+			rule: "Host(`a.example.com`)",
+			want: Result{Hosts: []string{"a.example.com"}},
+		},
+		{
+			name: "several arguments",
+			rule: "Host(`a.example.com`, `b.example.com`)",
+			want: Result{Hosts: []string{"a.example.com", "b.example.com"}},
+		},
+		{
+			name: "and with a negated matcher",
+			rule: "Host(`a.example.com`) && PathPrefix(`/`) && !ClientIP(`10.0.0.0/8`)",
+			want: Result{Hosts: []string{"a.example.com"}, Paths: []string{"/"}},
+		},
+		{
+			name: "or",
+			rule: "Host(`a.example.com`) || Host(`b.example.com`)",
+			want: Result{Hosts: []string{"a.example.com", "b.example.com"}},
+		},
+		{
+			name: "negated host",
+			rule: "!Host(`a.example.com`)",
+			want: Result{},
+		},
+		{
+			name: "double negation",
+			rule: "!!Host(`a.example.com`)",
+			want: Result{Hosts: []string{"a.example.com"}},
+		},
+		{
+			name: "negated parentheses",
+			rule: "PathPrefix(`/api`) && !(Host(`a.example.com`) || Path(`/health`))",
+			want: Result{Paths: []string{"/api"}},
+		},
+		{
+			name: "nested parentheses",
+			rule: "((Host(`a.example.com`) || (Host(`b.example.com`))) && (PathPrefix(`/a`) || PathPrefix(`/b`)))",
+			want: Result{Hosts: []string{"a.example.com", "b.example.com"}, Paths: []string{"/a", "/b"}},
+		},
+		{
+			name: "catch-all HostSNI",
+			rule: "HostSNI(`*`)",
+			want: Result{},
+		},
+		{
+			name: "HostSNI",
+			rule: "HostSNI(`db.example.com`, `*`)",
+			want: Result{Hosts: []string{"db.example.com"}},
+		},
+		{
+			name: "HostHeader",
+			rule: "HostHeader(`a.example.com`)",
+			want: Result{Hosts: []string{"a.example.com"}},
+		},
+		{
+			name: "HostRegexp",
+			rule: "HostRegexp(`{sub:[a-z]+}.example.com`) || HostSNIRegexp(`^.+\\.example\\.com$`)",
+			want: Result{Regexps: []string{"{sub:[a-z]+}.example.com", `^.+\.example\.com$`}},
+		},
+		{
+			name: "double quoted strings",
+			rule: `Host("a.example.com") && Path("/a\"b")`,
+			want: Result{Hosts: []string{"a.example.com"}, Paths: []string{`/a"b`}},
+		},
+		{
+			name: "matcher without arguments",
+			rule: "Method() || Host(`a.example.com`)",
+			want: Result{Hosts: []string{"a.example.com"}},
+		},
+		{
+			name: "whitespace",
+			rule: "\tHost( `a.example.com` )\n&&\r\nPath(`/`)",
+			want: Result{Hosts: []string{"a.example.com"}, Paths: []string{"/"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.rule)
+			if err != nil {
+				t.Fatalf("Parse(%s) failed. %s", test.rule, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Parse(%s) = %+v, want %+v", test.rule, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		rule string
+	}{
+		{"empty", ""},
+		{"unterminated backtick", "Host(`a.example.com)"},
+		{"unterminated quote", `Host("a.example.com)`},
+		{"invalid escape", `Host("a\qb")`},
+		{"missing closing parenthesis", "Host(`a.example.com`"},
+		{"unbalanced parentheses", "(Host(`a.example.com`)"},
+		{"extra closing parenthesis", "Host(`a.example.com`))"},
+		{"missing operand", "Host(`a.example.com`) &&"},
+		{"missing operator", "Host(`a.example.com`) Host(`b.example.com`)"},
+		{"single ampersand", "Host(`a.example.com`) & Path(`/`)"},
+		{"unquoted argument", "Host(a.example.com)"},
+		{"trailing comma", "Host(`a.example.com`,)"},
+		{"matcher without parentheses", "Host"},
+		{"unexpected character", "Host(`a.example.com`) ; Path(`/`)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Parse(test.rule)
+			if err == nil {
+				t.Errorf("Parse(%s) = %+v, want an error", test.rule, got)
+			}
+		})
+	}
+}
+
+func TestHosts(t *testing.T) {
+	hosts, err := Hosts("Host(`a.example.com`) && !Host(`b.example.com`) || HostSNI(`c.example.com`)")
+	if err != nil {
+		t.Fatalf("Hosts() failed. %s", err)
+	}
+	want := []string{"a.example.com", "c.example.com"}
+	if !reflect.DeepEqual(hosts, want) {
+		t.Errorf("Hosts() = %v, want %v", hosts, want)
+	}
+}
@@ -0,0 +1,53 @@
+package syncer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// addOrigin appends origin to origins unless it is already there.
+func addOrigin(origins []Origin, origin Origin) []Origin {
+	for _, o := range origins {
+		if o == origin {
+			return origins
+		}
+	}
+	return append(origins, origin)
+}
+
+// originComments returns the comment of every host of results, like
+// "router a@docker (docker) from https://traefik.lan", with the instances separated by ;.
+func (s *Syncer) originComments(results []FetchResult) map[string]string {
+	comments := map[string]string{}
+	for i, result := range results {
+		for host, origins := range result.Origins {
+			routers := make([]string, 0, len(origins))
+			for _, origin := range origins {
+				if origin.Provider != "" {
+					routers = append(routers, fmt.Sprintf("%s (%s)", origin.Router, origin.Provider))
+				} else {
+					routers = append(routers, origin.Router)
+				}
+			}
+			comment := fmt.Sprintf("router %s from %s", strings.Join(routers, ", "), s.Instances[i].URL)
+			if len(routers) > 1 {
+				comment = "routers" + strings.TrimPrefix(comment, "router")
+			}
+			if comments[host] != "" {
+				comment = comments[host] + "; " + comment
+			}
+			comments[host] = comment
+		}
+	}
+	return comments
+}
+
+// HostComment returns the comment of host of the last sync with Annotate, the routers and
+// instances it comes from.
+func (s *Syncer) HostComment(host string) string {
+	if !s.Annotate {
+		return ""
+	}
+	// The comments end the lines, so they can't span several
+	return strings.NewReplacer("\n", " ", "\r", " ").Replace(s.comments[host])
+}
@@ -0,0 +1,134 @@
+package syncer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// ResolveConflicts publishes the records of the instance of highest priority for the hosts
+// with different records on several instances, and applies ConflictPolicy to the ones on
+// several instances of the same priority. recordSets holds the records of each instance in the
+// order of Instances. Without it the DNS server would answer a mix of both instances.
+func (s *Syncer) ResolveConflicts(recordSets [][]output.Record) ([][]output.Record, error) {
+	// The records of every host on each instance, like "A 192.168.1.10"
+	values := map[string]map[int][]string{}
+	for i, records := range recordSets {
+		for _, r := range output.UniqueRecords(records) {
+			if values[r.Host] == nil {
+				values[r.Host] = map[int][]string{}
+			}
+			values[r.Host][i] = append(values[r.Host][i], r.Type+" "+r.Value)
+		}
+	}
+
+	hosts := make([]string, 0, len(values))
+	for host := range values {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	winners := map[string]map[int]bool{}
+	for _, host := range hosts {
+		sets := values[host]
+		if !conflicting(sets) {
+			continue
+		}
+		indexes := make([]int, 0, len(sets))
+		for i := range sets {
+			indexes = append(indexes, i)
+		}
+		sort.Ints(indexes)
+
+		top := s.highestPriority(indexes)
+		if len(top) < len(indexes) {
+			winners[host] = map[int]bool{}
+			for _, i := range top {
+				winners[host][i] = true
+			}
+			s.Debugf.Printf("Publishing host %s of %s, of highest priority", host, s.instanceURLs(top))
+			topSets := map[int][]string{}
+			for _, i := range top {
+				topSets[i] = sets[i]
+			}
+			if !conflicting(topSets) {
+				continue
+			}
+			sets, indexes = topSets, top
+		}
+
+		descriptions := make([]string, 0, len(sets))
+		for _, i := range indexes {
+			descriptions = append(descriptions, fmt.Sprintf("%s on %s", strings.Join(sets[i], ", "), s.Instances[i].URL))
+		}
+		conflict := fmt.Sprintf("host %s has different records on several instances: %s", host, strings.Join(descriptions, "; "))
+		switch s.ConflictPolicy {
+		case ConflictFail:
+			return nil, fmt.Errorf("aborting the sync since %s. Use -on-conflict to publish it", conflict)
+		case ConflictFirstWins:
+			winners[host] = map[int]bool{indexes[0]: true}
+		case ConflictLastWins:
+			winners[host] = map[int]bool{indexes[len(indexes)-1]: true}
+		default:
+			s.Warnf.Printf("Publishing the records of %s since %s", s.instanceURLs(indexes), conflict)
+			continue
+		}
+		for i := range winners[host] {
+			s.Warnf.Printf("Publishing the records of %s since %s", s.Instances[i].URL, conflict)
+		}
+	}
+	if len(winners) == 0 {
+		return recordSets, nil
+	}
+
+	resolved := make([][]output.Record, 0, len(recordSets))
+	for i, records := range recordSets {
+		kept := make([]output.Record, 0, len(records))
+		for _, r := range records {
+			if winner, ok := winners[r.Host]; ok && !winner[i] {
+				continue
+			}
+			kept = append(kept, r)
+		}
+		resolved = append(resolved, kept)
+	}
+	return resolved, nil
+}
+
+// conflicting reports whether the instances of sets don't have the same records.
+func conflicting(sets map[int][]string) bool {
+	first := ""
+	for _, values := range sets {
+		sort.Strings(values)
+		joined := strings.Join(values, ",")
+		if first == "" {
+			first = joined
+		} else if joined != first {
+			return true
+		}
+	}
+	return false
+}
+
+// highestPriority returns the indexes of the instances with the highest priority.
+func (s *Syncer) highestPriority(indexes []int) []int {
+	top := []int{}
+	for _, i := range indexes {
+		switch {
+		case len(top) == 0 || s.Instances[i].Priority == s.Instances[top[0]].Priority:
+			top = append(top, i)
+		case s.Instances[i].Priority > s.Instances[top[0]].Priority:
+			top = []int{i}
+		}
+	}
+	return top
+}
+
+func (s *Syncer) instanceURLs(indexes []int) string {
+	urls := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		urls = append(urls, s.Instances[i].URL)
+	}
+	return strings.Join(urls, ", ")
+}
@@ -0,0 +1,58 @@
+package syncer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestResolveConflicts(t *testing.T) {
+	recordSets := [][]output.Record{
+		{{Host: "a.example.com", Type: "A", Value: "192.168.1.10"}, {Host: "b.example.com", Type: "A", Value: "192.168.1.10"}},
+		{{Host: "a.example.com", Type: "A", Value: "192.168.1.20"}, {Host: "b.example.com", Type: "A", Value: "192.168.1.10"}},
+	}
+	tests := []struct {
+		policy     string
+		priorities []int
+		want       string
+	}{
+		{ConflictEmitAll, []int{0, 0}, "[[{a.example.com A 192.168.1.10} {b.example.com A 192.168.1.10}] [{a.example.com A 192.168.1.20} {b.example.com A 192.168.1.10}]]"},
+		{ConflictFirstWins, []int{0, 0}, "[[{a.example.com A 192.168.1.10} {b.example.com A 192.168.1.10}] [{b.example.com A 192.168.1.10}]]"},
+		{ConflictLastWins, []int{0, 0}, "[[{b.example.com A 192.168.1.10}] [{a.example.com A 192.168.1.20} {b.example.com A 192.168.1.10}]]"},
+		{ConflictEmitAll, []int{0, 1}, "[[{b.example.com A 192.168.1.10}] [{a.example.com A 192.168.1.20} {b.example.com A 192.168.1.10}]]"},
+		{ConflictFail, []int{1, 0}, "[[{a.example.com A 192.168.1.10} {b.example.com A 192.168.1.10}] [{b.example.com A 192.168.1.10}]]"},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%s %v", test.policy, test.priorities), func(t *testing.T) {
+			s := newTestSyncer(t, Options{
+				Instances: []*Instance{
+					{URL: "http://traefik1.lan", Priority: test.priorities[0]},
+					{URL: "http://traefik2.lan", Priority: test.priorities[1]},
+				},
+				ConflictPolicy: test.policy,
+			})
+			resolved, err := s.ResolveConflicts(recordSets)
+			if err != nil {
+				t.Fatalf("ResolveConflicts() failed. %s", err)
+			}
+			if got := fmt.Sprint(resolved); got != test.want {
+				t.Errorf("ResolveConflicts() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestResolveConflictsFail(t *testing.T) {
+	s := newTestSyncer(t, Options{
+		Instances:      []*Instance{{URL: "http://traefik1.lan"}, {URL: "http://traefik2.lan"}},
+		ConflictPolicy: ConflictFail,
+	})
+	_, err := s.ResolveConflicts([][]output.Record{
+		{{Host: "a.example.com", Type: "A", Value: "192.168.1.10"}},
+		{{Host: "a.example.com", Type: "A", Value: "192.168.1.20"}},
+	})
+	if err == nil {
+		t.Errorf("ResolveConflicts() succeeded, want an error")
+	}
+}
@@ -0,0 +1,57 @@
+package syncer
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// numberedLines returns the lines from 1 to n, with the ones in replaced changed.
+func numberedLines(n int, replaced map[int]string) string {
+	builder := strings.Builder{}
+	for i := 1; i <= n; i++ {
+		line, ok := replaced[i]
+		if !ok {
+			line = strconv.Itoa(i)
+		}
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		oldText string
+		newText string
+		want    string
+	}{
+		{"equal", "a\n", "a\n", ""},
+		{"new file", "", "x\ny\n", "@@ -0,0 +1,2 @@\n+x\n+y\n"},
+		{"removed file", "x\ny\n", "", "@@ -1,2 +0,0 @@\n-x\n-y\n"},
+		{
+			"close changes",
+			numberedLines(10, nil),
+			numberedLines(10, map[int]string{3: "three", 8: "eight"}),
+			"@@ -1,10 +1,10 @@\n 1\n 2\n-3\n+three\n 4\n 5\n 6\n 7\n-8\n+eight\n 9\n 10\n",
+		},
+		{
+			"distant changes",
+			numberedLines(20, nil),
+			numberedLines(20, map[int]string{5: "five", 16: "sixteen"}),
+			"@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n" +
+				"@@ -13,7 +13,7 @@\n 13\n 14\n 15\n-16\n+sixteen\n 17\n 18\n 19\n",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			want := test.want
+			if want != "" {
+				want = "--- old\n+++ new\n" + want
+			}
+			if got := UnifiedDiff("old", "new", test.oldText, test.newText); got != want {
+				t.Errorf("UnifiedDiff() =\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package syncer
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/rules"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// FetchResult are the records retrieved from an instance or the error that prevented it.
+type FetchResult struct {
+	Records []output.Record
+	// Origins are the routers of the hosts of Records.
+	Origins map[string][]Origin
+	Err     error
+}
+
+// Origin is a router a host comes from.
+type Origin struct {
+	Router   string `json:"router"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// FetchAll retrieves the records of the instances concurrently, at most Concurrency at once.
+// The results are in the order of the instances so the generated files are stable.
+func (s *Syncer) FetchAll() []FetchResult {
+	results := make([]FetchResult, len(s.Instances))
+	workers := s.Concurrency
+	if workers < 1 || workers > len(s.Instances) {
+		workers = len(s.Instances)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = s.fetchInstance(s.Instances[i])
+			}
+		}()
+	}
+	for i := range s.Instances {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// fetchInstance retrieves the records of instance, giving up after InstanceTimeout.
+func (s *Syncer) fetchInstance(instance *Instance) FetchResult {
+	ctx := context.Background()
+	if s.InstanceTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.InstanceTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	records, origins, err := s.Fetch(ctx, instance)
+	s.Debugf.Printf("Fetched routers from %s in %s", instance.URL, time.Since(start))
+	s.Metrics.ObservePhase("fetch", time.Since(start))
+	return FetchResult{Records: records, Origins: origins, Err: err}
+}
+
+// Fetch returns the records of the hosts of the routers of instance, and the routers of every
+// host.
+func (s *Syncer) Fetch(ctx context.Context, instance *Instance) ([]output.Record, map[string][]Origin, error) {
+	answers, err := s.answers(ctx, instance)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(answers) == 0 {
+		s.Warnf.Printf("Skipping the hosts of %s since none of its IPs is within the allowed CIDRs %s", instance.URL, joinCIDRs(s.AllowedCIDRs))
+		return nil, map[string][]Origin{}, nil
+	}
+
+	allRouters, err := instance.Source.Routers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.Metrics.SetRouters(instance.URL, len(allRouters))
+
+	records := make([]output.Record, 0, len(allRouters))
+	origins := map[string][]Origin{}
+	for _, router := range allRouters {
+		if s.SkipDashboard && router.IsDashboard() {
+			s.Debugf.Printf("Skipping dashboard router with rule %s", router.Rule)
+			continue
+		}
+		if !s.isRouterPublished(router) {
+			continue
+		}
+		result, err := rules.Parse(router.Rule)
+		if err != nil {
+			s.Warnf.Printf("Skipping rule %s. %s", router.Rule, err)
+			continue
+		}
+		hosts := append(result.Hosts, s.hostRegexpsHosts(result.Regexps, router.Rule)...)
+		for _, h := range hosts {
+			host, err := NormalizeHost(h)
+			if err != nil {
+				s.Warnf.Printf("Skipping host %s from rule %s. %s", h, router.Rule, err)
+				continue
+			}
+			if !s.isHostPublished(host) {
+				s.Debugf.Printf("Skipping host %s filtered by -include or -exclude", host)
+				continue
+			}
+			if !InZones(host, s.Zones) {
+				if s.OutsideZones != OutsideZonesInclude {
+					s.Warnf.Printf("Skipping host %s from rule %s outside of the zones %s", host, router.Rule, strings.Join(s.Zones, ", "))
+					continue
+				}
+				s.Warnf.Printf("Publishing host %s from rule %s outside of the zones %s", host, router.Rule, strings.Join(s.Zones, ", "))
+			}
+			origins[host] = addOrigin(origins[host], Origin{Router: router.Name, Provider: router.ProviderName()})
+			for _, answer := range answers {
+				if answer.Type == "CNAME" && answer.Value == host+"." {
+					continue
+				}
+				answer.Host = host
+				records = append(records, answer)
+			}
+		}
+	}
+	return records, origins, nil
+}
+
+// isRouterPublished applies the provider, entry point, status and marker filters to router.
+func (s *Syncer) isRouterPublished(router traefik.Router) bool {
+	provider := router.ProviderName()
+	if len(s.Providers) > 0 && !containsString(s.Providers, provider) {
+		s.Debugf.Printf("Skipping router %s of provider %s filtered by -providers", router.Name, provider)
+		return false
+	}
+	if len(s.EntryPoints) > 0 && !containsAnyString(s.EntryPoints, router.EntryPoints) {
+		s.Debugf.Printf("Skipping router %s of entry points %s filtered by -entrypoints", router.Name, strings.Join(router.EntryPoints, ","))
+		return false
+	}
+	if containsString(s.ExcludeProviders, provider) {
+		s.Debugf.Printf("Skipping router %s of provider %s filtered by -exclude-providers", router.Name, provider)
+		return false
+	}
+	if !s.IncludeDisabled && !router.IsEnabled() {
+		s.Debugf.Printf("Skipping router %s with status %s", router.Name, router.Status)
+		return false
+	}
+	if s.RouterMarker == "" && s.MarkerMiddleware == "" {
+		return true
+	}
+	if s.isRouterMarked(router) != (s.MarkerMode == MarkerOptIn) {
+		s.Debugf.Printf("Skipping router %s filtered by -marker-mode %s", router.Name, s.MarkerMode)
+		return false
+	}
+	return true
+}
+
+// isRouterMarked reports whether the name of router starts or ends with RouterMarker or it
+// uses the MarkerMiddleware.
+func (s *Syncer) isRouterMarked(router traefik.Router) bool {
+	name := router.ShortName()
+	if s.RouterMarker != "" && (strings.HasPrefix(name, s.RouterMarker) || strings.HasSuffix(name, s.RouterMarker)) {
+		return true
+	}
+	return s.MarkerMiddleware != "" && router.HasMiddleware(s.MarkerMiddleware)
+}
+
+// hostRegexpsHosts returns the hosts published for the HostRegexp expressions of rule with the
+// HostRegexp mode.
+func (s *Syncer) hostRegexpsHosts(expressions []string, rule string) []string {
+	hosts := []string{}
+	for _, expression := range expressions {
+		switch s.HostRegexp {
+		case HostRegexpWildcard:
+			domain, ok := rules.WildcardDomain(expression)
+			if !ok {
+				s.Warnf.Printf("Skipping HostRegexp %s of rule %s. Only expressions matching the subdomains of a domain can be published as wildcards", expression, rule)
+				continue
+			}
+			hosts = append(hosts, output.WildcardPrefix+domain)
+		case HostRegexpExpand:
+			re, err := rules.CompileHostRegexp(expression)
+			if err != nil {
+				s.Warnf.Printf("Skipping HostRegexp %s of rule %s. %s", expression, rule, err)
+				continue
+			}
+			for _, candidate := range s.RegexpHosts {
+				if re.MatchString(candidate) {
+					hosts = append(hosts, candidate)
+				}
+			}
+		default:
+			s.Warnf.Printf("Skipping HostRegexp %s of rule %s. Use -host-regexp to publish it", expression, rule)
+		}
+	}
+	return hosts
+}
+
+// isHostPublished applies the Include and Exclude filters to host.
+func (s *Syncer) isHostPublished(host string) bool {
+	if len(s.Include) > 0 && !matchesAny(s.Include, host) {
+		return false
+	}
+	return !matchesAny(s.Exclude, host)
+}
+
+// InZones reports whether host is one of zones or below one of them, or true without zones.
+// The wildcard hosts are in the zones of their domain.
+func InZones(host string, zones []string) bool {
+	if len(zones) == 0 {
+		return true
+	}
+	host = strings.TrimPrefix(host, output.WildcardPrefix)
+	for _, zone := range zones {
+		zone = strings.ToLower(strings.Trim(zone, "."))
+		if host == zone || strings.HasSuffix(host, "."+zone) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package syncer
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// staticSource returns the same routers on every sync.
+type staticSource []traefik.Router
+
+func (s staticSource) Routers(ctx context.Context) ([]traefik.Router, error) {
+	return s, nil
+}
+
+func newTestSyncer(t *testing.T, options Options) *Syncer {
+	t.Helper()
+	s, err := New(options)
+	if err != nil {
+		t.Fatalf("New() failed. %s", err)
+	}
+	return s
+}
+
+func TestOverlappingRoutersWriteOneRecord(t *testing.T) {
+	instance := &Instance{
+		URL: "http://traefik.lan",
+		IP:  net.ParseIP("192.168.1.10"),
+		Source: staticSource{
+			{Name: "whoami@docker", Rule: "Host(`whoami.example.com`)", Status: traefik.StatusEnabled},
+			{Name: "whoami-secure@docker", Rule: "Host(`whoami.example.com`) && PathPrefix(`/`)", Status: traefik.StatusEnabled},
+			{Name: "whoami-tcp@docker", Rule: "HostSNI(`whoami.example.com`)", Status: traefik.StatusEnabled},
+		},
+	}
+	s := newTestSyncer(t, Options{Instances: []*Instance{instance}})
+
+	records, origins, err := s.Fetch(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("Fetch() failed. %s", err)
+	}
+	if len(origins["whoami.example.com"]) != 3 {
+		t.Errorf("got the origins %v, want the 3 routers", origins["whoami.example.com"])
+	}
+	unique := output.UniqueRecords(records)
+	want := output.Record{Host: "whoami.example.com", Type: "A", Value: "192.168.1.10"}
+	if len(unique) != 1 || unique[0] != want {
+		t.Errorf("UniqueRecords() = %v, want %v", unique, want)
+	}
+
+	builder := strings.Builder{}
+	output.Unbound{}.Write(&builder, records)
+	if lines := strings.Count(builder.String(), "local-data:"); lines != 1 {
+		t.Errorf("got %d local-data lines, want 1:\n%s", lines, builder.String())
+	}
+}
+
+func TestFetchFilters(t *testing.T) {
+	routers := staticSource{
+		{Name: "a@docker", Rule: "Host(`a.example.com`)", Status: traefik.StatusEnabled},
+		{Name: "b@file", Rule: "Host(`b.example.com`)", Status: traefik.StatusEnabled},
+		{Name: "c@docker", Rule: "Host(`c.example.org`)", Status: traefik.StatusEnabled},
+		{Name: "d@docker", Rule: "Host(`d.example.com`)", Status: "disabled"},
+		{Name: "e-public@docker", Rule: "Host(`e.example.com`)", Status: traefik.StatusEnabled},
+		{Name: "f@docker", Rule: "HostRegexp(`^.+\\.apps\\.example\\.com$`)", Status: traefik.StatusEnabled},
+	}
+	tests := []struct {
+		name    string
+		options Options
+		want    []string
+	}{
+		{"all", Options{}, []string{"a.example.com", "b.example.com", "c.example.org", "e.example.com"}},
+		{"include", Options{Include: []*regexp.Regexp{regexp.MustCompile(`^[ab]\.`)}}, []string{"a.example.com", "b.example.com"}},
+		{"exclude", Options{Exclude: []*regexp.Regexp{regexp.MustCompile(`^a\.`)}}, []string{"b.example.com", "c.example.org", "e.example.com"}},
+		{"zones", Options{Zones: []string{"example.com."}}, []string{"a.example.com", "b.example.com", "e.example.com"}},
+		{"outside zones", Options{Zones: []string{"example.com"}, OutsideZones: OutsideZonesInclude}, []string{"a.example.com", "b.example.com", "c.example.org", "e.example.com"}},
+		{"providers", Options{Providers: []string{"file"}}, []string{"b.example.com"}},
+		{"exclude providers", Options{ExcludeProviders: []string{"docker"}}, []string{"b.example.com"}},
+		{"disabled", Options{IncludeDisabled: true}, []string{"a.example.com", "b.example.com", "c.example.org", "d.example.com", "e.example.com"}},
+		{"opt-out marker", Options{RouterMarker: "-public"}, []string{"a.example.com", "b.example.com", "c.example.org"}},
+		{"opt-in marker", Options{RouterMarker: "-public", MarkerMode: MarkerOptIn}, []string{"e.example.com"}},
+		{"wildcard", Options{HostRegexp: HostRegexpWildcard, Include: []*regexp.Regexp{regexp.MustCompile(`apps`)}}, []string{"*.apps.example.com"}},
+		{"expand", Options{HostRegexp: HostRegexpExpand, RegexpHosts: []string{"x.apps.example.com", "x.example.com"}, Include: []*regexp.Regexp{regexp.MustCompile(`apps`)}}, []string{"x.apps.example.com"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			instance := &Instance{URL: "http://traefik.lan", IP: net.ParseIP("192.168.1.10").To4(), Source: routers}
+			test.options.Instances = []*Instance{instance}
+			s := newTestSyncer(t, test.options)
+
+			records, _, err := s.Fetch(context.Background(), instance)
+			if err != nil {
+				t.Fatalf("Fetch() failed. %s", err)
+			}
+			hosts := []string{}
+			for _, r := range records {
+				hosts = append(hosts, r.Host)
+			}
+			if strings.Join(hosts, ",") != strings.Join(test.want, ",") {
+				t.Errorf("Fetch() hosts = %v, want %v", hosts, test.want)
+			}
+		})
+	}
+}
+
+func TestInZones(t *testing.T) {
+	tests := []struct {
+		host  string
+		zones []string
+		want  bool
+	}{
+		{"a.example.com", nil, true},
+		{"a.example.com", []string{"example.com"}, true},
+		{"example.com", []string{"example.com."}, true},
+		{"a.example.com", []string{"Example.COM"}, true},
+		{"*.example.com", []string{"example.com"}, true},
+		{"a.notexample.com", []string{"example.com"}, false},
+		{"a.example.org", []string{"example.com", "lan"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			if got := InZones(test.host, test.zones); got != test.want {
+				t.Errorf("InZones(%s, %v) = %t, want %t", test.host, test.zones, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package syncer
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// BackupSuffix is the suffix of the backup of a file, restored when a change fails, and of its
+// timestamped generations.
+const BackupSuffix = ".bak"
+
+// backupTimeFormat sorts lexically in chronological order.
+const backupTimeFormat = "20060102T150405.000000000"
+
+// Backup copies path to path.bak, used to restore it when a change fails, and to a
+// timestamped generation, removing the generations beyond Backups.
+func (s *Syncer) Backup(path string) error {
+	err := copyFile(path, path+BackupSuffix)
+	if err != nil {
+		return err
+	}
+
+	if s.Backups <= 0 {
+		return nil
+	}
+	err = copyFile(path, path+BackupSuffix+"."+time.Now().Format(backupTimeFormat))
+	if err != nil {
+		return err
+	}
+	generations, err := BackupGenerations(path)
+	if err != nil {
+		s.Errorf.Printf("Error listing the backups of %s. %s", path, err)
+		return nil
+	}
+	if len(generations) <= s.Backups {
+		return nil
+	}
+	for _, generation := range generations[s.Backups:] {
+		err := os.Remove(generation)
+		if err != nil {
+			s.Errorf.Printf("Error removing old backup %s. %s", generation, err)
+		}
+	}
+	return nil
+}
+
+// Restore replaces path with its backup atomically.
+func Restore(path string) error {
+	contents, err := os.ReadFile(path + BackupSuffix)
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, string(contents))
+}
+
+// BackupGenerations returns the timestamped backups of path, newest first.
+func BackupGenerations(path string) ([]string, error) {
+	generations, err := filepath.Glob(path + BackupSuffix + ".*")
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(generations)))
+	return generations, nil
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, in)
+	if err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// WriteFile replaces the file atomically. The contents are written to a temporary file in the
+// same directory, synced to disk and renamed over path, so a reader never sees a half written
+// file.
+func WriteFile(path string, contents string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file for %s. %s", path, err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	_, err = file.WriteString(contents)
+	if err != nil {
+		return fmt.Errorf("error writing contents to file %s. %s", file.Name(), err)
+	}
+	err = file.Chmod(0644)
+	if err != nil {
+		return fmt.Errorf("error changing permissions to file %s. %s", file.Name(), err)
+	}
+	err = file.Sync()
+	if err != nil {
+		return fmt.Errorf("error syncing file %s. %s", file.Name(), err)
+	}
+	err = file.Close()
+	if err != nil {
+		return fmt.Errorf("error closing file %s. %s", file.Name(), err)
+	}
+
+	err = os.Rename(file.Name(), path)
+	if err != nil {
+		return fmt.Errorf("error renaming %s to %s. %s", file.Name(), path, err)
+	}
+	syncDir(filepath.Dir(path))
+	return nil
+}
+
+// syncDir flushes the directory entry of a renamed file. It is best effort since not every
+// platform supports syncing directories.
+func syncDir(path string) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer dir.Close()
+	dir.Sync()
+}
+
+func createFileIfNotExists(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("error creating file %s. %s", path, err)
+		}
+		defer file.Close()
+
+		err = os.Chmod(path, 0644)
+		if err != nil {
+			return fmt.Errorf("error changing permissions to file %s. %s", path, err)
+		}
+	}
+	return nil
+}
+
+// fileHash returns the SHA256 of the file at path in hex.
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("error opening file %s. %s", path, err)
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("error copying file contents of %s to calculate SHA256. %s", path, err)
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
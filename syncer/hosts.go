@@ -0,0 +1,98 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"golang.org/x/net/idna"
+)
+
+// NormalizeHost lowercases host, drops its trailing dot and converts internationalized
+// hostnames to their punycode (ACE) form so unbound receives names it can serve. Hosts that
+// aren't valid RFC 1123 hostnames once converted, or with punycode labels that don't decode
+// to a valid internationalized label, are rejected. The wildcard hosts keep their leading *.
+func NormalizeHost(host string) (string, error) {
+	if strings.HasPrefix(host, output.WildcardPrefix) {
+		domain, err := NormalizeHost(strings.TrimPrefix(host, output.WildcardPrefix))
+		return output.WildcardPrefix + domain, err
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if needsIDNA(host) {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", err
+		}
+		// An ASCII host is left as is by a valid conversion, which would decode labels like
+		// xn--abc- to abc
+		if isASCII(host) && ascii != host {
+			return "", fmt.Errorf("invalid punycode label in hostname %s", host)
+		}
+		host = ascii
+	}
+	return host, ValidateHostname(host)
+}
+
+// needsIDNA reports whether host has non-ASCII characters or punycode labels, which the
+// conversion to punycode checks.
+func needsIDNA(host string) bool {
+	if !isASCII(host) {
+		return true
+	}
+	for _, label := range strings.Split(host, ".") {
+		if strings.HasPrefix(label, "xn--") {
+			return true
+		}
+	}
+	return false
+}
+
+func isASCII(s string) bool {
+	for _, r := range s {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
+
+// ValidateHostname checks that host is made of labels of lowercase letters, digits and
+// hyphens, of at most 63 characters, which don't start or end with a hyphen.
+func ValidateHostname(host string) error {
+	if host == "" {
+		return errors.New("empty hostname")
+	}
+	if len(host) > 253 {
+		return fmt.Errorf("hostname longer than 253 characters")
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" {
+			return fmt.Errorf("empty label in hostname %s", host)
+		}
+		if len(label) > 63 {
+			return fmt.Errorf("label %s longer than 63 characters", label)
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("label %s starts or ends with a hyphen", label)
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return fmt.Errorf("invalid character %q in label %s", r, label)
+			}
+		}
+	}
+	return nil
+}
+
+// matchesAny reports whether s matches any of the regular expressions.
+func matchesAny(expressions []*regexp.Regexp, s string) bool {
+	for _, re := range expressions {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
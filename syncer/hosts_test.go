@@ -0,0 +1,81 @@
+package syncer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"a.example.com", "a.example.com"},
+		{"A.Example.COM.", "a.example.com"},
+		{"münchen.example.com", "xn--mnchen-3ya.example.com"},
+		{"München.Example.com.", "xn--mnchen-3ya.example.com"},
+		{"xn--mnchen-3ya.example.com", "xn--mnchen-3ya.example.com"},
+		{"日本.jp", "xn--wgv71a.jp"},
+		{"*.münchen.example.com", "*.xn--mnchen-3ya.example.com"},
+		{"*.example.com", "*.example.com"},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			got, err := NormalizeHost(test.host)
+			if err != nil {
+				t.Fatalf("NormalizeHost(%s) failed. %s", test.host, err)
+			}
+			if got != test.want {
+				t.Errorf("NormalizeHost(%s) = %s, want %s", test.host, got, test.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHostInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"mün chen.example.com",
+		"\u200d.example.com",
+		"münchen..example.com",
+		"xn--a.example.com",
+		"xn--abc-.example.com",
+		"xn--.example.com",
+		"a_b.example.com",
+		"-a.example.com",
+		"*.xn--a.example.com",
+		"a.*.example.com",
+	}
+	for _, host := range tests {
+		t.Run(host, func(t *testing.T) {
+			got, err := NormalizeHost(host)
+			if err == nil {
+				t.Errorf("NormalizeHost(%q) = %s, want an error", host, got)
+			}
+		})
+	}
+}
+
+func TestValidateHostname(t *testing.T) {
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{"a.example.com", true},
+		{"a-1.example.com", true},
+		{"xn--mnchen-3ya.example.com", true},
+		{"A.example.com", false},
+		{"a..example.com", false},
+		{"a-.example.com", false},
+		{strings.Repeat("a", 64) + ".example.com", false},
+		{strings.Repeat("a.", 127) + "com", false},
+	}
+	for _, test := range tests {
+		t.Run(test.host, func(t *testing.T) {
+			err := ValidateHostname(test.host)
+			if (err == nil) != test.valid {
+				t.Errorf("ValidateHostname(%s) = %v, want valid %t", test.host, err, test.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// answers returns the records, without host, every host of the instance is published with.
+// These are the Traefik IPs inside AllowedCIDRs or, with CNAME, its hostname.
+func (s *Syncer) answers(ctx context.Context, instance *Instance) ([]output.Record, error) {
+	if s.CNAME && instance.Interface == "" {
+		u, err := url.Parse(instance.URL)
+		if err != nil {
+			return nil, err
+		}
+		if u.Hostname() == "" {
+			s.Warnf.Printf("Publishing A records for %s since it has no hostname for CNAMEs to point to", instance.URL)
+		} else if net.ParseIP(u.Hostname()) == nil {
+			return []output.Record{{Type: "CNAME", Value: u.Hostname() + "."}}, nil
+		} else {
+			s.Warnf.Printf("Publishing A records for %s since CNAMEs can't point to an IP", instance.URL)
+		}
+	}
+
+	ips := []net.IP{instance.IP}
+	if instance.IP == nil {
+		var err error
+		ips, err = s.InstanceIPs(ctx, instance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ips = s.allowedIPs(ips, instance.URL)
+	answers := make([]output.Record, 0, len(ips))
+	for _, ip := range ips {
+		answers = append(answers, output.Record{Type: RecordType(ip), Value: ip.String()})
+	}
+	return answers, nil
+}
+
+// allowedIPs returns the IPs of ips inside AllowedCIDRs, warning about the other ones of
+// name.
+func (s *Syncer) allowedIPs(ips []net.IP, name string) []net.IP {
+	allowed := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		if !allowedIP(s.AllowedCIDRs, ip) {
+			s.Warnf.Printf("Skipping IP %s of %s since it is not within the allowed CIDRs %s", ip, name, joinCIDRs(s.AllowedCIDRs))
+			continue
+		}
+		allowed = append(allowed, ip)
+	}
+	return allowed
+}
+
+// allowedIP reports whether ip is inside any of the CIDRs. No CIDRs allow every IP.
+func allowedIP(cidrs []*net.IPNet, ip net.IP) bool {
+	if len(cidrs) == 0 {
+		return true
+	}
+	for _, ipNet := range cidrs {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func joinCIDRs(cidrs []*net.IPNet) string {
+	values := make([]string, 0, len(cidrs))
+	for _, ipNet := range cidrs {
+		values = append(values, ipNet.String())
+	}
+	return strings.Join(values, ",")
+}
+
+// InstanceIPs returns the IPs published for the hosts of instance without IP, the ones of its
+// interface or of the host of its URL.
+func (s *Syncer) InstanceIPs(ctx context.Context, instance *Instance) ([]net.IP, error) {
+	if instance.Interface != "" {
+		return s.interfaceIPs(instance.Interface)
+	}
+	return s.hostIPs(ctx, instance.URL)
+}
+
+// hostIPs resolves the host of the Traefik URL, without its port, and returns its IPs
+// selected with SelectIPs. A host that is already an IP, like in "http://192.168.1.5:8080" or
+// "http://[fd00::1]:8080", is used as is.
+func (s *Syncer) hostIPs(ctx context.Context, rawURL string) ([]net.IP, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Hostname()
+	if host == "" {
+		return nil, fmt.Errorf("no host in %s to resolve", rawURL)
+	}
+
+	var ips []net.IP
+	// The zone of a link-local IPv6 like fe80::1%eth0 isn't part of the published IP
+	if ip := net.ParseIP(strings.SplitN(host, "%", 2)[0]); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		resolver := net.DefaultResolver
+		if s.Resolver != nil {
+			resolver = s.Resolver
+		}
+		addrs, err := resolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving host %s. %w", host, err)
+		}
+		ips = make([]net.IP, 0, len(addrs))
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no IPs found for host %s", host)
+	}
+	return s.SelectIPs(ips, "host "+host)
+}
+
+// interfaceIPs returns the IPs of the network interface name, without the link-local ones,
+// selected like the ones of a host.
+func (s *Syncer) interfaceIPs(name string) ([]net.IP, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the IPs of interface %s. %s", name, err)
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, fmt.Errorf("error reading the IPs of interface %s. %s", name, err)
+	}
+	ips := []net.IP{}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		ips = append(ips, ipNet.IP)
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no IPs found for interface %s", name)
+	}
+	return s.SelectIPs(ips, "interface "+name)
+}
+
+// SelectIPs returns the IPs of ips of the families of IPFamily, selected with IPSelection.
+// name is what they belong to, for the errors.
+func (s *Syncer) SelectIPs(ips []net.IP, name string) ([]net.IP, error) {
+	var ipv4, ipv6 []net.IP
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			ipv4 = append(ipv4, ip.To4())
+		} else {
+			ipv6 = append(ipv6, ip)
+		}
+	}
+
+	selected := []net.IP{}
+	if s.IPFamily != IPFamilyV6 {
+		selected = append(selected, s.selectIPs(ipv4)...)
+	}
+	if s.IPFamily != IPFamilyV4 {
+		selected = append(selected, s.selectIPs(ipv6)...)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no %s IPs found for %s", s.IPFamily, name)
+	}
+	return selected, nil
+}
+
+// selectIPs returns the IPs of a family published with IPSelection: the first one, all of
+// them sorted so the file is stable, or the first private or IPSelection subnet one, falling
+// back to the first one.
+func (s *Syncer) selectIPs(ips []net.IP) []net.IP {
+	if len(ips) == 0 {
+		return nil
+	}
+	switch s.IPSelection {
+	case IPSelectionAll:
+		sorted := append([]net.IP{}, ips...)
+		sort.Slice(sorted, func(i, j int) bool {
+			return bytes.Compare(sorted[i], sorted[j]) < 0
+		})
+		return sorted
+	case IPSelectionFirst:
+		return ips[:1]
+	}
+	for _, ip := range ips {
+		if s.IPSelection == IPSelectionPrivate && ip.IsPrivate() || s.ipSubnet != nil && s.ipSubnet.Contains(ip) {
+			return []net.IP{ip}
+		}
+	}
+	return ips[:1]
+}
+
+// RecordType returns the DNS record type for ip, A for IPv4 and AAAA for IPv6.
+func RecordType(ip net.IP) string {
+	if ip.To4() != nil {
+		return "A"
+	}
+	return "AAAA"
+}
+
+// mapRecordSets returns the records of recordSets pointing to the IP of t or to the IPs its
+// IPMap maps their IPs to.
+func (t *Target) mapRecordSets(recordSets [][]output.Record) [][]output.Record {
+	if t.IP == nil && len(t.IPMap) == 0 {
+		return recordSets
+	}
+	sets := make([][]output.Record, 0, len(recordSets))
+	for _, records := range recordSets {
+		set := make([]output.Record, 0, len(records))
+		seen := map[string]bool{}
+		for _, record := range records {
+			if t.IP != nil {
+				if !seen[record.Host] {
+					set = append(set, output.Record{Host: record.Host, Type: RecordType(t.IP), Value: t.IP.String()})
+				}
+				seen[record.Host] = true
+				continue
+			}
+			if to, ok := t.IPMap[record.Value]; ok && (record.Type == "A" || record.Type == "AAAA") {
+				record = output.Record{Host: record.Host, Type: RecordType(to), Value: to.String()}
+			}
+			set = append(set, record)
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
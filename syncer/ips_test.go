@@ -0,0 +1,142 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+func TestSelectIPs(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("203.0.113.5"),
+		net.ParseIP("192.168.1.10"),
+		net.ParseIP("10.0.0.2"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("fd00::1"),
+	}
+	tests := []struct {
+		family    string
+		selection string
+		want      string
+	}{
+		{IPFamilyV4, IPSelectionFirst, "203.0.113.5"},
+		{IPFamilyV4, IPSelectionAll, "10.0.0.2, 192.168.1.10, 203.0.113.5"},
+		{IPFamilyV4, IPSelectionPrivate, "192.168.1.10"},
+		{IPFamilyV4, "10.0.0.0/8", "10.0.0.2"},
+		{IPFamilyV4, "172.16.0.0/12", "203.0.113.5"},
+		{IPFamilyV6, IPSelectionFirst, "2001:db8::1"},
+		{IPFamilyV6, IPSelectionPrivate, "fd00::1"},
+		{IPFamilyBoth, IPSelectionPrivate, "192.168.1.10, fd00::1"},
+	}
+	for _, test := range tests {
+		t.Run(test.family+" "+test.selection, func(t *testing.T) {
+			s := newTestSyncer(t, Options{IPFamily: test.family, IPSelection: test.selection})
+			got, err := s.SelectIPs(ips, "test")
+			if err != nil {
+				t.Fatalf("SelectIPs() failed. %s", err)
+			}
+			if JoinIPs(got) != test.want {
+				t.Errorf("SelectIPs() = %s, want %s", JoinIPs(got), test.want)
+			}
+		})
+	}
+}
+
+func TestSelectIPsWithoutFamily(t *testing.T) {
+	s := newTestSyncer(t, Options{IPFamily: IPFamilyV6})
+	got, err := s.SelectIPs([]net.IP{net.ParseIP("192.168.1.10")}, "host traefik.lan")
+	if err == nil {
+		t.Errorf("SelectIPs() = %s, want an error", JoinIPs(got))
+	}
+}
+
+func TestRecordType(t *testing.T) {
+	if got := RecordType(net.ParseIP("192.168.1.10")); got != "A" {
+		t.Errorf("RecordType(192.168.1.10) = %s, want A", got)
+	}
+	if got := RecordType(net.ParseIP("fd00::1")); got != "AAAA" {
+		t.Errorf("RecordType(fd00::1) = %s, want AAAA", got)
+	}
+}
+
+func TestAllowedIPs(t *testing.T) {
+	cidrs := []*net.IPNet{}
+	for _, cidr := range []string{"10.0.0.0/8", "192.168.0.0/16", "fd00::/8"} {
+		_, ipNet, _ := net.ParseCIDR(cidr)
+		cidrs = append(cidrs, ipNet)
+	}
+	tests := []struct {
+		name         string
+		cidrs        []*net.IPNet
+		ips          string
+		want         string
+		wantWarnings int
+	}{
+		{"no CIDRs", nil, "203.0.113.5, 10.0.0.2", "203.0.113.5, 10.0.0.2", 0},
+		{"all allowed", cidrs, "192.168.1.10, 10.0.0.2, fd00::1", "192.168.1.10, 10.0.0.2, fd00::1", 0},
+		{"public IP dropped", cidrs, "203.0.113.5, 192.168.1.10, 2001:db8::1", "192.168.1.10", 2},
+		{"none allowed", cidrs, "203.0.113.5", "no IPs", 1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warnings := 0
+			s := newTestSyncer(t, Options{AllowedCIDRs: test.cidrs, Warnf: func(format string, args ...interface{}) { warnings++ }})
+			ips := []net.IP{}
+			for _, ip := range strings.Split(test.ips, ", ") {
+				ips = append(ips, net.ParseIP(ip))
+			}
+			got := s.allowedIPs(ips, "http://traefik.lan")
+			if JoinIPs(got) != test.want || warnings != test.wantWarnings {
+				t.Errorf("allowedIPs() = %s with %d warnings, want %s with %d", JoinIPs(got), warnings, test.want, test.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestFetchSkipsInstanceWithoutAllowedIPs(t *testing.T) {
+	_, private, _ := net.ParseCIDR("192.168.0.0/16")
+	tests := []struct {
+		ip          string
+		wantRecords int
+	}{
+		{"192.168.1.10", 1},
+		{"203.0.113.5", 0},
+	}
+	for _, test := range tests {
+		t.Run(test.ip, func(t *testing.T) {
+			instance := &Instance{
+				URL:    "http://traefik.lan",
+				IP:     net.ParseIP(test.ip).To4(),
+				Source: staticSource{{Name: "a@docker", Rule: "Host(`a.example.com`)", Status: traefik.StatusEnabled}},
+			}
+			s := newTestSyncer(t, Options{Instances: []*Instance{instance}, AllowedCIDRs: []*net.IPNet{private}})
+			records, _, err := s.Fetch(context.Background(), instance)
+			if err != nil {
+				t.Fatalf("Fetch() failed. %s", err)
+			}
+			if len(records) != test.wantRecords {
+				t.Errorf("Fetch() = %v, want %d records", records, test.wantRecords)
+			}
+		})
+	}
+}
+
+func TestHostIPsLookupError(t *testing.T) {
+	resolver := &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return nil, errors.New("unreachable DNS server")
+		},
+	}
+	s := newTestSyncer(t, Options{Resolver: resolver})
+
+	_, err := s.hostIPs(context.Background(), "http://traefik.example.com:8080")
+	var dnsErr *net.DNSError
+	if !errors.As(err, &dnsErr) || !strings.Contains(err.Error(), "traefik.example.com") {
+		t.Errorf("hostIPs() = %v, want the error of the lookup of traefik.example.com", err)
+	}
+}
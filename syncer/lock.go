@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// lockRetryDelay is the time between the attempts to take a lock held by another run, with
+// LockWait.
+const lockRetryDelay = 200 * time.Millisecond
+
+// errLocked is returned when another run holds a lock for longer than LockWait.
+var errLocked = errors.New("locked by another run")
+
+// lockPaths returns the lock files of the sync, LockFile or one next to every file, sorted so
+// runs with several files take them in the same order.
+func (s *Syncer) lockPaths() []string {
+	if s.LockFile == "none" {
+		return nil
+	}
+	if s.LockFile != "" {
+		return []string{s.LockFile}
+	}
+	paths := []string{}
+	seen := map[string]bool{}
+	for _, t := range s.Targets {
+		path := t.Path + ".lock"
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// lockTargets takes an advisory lock on the lock files of the sync, so a cron job running
+// while the previous one still waits for a slow Traefik doesn't race on the files and their
+// backups. It returns the function releasing them.
+func (s *Syncer) lockTargets() (func(), error) {
+	files := []*os.File{}
+	unlock := func() {
+		for _, f := range files {
+			unlockFile(f)
+			f.Close()
+		}
+	}
+	for _, path := range s.lockPaths() {
+		f, err := s.lockFile(path, s.LockWait)
+		if err != nil {
+			unlock()
+			if errors.Is(err, errLocked) {
+				return nil, Error{Locked, fmt.Errorf("error locking %s. %s", path, err)}
+			}
+			return nil, fmt.Errorf("error locking %s. %s", path, err)
+		}
+		files = append(files, f)
+	}
+	return unlock, nil
+}
+
+// lockFile takes an exclusive flock on path, waiting up to wait for the run holding it, and
+// writes the PID of the process to it.
+func (s *Syncer) lockFile(path string, wait time.Duration) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(wait)
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if locked {
+			break
+		}
+		if !time.Now().Before(deadline) {
+			holder, _ := os.ReadFile(path)
+			f.Close()
+			if pid := strings.TrimSpace(string(holder)); pid != "" {
+				return nil, fmt.Errorf("%w with PID %s", errLocked, pid)
+			}
+			return nil, errLocked
+		}
+		time.Sleep(lockRetryDelay)
+	}
+	// The lock files are never removed, another run may be waiting on them
+	err = f.Truncate(0)
+	if err == nil {
+		_, err = f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	if err != nil {
+		s.Debugf.Printf("Error writing the PID to %s. %s", path, err)
+	}
+	return f, nil
+}
@@ -0,0 +1,129 @@
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// probeRetryDelay is the time between the queries of a host that doesn't resolve yet, while
+// the DNS server starts.
+const probeRetryDelay = 500 * time.Millisecond
+
+// probe queries the Probe DNS server of t for a sample of the A and AAAA records after they
+// are applied, and fails if any of them doesn't resolve to its IP, like when the file isn't
+// included by the configuration of the server.
+func (s *Syncer) probe(t *Target, records []output.Record) error {
+	address := t.Probe
+	if address == "" {
+		return nil
+	}
+	samples := probeSamples(records, t.Zone, s.ProbeSamples)
+	if len(samples) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		s.Debugf.Printf("Probed %d hosts of %s on %s in %s", len(samples), t.Path, address, time.Since(start))
+		s.Metrics.ObservePhase("probe", time.Since(start))
+	}()
+	resolver := NewResolver(address)
+	ctx := context.Background()
+	if s.ProbeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.ProbeTimeout)
+		defer cancel()
+	}
+	for _, record := range samples {
+		err := probeRecord(ctx, resolver, record)
+		if err != nil {
+			return fmt.Errorf("error probing %s on %s. %s", t.Path, address, err)
+		}
+	}
+	return nil
+}
+
+// NewResolver returns a resolver that queries the DNS server at address, on port 53 unless it
+// has one.
+func NewResolver(address string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "53")
+	}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, address)
+		},
+	}
+}
+
+// probeRecord queries the host of record until it resolves to its IP or ctx is done.
+func probeRecord(ctx context.Context, resolver *net.Resolver, record output.Record) error {
+	network := "ip4"
+	if record.Type == "AAAA" {
+		network = "ip6"
+	}
+	expected := net.ParseIP(record.Value)
+	for {
+		ips, err := resolver.LookupIP(ctx, network, record.Host)
+		if err == nil {
+			for _, ip := range ips {
+				if ip.Equal(expected) {
+					return nil
+				}
+			}
+			err = fmt.Errorf("got %s", JoinIPs(ips))
+		}
+		// The error names the server of resolv.conf instead of the probed one
+		if dnsErr, ok := err.(*net.DNSError); ok {
+			err = errors.New(dnsErr.Err)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%s doesn't resolve to %s. %s", record.Host, record.Value, err)
+		case <-time.After(probeRetryDelay):
+		}
+	}
+}
+
+// probeSamples returns at most size A and AAAA records of records in zone, when set, spread
+// evenly over them.
+func probeSamples(records []output.Record, zone string, size int) []output.Record {
+	zone = strings.TrimSuffix(zone, ".")
+	candidates := []output.Record{}
+	for _, record := range output.UniqueRecords(records) {
+		if record.Type != "A" && record.Type != "AAAA" || strings.HasPrefix(record.Host, "*.") {
+			continue
+		}
+		if zone != "" && record.Host != zone && !strings.HasSuffix(record.Host, "."+zone) {
+			continue
+		}
+		candidates = append(candidates, record)
+	}
+	if size <= 0 || len(candidates) <= size {
+		return candidates
+	}
+	samples := make([]output.Record, 0, size)
+	for i := 0; i < size; i++ {
+		samples = append(samples, candidates[i*len(candidates)/size])
+	}
+	return samples
+}
+
+// JoinIPs returns ips separated by commas, or "no IPs".
+func JoinIPs(ips []net.IP) string {
+	values := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		values = append(values, ip.String())
+	}
+	if len(values) == 0 {
+		return "no IPs"
+	}
+	return strings.Join(values, ", ")
+}
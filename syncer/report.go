@@ -0,0 +1,158 @@
+package syncer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// ChangeReport lists the records a sync added, removed and repointed to other values, by
+// instance, host and type.
+type ChangeReport struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Files     []string       `json:"files"`
+	Added     []RecordChange `json:"added"`
+	Removed   []RecordChange `json:"removed"`
+	Repointed []RecordChange `json:"repointed"`
+}
+
+// RecordChange are the values of the records of a host and type before and after a sync.
+type RecordChange struct {
+	Instance string   `json:"instance"`
+	Host     string   `json:"host"`
+	Type     string   `json:"type"`
+	Old      []string `json:"old,omitempty"`
+	New      []string `json:"new,omitempty"`
+}
+
+type recordKey struct {
+	host       string
+	recordType string
+}
+
+// newChangeReport compares the records of every instance in previous and current.
+func newChangeReport(previous []SourceState, current []SourceState) ChangeReport {
+	before := map[string]map[recordKey][]string{}
+	after := map[string]map[recordKey][]string{}
+	urls := []string{}
+	seen := map[string]bool{}
+	for _, source := range append(append([]SourceState{}, previous...), current...) {
+		if !seen[source.URL] {
+			seen[source.URL] = true
+			urls = append(urls, source.URL)
+		}
+	}
+	for _, source := range previous {
+		before[source.URL] = recordValues(source.Records)
+	}
+	for _, source := range current {
+		after[source.URL] = recordValues(source.Records)
+	}
+
+	report := ChangeReport{Timestamp: time.Now(), Added: []RecordChange{}, Removed: []RecordChange{}, Repointed: []RecordChange{}}
+	for _, url := range urls {
+		keys := []recordKey{}
+		for key := range before[url] {
+			keys = append(keys, key)
+		}
+		for key := range after[url] {
+			if _, ok := before[url][key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			if keys[i].host != keys[j].host {
+				return keys[i].host < keys[j].host
+			}
+			return keys[i].recordType < keys[j].recordType
+		})
+		for _, key := range keys {
+			change := RecordChange{Instance: url, Host: key.host, Type: key.recordType, Old: before[url][key], New: after[url][key]}
+			switch {
+			case change.Old == nil:
+				report.Added = append(report.Added, change)
+			case change.New == nil:
+				report.Removed = append(report.Removed, change)
+			case strings.Join(change.Old, ",") != strings.Join(change.New, ","):
+				report.Repointed = append(report.Repointed, change)
+			}
+		}
+	}
+	return report
+}
+
+// recordValues returns the sorted values of the records of every host and type.
+func recordValues(records []output.Record) map[recordKey][]string {
+	values := map[recordKey][]string{}
+	for _, r := range output.UniqueRecords(records) {
+		key := recordKey{host: r.Host, recordType: r.Type}
+		values[key] = append(values[key], r.Value)
+	}
+	for _, v := range values {
+		sort.Strings(v)
+	}
+	return values
+}
+
+// Empty reports whether no record changed.
+func (r ChangeReport) Empty() bool {
+	return len(r.Added) == 0 && len(r.Removed) == 0 && len(r.Repointed) == 0
+}
+
+// checkRemovals fails when report removes more of the records of previous than MaxRemoved
+// or MaxRemovedPercent, like when Traefik returns no routers while it restarts.
+func (s *Syncer) checkRemovals(previous []SourceState, report ChangeReport) error {
+	total := 0
+	for _, source := range previous {
+		total += len(output.UniqueRecords(source.Records))
+	}
+	removed := 0
+	for _, change := range report.Removed {
+		removed += len(change.Old)
+	}
+	if removed == 0 {
+		return nil
+	}
+	if s.MaxRemoved > 0 && removed > s.MaxRemoved {
+		return fmt.Errorf("refusing to remove %d of %d records, more than -max-removed %d. Use -force to apply it", removed, total, s.MaxRemoved)
+	}
+	if s.MaxRemovedPercent > 0 && removed*100 > total*s.MaxRemovedPercent {
+		return fmt.Errorf("refusing to remove %d of %d records, more than -max-removed-percent %d%%. Use -force to apply it", removed, total, s.MaxRemovedPercent)
+	}
+	return nil
+}
+
+// logReport logs the number of changes of report and every change in debug.
+func (s *Syncer) logReport(r ChangeReport) {
+	if r.Empty() {
+		return
+	}
+	for _, change := range r.Added {
+		s.Debugf.Printf("Added %s %s %s from %s", change.Host, change.Type, strings.Join(change.New, ", "), change.Instance)
+	}
+	for _, change := range r.Removed {
+		s.Debugf.Printf("Removed %s %s %s from %s", change.Host, change.Type, strings.Join(change.Old, ", "), change.Instance)
+	}
+	for _, change := range r.Repointed {
+		s.Debugf.Printf("Repointed %s %s from %s to %s from %s", change.Host, change.Type, strings.Join(change.Old, ", "), strings.Join(change.New, ", "), change.Instance)
+	}
+	s.Infof.Printf("Records changed since the last sync: %d added, %d removed, %d repointed", len(r.Added), len(r.Removed), len(r.Repointed))
+}
+
+// writeChangeReport saves the report to path. It is best effort, failures are only logged.
+func (s *Syncer) writeChangeReport(path string, report ChangeReport) {
+	contents, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		s.Errorf.Printf("Error marshalling the change report. %s", err)
+		return
+	}
+	err = os.WriteFile(path, append(contents, '\n'), 0644)
+	if err != nil {
+		s.Errorf.Printf("Error writing the change report to %s. %s", path, err)
+	}
+}
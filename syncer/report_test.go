@@ -0,0 +1,53 @@
+package syncer
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// sourceWithRecords returns the state of an instance with n records.
+func sourceWithRecords(n int) []SourceState {
+	records := []output.Record{}
+	for i := 0; i < n; i++ {
+		records = append(records, output.Record{Host: "host" + strconv.Itoa(i) + ".example.com", Type: "A", Value: "192.168.1.10"})
+	}
+	return []SourceState{{URL: "http://traefik.lan", Records: records}}
+}
+
+func TestCheckRemovals(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  Options
+		previous int
+		current  int
+		wantErr  bool
+	}{
+		{"no limits", Options{}, 10, 0, false},
+		{"absolute under", Options{MaxRemoved: 3}, 10, 8, false},
+		{"absolute at", Options{MaxRemoved: 3}, 10, 7, false},
+		{"absolute over", Options{MaxRemoved: 3}, 10, 6, true},
+		{"percent at", Options{MaxRemovedPercent: 50}, 10, 5, false},
+		{"percent over", Options{MaxRemovedPercent: 50}, 10, 4, true},
+		{"percent rounding", Options{MaxRemovedPercent: 50}, 3, 1, true},
+		{"percent of everything", Options{MaxRemovedPercent: 100}, 10, 0, false},
+		{"absolute over within percent", Options{MaxRemoved: 1, MaxRemovedPercent: 50}, 10, 8, true},
+		{"percent over within absolute", Options{MaxRemoved: 5, MaxRemovedPercent: 10}, 10, 8, true},
+		{"no previous records", Options{MaxRemoved: 1, MaxRemovedPercent: 10}, 0, 0, false},
+		{"no previous records with new ones", Options{MaxRemoved: 1, MaxRemovedPercent: 10}, 0, 5, false},
+		{"nothing removed", Options{MaxRemoved: 1, MaxRemovedPercent: 10}, 10, 10, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestSyncer(t, test.options)
+			previous := sourceWithRecords(test.previous)
+			report := newChangeReport(previous, sourceWithRecords(test.current))
+
+			err := s.checkRemovals(previous, report)
+			if (err != nil) != test.wantErr {
+				t.Errorf("checkRemovals() removing %d of %d = %v, want an error %t", len(report.Removed), test.previous, err, test.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package syncer
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+// State is persisted after every successful sync so operators can check when the tool last
+// did its job. It also keeps the records managed for every instance and the files they were
+// written to.
+type State struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Hosts     int           `json:"hosts"`
+	FileHash  string        `json:"fileHash"`
+	Sources   []SourceState `json:"sources"`
+	Files     []FileState   `json:"files,omitempty"`
+}
+
+// FileState is a file written by an output backend.
+type FileState struct {
+	Path    string `json:"path"`
+	Backend string `json:"backend"`
+	Hash    string `json:"hash"`
+}
+
+// SourceState is the result of the last fetch from an instance. Records are the ones of the
+// last successful fetch, at FetchedAt, kept with FetchErrorKeep.
+type SourceState struct {
+	URL       string          `json:"url"`
+	Success   bool            `json:"success"`
+	Error     string          `json:"error,omitempty"`
+	Stale     bool            `json:"stale,omitempty"`
+	FetchedAt time.Time       `json:"fetchedAt,omitempty"`
+	Records   []output.Record `json:"records,omitempty"`
+	Missing   []MissingRecord `json:"missing,omitempty"`
+}
+
+// MissingRecord is a host and type of the records of an instance whose routers disappeared
+// at Since, published until RemovalGrace passes.
+type MissingRecord struct {
+	Host  string    `json:"host"`
+	Type  string    `json:"type"`
+	Since time.Time `json:"since"`
+}
+
+// ReadState reads the state saved at path.
+func ReadState(path string) (State, error) {
+	var state State
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(contents, &state)
+	return state, err
+}
+
+// writeState saves the state to path. It is best effort, failures are only logged.
+func (s *Syncer) writeState(path string, state State) {
+	contents, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		s.Errorf.Printf("Error marshalling sync state. %s", err)
+		return
+	}
+	// Written atomically, since a truncated state would make every record look new or removed
+	err = WriteFile(path, string(contents)+"\n")
+	if err != nil {
+		s.Errorf.Printf("Error writing sync state to %s. %s", path, err)
+	}
+}
+
+// lastKnownRecords returns the records of the last successful fetch from url saved in the
+// state file, unless they are older than MaxStale.
+func (s *Syncer) lastKnownRecords(previous State, url string) (SourceState, bool) {
+	for _, source := range previous.Sources {
+		if source.URL != url || source.FetchedAt.IsZero() {
+			continue
+		}
+		if s.MaxStale > 0 && time.Since(source.FetchedAt) > s.MaxStale {
+			s.Warnf.Printf("Not using the records of %s from %s ago, older than -max-stale %s", url, time.Since(source.FetchedAt).Round(time.Second), s.MaxStale)
+			return SourceState{}, false
+		}
+		return source, true
+	}
+	return SourceState{}, false
+}
+
+func successfulSources(sources []SourceState) int {
+	successful := 0
+	for _, source := range sources {
+		if source.Success {
+			successful++
+		}
+	}
+	return successful
+}
+
+func staleSources(sources []SourceState) int {
+	stale := 0
+	for _, source := range sources {
+		if source.Stale {
+			stale++
+		}
+	}
+	return stale
+}
+
+// previousSource returns the source of url in sources, empty when there is none.
+func previousSource(sources []SourceState, url string) SourceState {
+	for _, source := range sources {
+		if source.URL == url {
+			return source
+		}
+	}
+	return SourceState{}
+}
+
+// keepMissingRecords adds to records the ones of previous whose host and type disappeared less
+// than RemovalGrace ago, and returns them with the hosts and types kept.
+func (s *Syncer) keepMissingRecords(previous SourceState, records []output.Record) ([]output.Record, []MissingRecord) {
+	current := map[recordKey]bool{}
+	for _, r := range records {
+		current[recordKey{host: r.Host, recordType: r.Type}] = true
+	}
+	since := map[recordKey]time.Time{}
+	for _, m := range previous.Missing {
+		since[recordKey{host: m.Host, recordType: m.Type}] = m.Since
+	}
+
+	kept := append([]output.Record{}, records...)
+	missing := []MissingRecord{}
+	seen := map[recordKey]bool{}
+	for _, r := range previous.Records {
+		key := recordKey{host: r.Host, recordType: r.Type}
+		if current[key] {
+			continue
+		}
+		start, ok := since[key]
+		if !ok {
+			start = time.Now()
+		}
+		if time.Since(start) >= s.RemovalGrace {
+			if !seen[key] {
+				s.Infof.Printf("Removing %s %s from %s since its routers disappeared %s ago", r.Host, r.Type, previous.URL, time.Since(start).Round(time.Second))
+			}
+			seen[key] = true
+			continue
+		}
+		kept = append(kept, r)
+		if !seen[key] {
+			if !ok {
+				s.Infof.Printf("Keeping %s %s from %s for %s since its routers disappeared", r.Host, r.Type, previous.URL, s.RemovalGrace)
+			}
+			missing = append(missing, MissingRecord{Host: r.Host, Type: r.Type, Since: start})
+		}
+		seen[key] = true
+	}
+	return kept, missing
+}
@@ -0,0 +1,49 @@
+package syncer
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/output"
+)
+
+func TestKeepMissingRecords(t *testing.T) {
+	a := output.Record{Host: "a.example.com", Type: "A", Value: "192.168.1.10"}
+	b := output.Record{Host: "b.example.com", Type: "A", Value: "192.168.1.10"}
+	bAAAA := output.Record{Host: "b.example.com", Type: "AAAA", Value: "fd00::10"}
+	tests := []struct {
+		name        string
+		since       time.Duration
+		records     []output.Record
+		wantRecords []output.Record
+		wantMissing bool
+	}{
+		{"just disappeared", 0, []output.Record{a}, []output.Record{a, b, bAAAA}, true},
+		{"within the grace period", time.Minute, []output.Record{a}, []output.Record{a, b, bAAAA}, true},
+		{"after the grace period", time.Hour, []output.Record{a}, []output.Record{a}, false},
+		{"back again", time.Minute, []output.Record{a, b, bAAAA}, []output.Record{a, b, bAAAA}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s := newTestSyncer(t, Options{RemovalGrace: 10 * time.Minute})
+			previous := SourceState{URL: "http://traefik.lan", Records: []output.Record{a, b, bAAAA}}
+			if test.since > 0 {
+				since := time.Now().Add(-test.since)
+				previous.Missing = []MissingRecord{{Host: b.Host, Type: "A", Since: since}, {Host: b.Host, Type: "AAAA", Since: since}}
+			}
+
+			records, missing := s.keepMissingRecords(previous, test.records)
+			if !reflect.DeepEqual(records, test.wantRecords) {
+				t.Errorf("keepMissingRecords() = %v, want %v", records, test.wantRecords)
+			}
+			if (len(missing) == 2) != test.wantMissing {
+				t.Errorf("keepMissingRecords() missing = %v, want the hosts and types of b %t", missing, test.wantMissing)
+			}
+			// The time of the disappearance is kept so the grace period isn't extended
+			if test.since > 0 && test.wantMissing && !missing[0].Since.Equal(previous.Missing[0].Since) {
+				t.Errorf("keepMissingRecords() missing since %s, want %s", missing[0].Since, previous.Missing[0].Since)
+			}
+		})
+	}
+}
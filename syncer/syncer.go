@@ -0,0 +1,739 @@
+// Package syncer publishes the hosts of the routers of Traefik instances as the records of DNS
+// servers. It normalizes and filters the hosts, selects the IPs they point to, resolves the
+// conflicts between instances and writes, checks and applies the file of every output backend,
+// restoring the previous one when it fails.
+package syncer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/backend"
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// Families of the IPs of the records.
+const (
+	IPFamilyV4   = "v4"
+	IPFamilyV6   = "v6"
+	IPFamilyBoth = "both"
+)
+
+// IPs published for a Traefik host that resolves to several of a family, besides a CIDR.
+const (
+	IPSelectionFirst   = "first"
+	IPSelectionAll     = "all"
+	IPSelectionPrivate = "prefer-private"
+)
+
+// Policies of an instance that can't be fetched.
+const (
+	FetchErrorDrop = "drop"
+	FetchErrorKeep = "keep"
+)
+
+// Policies of a host with different records on several instances of the same priority.
+const (
+	ConflictEmitAll   = "emit-all"
+	ConflictFirstWins = "first-wins"
+	ConflictLastWins  = "last-wins"
+	ConflictFail      = "fail"
+)
+
+// Modes of the HostRegexp and HostSNIRegexp matchers.
+const (
+	HostRegexpIgnore   = "ignore"
+	HostRegexpWildcard = "wildcard"
+	HostRegexpExpand   = "expand"
+)
+
+// Modes of the marked routers.
+const (
+	MarkerOptOut = "opt-out"
+	MarkerOptIn  = "opt-in"
+)
+
+// Policies of the hosts outside of the zones.
+const (
+	OutsideZonesSkip    = "skip"
+	OutsideZonesInclude = "include"
+)
+
+// Sources of the IPs of the records of a target.
+const (
+	AddressSourceHost      = "host"
+	AddressSourceTailscale = "tailscale"
+)
+
+// Results of the changes of the files given to the Observer.
+const (
+	ResultApplied       = "applied"
+	ResultInvalid       = "invalid"
+	ResultReloadFailure = "reload-failure"
+)
+
+// Options are the instances the records come from, the targets they are written to and how.
+// The empty policies and modes are the first of their constants.
+type Options struct {
+	// Instances are the Traefik instances the routers are retrieved from. Their records are
+	// written in this order, so the files are stable.
+	Instances []*Instance
+	// Targets are the files the records are written to, with the backends applying them.
+	Targets []*Target
+
+	// IPFamily is the family of the records, IPFamilyV4, IPFamilyV6 or IPFamilyBoth.
+	IPFamily string
+	// IPSelection is the IPs published for a Traefik host that resolves to several of a
+	// family, one of the IPSelection constants or a CIDR whose first IP is published.
+	IPSelection string
+	// AllowedCIDRs are the CIDRs the IPs of the instances must belong to. Empty allows any.
+	AllowedCIDRs []*net.IPNet
+	// Resolver resolves the hosts of the instances instead of the system resolver.
+	Resolver *net.Resolver
+	// CNAME publishes the hosts as CNAMEs of the hostname of their instance instead of its IPs.
+	CNAME bool
+
+	// Include are the expressions of the hosts published, any when empty, and Exclude the ones
+	// of the hosts never published.
+	Include []*regexp.Regexp
+	Exclude []*regexp.Regexp
+	// Zones are the domains served internally, and OutsideZones the policy of the hosts
+	// outside of them.
+	Zones        []string
+	OutsideZones string
+	// HostRegexp is how the HostRegexp matchers are published, and RegexpHosts the hosts
+	// published for the ones they match with HostRegexpExpand.
+	HostRegexp  string
+	RegexpHosts []string
+
+	// Providers and EntryPoints are the ones of the routers published, any when empty, and
+	// ExcludeProviders the ones never published.
+	Providers        []string
+	ExcludeProviders []string
+	EntryPoints      []string
+	// IncludeDisabled publishes the routers whose status isn't enabled.
+	IncludeDisabled bool
+	// SkipDashboard skips the routers of the dashboard and API of Traefik.
+	SkipDashboard bool
+	// RouterMarker is the prefix or suffix of the names of the marked routers, and
+	// MarkerMiddleware the middleware of the marked HTTP routers, published with MarkerMode.
+	RouterMarker     string
+	MarkerMiddleware string
+	MarkerMode       string
+
+	// Concurrency is the maximum number of instances fetched at the same time, all of them
+	// when 0, and InstanceTimeout the time to fetch one, forever when 0.
+	Concurrency     int
+	InstanceTimeout time.Duration
+	// FetchErrorPolicy is what is published for an instance that can't be fetched. With
+	// FetchErrorKeep the records of its last fetch in StateFile are kept for MaxStale.
+	FetchErrorPolicy string
+	MaxStale         time.Duration
+	// ConflictPolicy is what is published for a host with different records on several
+	// instances of the same priority.
+	ConflictPolicy string
+	// RemovalGrace is the time the records of the hosts whose routers disappear are kept.
+	RemovalGrace time.Duration
+	// MaxRemoved and MaxRemovedPercent are the records of the previous sync a sync can remove,
+	// any when 0, unless Force is set.
+	MaxRemoved        int
+	MaxRemovedPercent int
+	Force             bool
+	// Strict aborts the sync when an instance fails, and fails it when a file is invalid.
+	Strict bool
+	// SkipEmpty leaves the files untouched when there are no records.
+	SkipEmpty bool
+
+	// Header is the comment at the top of the files. Annotate adds the time of the sync to it,
+	// and the routers of every host to the comments of HostComment.
+	Header   string
+	Annotate bool
+	// Merge only manages the records between the markers of the files.
+	Merge bool
+	// DryRun prints the diffs of the files to DiffOutput, os.Stdout when nil, instead of
+	// writing them.
+	DryRun     bool
+	DiffOutput io.Writer
+	// Backups is the number of timestamped backups of every file kept.
+	Backups int
+	// StateFile is where the records of every sync are saved, and ChangeReport where the
+	// records changed by the last one are written.
+	StateFile    string
+	ChangeReport string
+	// LockFile is the lock held while syncing, one next to every file when empty and none
+	// when "none", waiting up to LockWait for the run holding it.
+	LockFile string
+	LockWait time.Duration
+	// ProbeSamples is the number of hosts queried on the Probe of the targets, for up to
+	// ProbeTimeout.
+	ProbeSamples int
+	ProbeTimeout time.Duration
+	// TailscaleSocket is the socket of tailscaled for the targets of AddressSourceTailscale,
+	// whose requests take at most RequestTimeout.
+	TailscaleSocket string
+	RequestTimeout  time.Duration
+
+	Debugf output.Logf
+	Infof  output.Logf
+	Warnf  output.Logf
+	Errorf output.Logf
+	// Metrics and Observer are told of the syncs and of the changes of the files.
+	Metrics  Metrics
+	Observer Observer
+}
+
+// Instance is a Traefik instance the routers are retrieved from.
+type Instance struct {
+	URL string
+	// IP is published for the hosts of the instance instead of the IPs of the host of URL.
+	IP net.IP
+	// Interface is the network interface whose IPs are published, when IP is nil.
+	Interface string
+	// Priority publishes the records of the instance for the hosts also on instances of lower
+	// priority, like a primary Traefik with a standby.
+	Priority int
+	Source   RouterSource
+}
+
+// RouterSource retrieves the routers of an instance, from the Traefik API or from where
+// Traefik reads its configuration.
+type RouterSource interface {
+	Routers(ctx context.Context) ([]traefik.Router, error)
+}
+
+// Target is a file written with the records and the output backend that feeds it to its DNS
+// server.
+type Target struct {
+	// Name is the name of the backend.
+	Name    string
+	Path    string
+	Backend backend.OutputBackend
+	// Ordered compares the file byte for byte, since the order of its lines matters, like the
+	// SOA record of a zone file. Only its entries are compared otherwise.
+	Ordered bool
+	// Probe is the address of the DNS server queried after applying the file, for the hosts in
+	// Zone when set.
+	Probe string
+	Zone  string
+	// AddressSource is where the IPs of the records come from, and IPFamily narrows them to the
+	// A or AAAA records, the ones of Options when empty.
+	AddressSource string
+	IPFamily      string
+	// IP replaces the IPs of every record, and IPMap the ones of its keys.
+	IP    net.IP
+	IPMap map[string]net.IP
+}
+
+// Metrics are told of the phases and outcomes of the syncs.
+type Metrics interface {
+	SetRouters(instance string, routers int)
+	IncAPIErrors(instance string)
+	IncReloadFailures()
+	ObservePhase(phase string, duration time.Duration)
+	ObserveSync(duration time.Duration)
+	SetSuccess(records int, at time.Time)
+	SetConfigValid(valid bool)
+}
+
+// Observer is told of the changes of the files, with the records changed by the sync.
+type Observer interface {
+	// Applied is called once the new contents of the file of t are applied.
+	Applied(t *Target, oldContents string, newContents string, report ChangeReport)
+	// Failed is called when the file of t was restored since it is invalid or couldn't be
+	// applied, with ResultInvalid or ResultReloadFailure.
+	Failed(t *Target, result string, err error, report ChangeReport)
+}
+
+// ErrorKind tells apart the failures of a sync.
+type ErrorKind int
+
+// Kinds of the errors of a sync.
+const (
+	// FetchFailure is returned when Strict aborts a sync since an instance failed.
+	FetchFailure ErrorKind = iota + 1
+	// ValidationFailure is returned when a file fails its check with Strict.
+	ValidationFailure
+	// ReloadFailure is returned when a file can't be applied to its DNS server.
+	ReloadFailure
+	// Locked is returned when another run holds the lock for longer than LockWait.
+	Locked
+)
+
+// Error is an error of a sync with its kind.
+type Error struct {
+	Kind ErrorKind
+	Err  error
+}
+
+func (e Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e Error) Unwrap() error {
+	return e.Err
+}
+
+// Syncer syncs the records of the instances of its options to its targets.
+type Syncer struct {
+	Options
+
+	ipSubnet *net.IPNet
+	// lastSources are the sources of the last successful sync, compared with the next one when
+	// there is no state file.
+	lastSources []SourceState
+	// comments are the comments of the hosts of the last sync with Annotate.
+	comments map[string]string
+}
+
+// New returns the Syncer of options, failing on its unknown policies and modes.
+func New(options Options) (*Syncer, error) {
+	s := &Syncer{Options: options, comments: map[string]string{}}
+	defaults := []struct {
+		value   *string
+		name    string
+		allowed []string
+	}{
+		{&s.IPFamily, "IP family", []string{IPFamilyV4, IPFamilyV6, IPFamilyBoth}},
+		{&s.HostRegexp, "host regexp mode", []string{HostRegexpIgnore, HostRegexpWildcard, HostRegexpExpand}},
+		{&s.MarkerMode, "marker mode", []string{MarkerOptOut, MarkerOptIn}},
+		{&s.OutsideZones, "outside zones policy", []string{OutsideZonesSkip, OutsideZonesInclude}},
+		{&s.ConflictPolicy, "conflict policy", []string{ConflictEmitAll, ConflictFirstWins, ConflictLastWins, ConflictFail}},
+		{&s.FetchErrorPolicy, "fetch error policy", []string{FetchErrorDrop, FetchErrorKeep}},
+	}
+	for _, d := range defaults {
+		if *d.value == "" {
+			*d.value = d.allowed[0]
+		}
+		if !containsString(d.allowed, *d.value) {
+			return nil, fmt.Errorf("unknown %s %s. Use %s", d.name, *d.value, strings.Join(d.allowed, ", "))
+		}
+	}
+	if s.IPSelection == "" {
+		s.IPSelection = IPSelectionFirst
+	}
+	if s.IPSelection != IPSelectionFirst && s.IPSelection != IPSelectionAll && s.IPSelection != IPSelectionPrivate {
+		_, subnet, err := net.ParseCIDR(s.IPSelection)
+		if err != nil {
+			return nil, fmt.Errorf("unknown IP selection %s. Use %s, %s, %s or a CIDR", s.IPSelection, IPSelectionFirst, IPSelectionAll, IPSelectionPrivate)
+		}
+		s.ipSubnet = subnet
+	}
+	if s.FetchErrorPolicy == FetchErrorKeep && s.StateFile == "" {
+		return nil, fmt.Errorf("fetch error policy %s requires a state file", FetchErrorKeep)
+	}
+	if s.MaxRemovedPercent < 0 || s.MaxRemovedPercent > 100 {
+		return nil, errors.New("the maximum percentage of removed records must be between 0 and 100")
+	}
+	if s.MaxRemoved < 0 {
+		return nil, errors.New("the maximum number of removed records can't be negative")
+	}
+	for _, t := range s.Targets {
+		if t.AddressSource == "" {
+			t.AddressSource = AddressSourceHost
+		}
+		if t.IPFamily == "" {
+			t.IPFamily = s.IPFamily
+		}
+	}
+	if s.Metrics == nil {
+		s.Metrics = nopMetrics{}
+	}
+	if s.Observer == nil {
+		s.Observer = nopObserver{}
+	}
+	if s.DiffOutput == nil {
+		s.DiffOutput = os.Stdout
+	}
+	return s, nil
+}
+
+// Reload returns the Syncer of options, failing like New, that keeps the sources and comments
+// of the last sync of s. The records the new options no longer publish are removed with the
+// checks of any removal, even without a state file.
+func (s *Syncer) Reload(options Options) (*Syncer, error) {
+	next, err := New(options)
+	if err != nil {
+		return nil, err
+	}
+	next.lastSources = s.lastSources
+	next.comments = s.comments
+	return next, nil
+}
+
+// Sync retrieves the records of every instance and updates the file of every target with
+// them, applying it to its DNS server when its contents change. It reports whether any file
+// was changed.
+func (s *Syncer) Sync() (changed bool, err error) {
+	syncStart := time.Now()
+	state := State{}
+	defer func() {
+		s.Metrics.ObserveSync(time.Since(syncStart))
+		if err == nil {
+			s.Infof.Printf("Synced %d records from %d of %d Traefik instances in %s. Changed: %t", state.Hosts, successfulSources(state.Sources), len(state.Sources), time.Since(syncStart), changed)
+		}
+	}()
+
+	if !s.DryRun {
+		unlock, err := s.lockTargets()
+		if err != nil {
+			return false, err
+		}
+		defer unlock()
+	}
+
+	previous := State{}
+	if s.StateFile != "" {
+		previous, err = ReadState(s.StateFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.Errorf.Printf("Error reading sync state from %s. %s", s.StateFile, err)
+		}
+		err = nil
+	}
+
+	// Without a state file the records are only known from the previous sync of the daemon
+	previousSources, known := previous.Sources, !previous.Timestamp.IsZero()
+	if !known && s.lastSources != nil {
+		previousSources, known = s.lastSources, true
+	}
+
+	results := s.FetchAll()
+	recordSets := make([][]output.Record, 0, len(results))
+	for i, result := range results {
+		source := SourceState{URL: s.Instances[i].URL, Success: result.Err == nil}
+		if result.Err == nil {
+			source.FetchedAt = time.Now()
+			if s.RemovalGrace > 0 {
+				result.Records, source.Missing = s.keepMissingRecords(previousSource(previousSources, source.URL), result.Records)
+			}
+			source.Records = output.UniqueRecords(result.Records)
+		} else {
+			s.Errorf.Printf("%s", result.Err)
+			s.Metrics.IncAPIErrors(s.Instances[i].URL)
+			source.Error = result.Err.Error()
+			if s.FetchErrorPolicy == FetchErrorKeep {
+				if last, ok := s.lastKnownRecords(previous, source.URL); ok {
+					s.Warnf.Printf("Using the %d records of %s from %s ago", len(last.Records), source.URL, time.Since(last.FetchedAt).Round(time.Second))
+					source.Stale = true
+					source.FetchedAt = last.FetchedAt
+					source.Records = last.Records
+					result.Records = last.Records
+				}
+			}
+		}
+		state.Sources = append(state.Sources, source)
+		state.Hosts += len(output.UniqueRecords(result.Records))
+		recordSets = append(recordSets, result.Records)
+	}
+	if s.Strict {
+		failed := len(state.Sources) - successfulSources(state.Sources) - staleSources(state.Sources)
+		if failed > 0 {
+			return false, Error{FetchFailure, fmt.Errorf("aborting the sync without writing the files since %d of %d instances failed", failed, len(state.Sources))}
+		}
+	}
+
+	recordSets, err = s.ResolveConflicts(recordSets)
+	if err != nil {
+		return false, err
+	}
+
+	if s.SkipEmpty && state.Hosts == 0 {
+		s.Infof.Printf("No hosts extracted, leaving the files untouched")
+		return false, nil
+	}
+
+	report := newChangeReport(previousSources, state.Sources)
+	if known && !s.Force {
+		err = s.checkRemovals(previousSources, report)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if s.Annotate {
+		s.comments = s.originComments(results)
+	}
+
+	changedFiles := []string{}
+	invalidFiles := []string{}
+	var tailnetRecordSets [][]output.Record
+	for _, t := range s.Targets {
+		if t.AddressSource == AddressSourceTailscale && tailnetRecordSets == nil {
+			var tailscaleErr error
+			tailnetRecordSets, tailscaleErr = s.tailscaleRecordSets(recordSets)
+			if tailscaleErr != nil {
+				return false, tailscaleErr
+			}
+		}
+		targetRecordSets := recordSets
+		if t.AddressSource == AddressSourceTailscale {
+			targetRecordSets = tailnetRecordSets
+		}
+		targetRecordSets = t.mapRecordSets(familyRecordSets(targetRecordSets, t.IPFamily))
+
+		contents, renderErr := s.render(t, targetRecordSets)
+		if renderErr != nil {
+			if err == nil {
+				err = fmt.Errorf("error rendering %s. %s", t.Path, renderErr)
+			}
+			continue
+		}
+		targetChanged, targetErr := s.syncTarget(t, contents, targetRecordSets, report)
+		if targetChanged {
+			changed = true
+			changedFiles = append(changedFiles, t.Path)
+		}
+		var syncErr Error
+		if errors.As(targetErr, &syncErr) && syncErr.Kind == ValidationFailure && !s.Strict {
+			invalidFiles = append(invalidFiles, t.Path)
+			continue
+		}
+		if targetErr != nil && err == nil {
+			err = targetErr
+		}
+	}
+	if err != nil || s.DryRun {
+		return changed, err
+	}
+	if len(invalidFiles) > 0 {
+		// The records of the sources aren't served, so the next sync compares with the previous ones
+		s.Warnf.Printf("Keeping the state of the previous sync since %s failed its check", strings.Join(invalidFiles, ", "))
+		return changed, nil
+	}
+
+	s.lastSources = state.Sources
+	if changed && known {
+		report.Files = changedFiles
+		s.logReport(report)
+		if s.ChangeReport != "" {
+			s.writeChangeReport(s.ChangeReport, report)
+		}
+	}
+
+	s.Metrics.SetSuccess(state.Hosts, time.Now())
+	if s.StateFile != "" {
+		state.Timestamp = time.Now()
+		for i, t := range s.Targets {
+			hash, err := fileHash(t.Path)
+			if err != nil {
+				return changed, err
+			}
+			if i == 0 {
+				state.FileHash = hash
+			}
+			state.Files = append(state.Files, FileState{Path: t.Path, Backend: t.Name, Hash: hash})
+		}
+		s.writeState(s.StateFile, state)
+	}
+	return changed, nil
+}
+
+// render returns the contents of the file of t with the records of every instance.
+func (s *Syncer) render(t *Target, recordSets [][]output.Record) (string, error) {
+	header := s.Header
+	if s.Annotate {
+		header = strings.TrimPrefix(header+"\n"+output.TimestampHeader+time.Now().Format(time.RFC3339), "\n")
+	}
+	if fileWriter, ok := t.Backend.(backend.FileWriter); ok {
+		current, _ := os.ReadFile(t.Path)
+		return fileWriter.WriteFile(header, recordSets, string(current))
+	}
+
+	builder := strings.Builder{}
+	output.WriteHeader(&builder, header)
+	for _, records := range recordSets {
+		t.Backend.Render(&builder, records)
+	}
+	return builder.String(), nil
+}
+
+// syncTarget writes contents to the file of t when they changed, verifies it, applies it to
+// the DNS server and probes it for the records of recordSets, restoring the previous file if
+// any fails. The Observer is told of the outcome with the record changes of report. It
+// reports whether the file was changed.
+func (s *Syncer) syncTarget(t *Target, contents string, recordSets [][]output.Record, report ChangeReport) (bool, error) {
+	if s.Merge {
+		existing, err := os.ReadFile(t.Path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+		contents, err = output.Merge(string(existing), contents)
+		if err != nil {
+			return false, fmt.Errorf("error merging the records into %s. %s", t.Path, err)
+		}
+	}
+
+	if s.DryRun {
+		return s.printDiff(t.Path, contents)
+	}
+
+	err := createFileIfNotExists(t.Path)
+	if err != nil {
+		return false, err
+	}
+	oldContents, err := os.ReadFile(t.Path)
+	if err != nil {
+		return false, err
+	}
+	if sameContents(t, string(oldContents), contents) {
+		return false, nil
+	}
+	err = s.Backup(t.Path)
+	if err != nil {
+		return false, fmt.Errorf("error backing up %s. %s", t.Path, err)
+	}
+	start := time.Now()
+	err = WriteFile(t.Path, contents)
+	if err != nil {
+		return false, s.restore(t, err)
+	}
+	s.Debugf.Printf("Wrote %s in %s", t.Path, time.Since(start))
+	s.Metrics.ObservePhase("write", time.Since(start))
+
+	err = s.Verify(t)
+	s.Metrics.SetConfigValid(err == nil)
+	if err != nil {
+		s.Errorf.Printf("Error checking configuration of %s. %s", t.Path, err)
+		restoreErr := s.restore(t, nil)
+		s.Observer.Failed(t, ResultInvalid, err, report)
+		if restoreErr != nil {
+			return false, restoreErr
+		}
+		return false, Error{ValidationFailure, fmt.Errorf("error checking configuration of %s. %s", t.Path, err)}
+	}
+	err = s.Apply(t, string(oldContents), contents)
+	if err != nil {
+		s.Metrics.IncReloadFailures()
+		s.Observer.Failed(t, ResultReloadFailure, err, report)
+		// Restore the previous file so the next sync detects the change and tries again
+		restoreErr := s.restore(t, nil)
+		rollbackErr := t.Backend.Rollback(string(oldContents), contents)
+		if rollbackErr != nil {
+			s.Errorf.Printf("Error rolling back %s. %s", t.Path, rollbackErr)
+		}
+		if restoreErr != nil {
+			return false, restoreErr
+		}
+		return false, Error{ReloadFailure, err}
+	}
+	records := []output.Record{}
+	for _, recordSet := range recordSets {
+		records = append(records, recordSet...)
+	}
+	err = s.probe(t, records)
+	if err != nil {
+		s.Metrics.IncReloadFailures()
+		s.Observer.Failed(t, ResultReloadFailure, err, report)
+		// The new file is applied, so the previous one has to be applied again
+		restoreErr := s.restore(t, nil)
+		if restoreErr != nil {
+			return false, restoreErr
+		}
+		rollbackErr := s.Apply(t, contents, string(oldContents))
+		if rollbackErr != nil {
+			s.Errorf.Printf("Error applying the previous %s. %s", t.Path, rollbackErr)
+		}
+		return false, Error{ReloadFailure, err}
+	}
+	s.Observer.Applied(t, string(oldContents), contents, report)
+	return true, nil
+}
+
+// restore restores the backup of the file of t after it failed with err, returning err or the
+// error restoring it.
+func (s *Syncer) restore(t *Target, err error) error {
+	restoreErr := Restore(t.Path)
+	if restoreErr != nil {
+		return fmt.Errorf("error restoring backup %s. %s", t.Path, restoreErr)
+	}
+	return err
+}
+
+// Verify runs the checker of the backend of t on its file.
+func (s *Syncer) Verify(t *Target) error {
+	start := time.Now()
+	defer func() {
+		s.Debugf.Printf("Checked %s in %s", t.Path, time.Since(start))
+		s.Metrics.ObservePhase("checkconf", time.Since(start))
+	}()
+
+	return t.Backend.Verify(t.Path)
+}
+
+// Apply makes the DNS server of t use the new contents of its file.
+func (s *Syncer) Apply(t *Target, oldContents string, newContents string) error {
+	start := time.Now()
+	defer func() {
+		s.Debugf.Printf("Applied %s with the %s backend in %s", t.Path, t.Name, time.Since(start))
+		s.Metrics.ObservePhase("reload", time.Since(start))
+	}()
+
+	return t.Backend.Apply(oldContents, newContents)
+}
+
+// printDiff prints the changes that writing contents would make to the file at path.
+func (s *Syncer) printDiff(path string, contents string) (bool, error) {
+	current, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	diff := UnifiedDiff(path, path+" (generated)", string(current), contents)
+	if diff == "" {
+		s.Infof.Printf("No changes to %s", path)
+		return false, nil
+	}
+	fmt.Fprint(s.DiffOutput, diff)
+	return true, nil
+}
+
+// sameContents reports whether the current file of t already answers the records of
+// contents. Only the entries of the files are compared, unless t is Ordered, so comments, the
+// header and the order of the instances don't reload the DNS server.
+func sameContents(t *Target, current string, contents string) bool {
+	if t.Ordered {
+		return output.WithoutTimestamp(contents) == output.WithoutTimestamp(current)
+	}
+	return output.Entries(contents) == output.Entries(current)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyString(values []string, candidates []string) bool {
+	for _, candidate := range candidates {
+		if containsString(values, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) SetRouters(instance string, routers int)           {}
+func (nopMetrics) IncAPIErrors(instance string)                      {}
+func (nopMetrics) IncReloadFailures()                                {}
+func (nopMetrics) ObservePhase(phase string, duration time.Duration) {}
+func (nopMetrics) ObserveSync(duration time.Duration)                {}
+func (nopMetrics) SetSuccess(records int, at time.Time)              {}
+func (nopMetrics) SetConfigValid(valid bool)                         {}
+
+type nopObserver struct{}
+
+func (nopObserver) Applied(t *Target, oldContents string, newContents string, report ChangeReport) {
+}
+
+func (nopObserver) Failed(t *Target, result string, err error, report ChangeReport) {}
@@ -0,0 +1,203 @@
+package syncer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/traefik"
+)
+
+// lineBackend writes a line per record, failing its check with verifyErr.
+type lineBackend struct {
+	verifyErr error
+	applied   int
+}
+
+func (b *lineBackend) Render(builder *strings.Builder, records []output.Record) {
+	for _, r := range records {
+		fmt.Fprintf(builder, "%s %s %s\n", r.Host, r.Type, r.Value)
+	}
+}
+
+func (b *lineBackend) Verify(path string) error {
+	return b.verifyErr
+}
+
+func (b *lineBackend) Apply(oldContents string, newContents string) error {
+	b.applied++
+	return nil
+}
+
+func (b *lineBackend) Rollback(oldContents string, newContents string) error {
+	return nil
+}
+
+// recordingObserver keeps the results of the changes of the files.
+type recordingObserver struct {
+	results []string
+}
+
+func (o *recordingObserver) Applied(t *Target, oldContents string, newContents string, report ChangeReport) {
+	o.results = append(o.results, ResultApplied)
+}
+
+func (o *recordingObserver) Failed(t *Target, result string, err error, report ChangeReport) {
+	o.results = append(o.results, result)
+}
+
+func newSyncTest(t *testing.T, b *lineBackend, observer Observer, options Options) (*Syncer, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "records.conf")
+	options.Instances = []*Instance{{
+		URL: "http://traefik.lan",
+		IP:  net.ParseIP("192.168.1.10").To4(),
+		Source: staticSource{
+			{Name: "a@docker", Rule: "Host(`a.example.com`)", Status: traefik.StatusEnabled},
+		},
+	}}
+	options.Targets = []*Target{{Name: "lines", Path: path, Backend: b}}
+	options.Observer = observer
+	return newTestSyncer(t, options), path
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+	}{
+		{"ip family", Options{IPFamily: "v5"}},
+		{"ip selection", Options{IPSelection: "last"}},
+		{"host regexp", Options{HostRegexp: "all"}},
+		{"marker mode", Options{MarkerMode: "opt"}},
+		{"outside zones", Options{OutsideZones: "drop"}},
+		{"conflict policy", Options{ConflictPolicy: "random"}},
+		{"fetch error policy", Options{FetchErrorPolicy: "retry"}},
+		{"keep without state", Options{FetchErrorPolicy: FetchErrorKeep}},
+		{"max removed percent", Options{MaxRemovedPercent: 101}},
+		{"max removed", Options{MaxRemoved: -1}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(test.options)
+			if err == nil {
+				t.Errorf("New() succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestNewDefaults(t *testing.T) {
+	s := newTestSyncer(t, Options{Targets: []*Target{{Name: "lines", Path: "records.conf"}}})
+	if s.IPFamily != IPFamilyV4 || s.IPSelection != IPSelectionFirst || s.ConflictPolicy != ConflictEmitAll || s.FetchErrorPolicy != FetchErrorDrop {
+		t.Errorf("New() = %+v, want the first policies", s.Options)
+	}
+	if target := s.Targets[0]; target.AddressSource != AddressSourceHost || target.IPFamily != IPFamilyV4 {
+		t.Errorf("New() target = %+v, want the host address source and the v4 family", target)
+	}
+}
+
+func TestSync(t *testing.T) {
+	b := &lineBackend{}
+	observer := &recordingObserver{}
+	s, path := newSyncTest(t, b, observer, Options{Header: "# generated"})
+
+	changed, err := s.Sync()
+	if err != nil {
+		t.Fatalf("Sync() failed. %s", err)
+	}
+	if !changed {
+		t.Errorf("Sync() = false, want the file changed")
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() failed. %s", err)
+	}
+	if !strings.Contains(string(contents), "a.example.com A 192.168.1.10\n") {
+		t.Errorf("got the file:\n%s\nwant the record of a.example.com", contents)
+	}
+
+	changed, err = s.Sync()
+	if err != nil {
+		t.Fatalf("second Sync() failed. %s", err)
+	}
+	if changed {
+		t.Errorf("second Sync() = true, want the file unchanged")
+	}
+	if b.applied != 1 || strings.Join(observer.results, ",") != ResultApplied {
+		t.Errorf("got %d applies and the results %v, want a single applied change", b.applied, observer.results)
+	}
+}
+
+func TestSyncRestoresInvalidFile(t *testing.T) {
+	b := &lineBackend{verifyErr: errors.New("syntax error")}
+	observer := &recordingObserver{}
+	s, path := newSyncTest(t, b, observer, Options{Strict: true})
+	err := os.WriteFile(path, []byte("previous\n"), 0644)
+	if err != nil {
+		t.Fatalf("WriteFile() failed. %s", err)
+	}
+
+	changed, err := s.Sync()
+	var syncErr Error
+	if !errors.As(err, &syncErr) || syncErr.Kind != ValidationFailure {
+		t.Errorf("Sync() = %v, want a validation failure", err)
+	}
+	if changed || b.applied != 0 {
+		t.Errorf("Sync() changed %t with %d applies, want the invalid file left unapplied", changed, b.applied)
+	}
+	contents, _ := os.ReadFile(path)
+	if string(contents) != "previous\n" {
+		t.Errorf("got the file %q, want the previous one restored", contents)
+	}
+	if strings.Join(observer.results, ",") != ResultInvalid {
+		t.Errorf("got the results %v, want %s", observer.results, ResultInvalid)
+	}
+}
+
+func TestSyncKeepsStateOfInvalidFile(t *testing.T) {
+	b := &lineBackend{verifyErr: errors.New("syntax error")}
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	s, _ := newSyncTest(t, b, nil, Options{StateFile: stateFile})
+
+	changed, err := s.Sync()
+	if err != nil || changed {
+		t.Errorf("Sync() = %t, %v, want the invalid file skipped without -strict", changed, err)
+	}
+	if _, err := os.Stat(stateFile); !errors.Is(err, os.ErrNotExist) || s.lastSources != nil {
+		t.Errorf("Sync() saved the state of the records of the invalid file")
+	}
+
+	b.verifyErr = nil
+	changed, err = s.Sync()
+	if err != nil || !changed || b.applied != 1 {
+		t.Errorf("second Sync() = %t, %v with %d applies, want the file applied", changed, err, b.applied)
+	}
+	state, err := ReadState(stateFile)
+	if err != nil || len(state.Sources) != 1 {
+		t.Errorf("ReadState() = %+v, %v, want the state of the applied file", state, err)
+	}
+}
+
+func TestSyncDryRun(t *testing.T) {
+	b := &lineBackend{}
+	diff := &bytes.Buffer{}
+	s, path := newSyncTest(t, b, nil, Options{DryRun: true, DiffOutput: diff})
+
+	changed, err := s.Sync()
+	if err != nil {
+		t.Fatalf("Sync() failed. %s", err)
+	}
+	if !changed || !strings.Contains(diff.String(), "+a.example.com A 192.168.1.10") {
+		t.Errorf("Sync() = %t with the diff:\n%s\nwant the added record", changed, diff)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) || b.applied != 0 {
+		t.Errorf("Sync() wrote %s or applied it with DryRun", path)
+	}
+}
@@ -0,0 +1,103 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/dcasado/traefik2unbound/output"
+	"github.com/dcasado/traefik2unbound/tailscale"
+)
+
+// tailscaleRecordSets returns the records of every instance pointing to the Tailscale IPs of
+// the node of its host instead, or of this node when the instance has no host or it is a
+// loopback one.
+func (s *Syncer) tailscaleRecordSets(recordSets [][]output.Record) ([][]output.Record, error) {
+	ctx := context.Background()
+	if s.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.RequestTimeout)
+		defer cancel()
+	}
+	client := tailscale.Client{Socket: s.TailscaleSocket}
+	status, err := client.Status(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sets := make([][]output.Record, 0, len(recordSets))
+	for i, records := range recordSets {
+		if len(records) == 0 {
+			sets = append(sets, records)
+			continue
+		}
+		node := *status.Self
+		host := instanceHost(s.Instances[i].URL)
+		if host != "" {
+			var ok bool
+			node, ok = status.Node(host)
+			if !ok {
+				return nil, fmt.Errorf("no node of the tailnet is named %s for the Tailscale IPs of %s", host, s.Instances[i].URL)
+			}
+		}
+		ips := make([]net.IP, 0, len(node.TailscaleIPs))
+		for _, value := range node.TailscaleIPs {
+			if ip := net.ParseIP(value); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+		ips, err = s.SelectIPs(ips, "the Tailscale node "+node.HostName)
+		if err != nil {
+			return nil, err
+		}
+
+		set := []output.Record{}
+		seen := map[string]bool{}
+		for _, record := range records {
+			if seen[record.Host] {
+				continue
+			}
+			seen[record.Host] = true
+			for _, ip := range ips {
+				set = append(set, output.Record{Host: record.Host, Type: RecordType(ip), Value: ip.String()})
+			}
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// instanceHost returns the host of the URL of an instance, empty when it has none or it is a
+// loopback one, so its Traefik runs on this node.
+func instanceHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	host := u.Hostname()
+	if ip := net.ParseIP(host); host == "localhost" || ip != nil && ip.IsLoopback() {
+		return ""
+	}
+	return host
+}
+
+// familyRecordSets returns the records of recordSets without the A or AAAA ones outside of
+// family.
+func familyRecordSets(recordSets [][]output.Record, family string) [][]output.Record {
+	if family == IPFamilyBoth {
+		return recordSets
+	}
+	sets := make([][]output.Record, 0, len(recordSets))
+	for _, records := range recordSets {
+		set := make([]output.Record, 0, len(records))
+		for _, record := range records {
+			if family == IPFamilyV4 && record.Type == "AAAA" || family == IPFamilyV6 && record.Type == "A" {
+				continue
+			}
+			set = append(set, record)
+		}
+		sets = append(sets, set)
+	}
+	return sets
+}
@@ -0,0 +1,101 @@
+// Package tailscale reads the Tailscale IPs of the nodes of the tailnet from the local API of
+// tailscaled.
+package tailscale
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// DefaultSocket is the unix socket of the local API of tailscaled on Linux.
+const DefaultSocket = "/var/run/tailscale/tailscaled.sock"
+
+// Client reads the status of the tailnet from the local API of tailscaled at Socket.
+type Client struct {
+	Socket string
+}
+
+// Node is a machine of the tailnet.
+type Node struct {
+	HostName     string   `json:"HostName"`
+	DNSName      string   `json:"DNSName"`
+	TailscaleIPs []string `json:"TailscaleIPs"`
+}
+
+// Status is this node and its peers.
+type Status struct {
+	Self *Node           `json:"Self"`
+	Peer map[string]Node `json:"Peer"`
+}
+
+// Status returns the status of the tailnet.
+func (c *Client) Status(ctx context.Context) (Status, error) {
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _ string, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", c.Socket)
+			},
+		},
+	}
+	// The host is ignored over the socket, but tailscaled expects this one
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://local-tailscaled.sock/localapi/v0/status", nil)
+	if err != nil {
+		return Status{}, err
+	}
+	req.Header.Set("Sec-Tailscale", "localapi")
+	resp, err := client.Do(req)
+	if err != nil {
+		return Status{}, fmt.Errorf("could not retrieve the status of Tailscale. %s", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Status{}, fmt.Errorf("error reading Tailscale response body. %s", err)
+	}
+	if resp.StatusCode >= 400 {
+		return Status{}, fmt.Errorf("response from Tailscale not successful. Status: %s, %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	status := Status{}
+	err = json.Unmarshal(body, &status)
+	if err != nil {
+		return Status{}, fmt.Errorf("error unmarshalling Tailscale response body. %s", err)
+	}
+	if status.Self == nil {
+		return Status{}, fmt.Errorf("not logged in to Tailscale")
+	}
+	return status, nil
+}
+
+// Node returns the node named name, by its host name or MagicDNS name, or that has the
+// Tailscale IP name.
+func (s Status) Node(name string) (Node, bool) {
+	keys := make([]string, 0, len(s.Peer))
+	for key := range s.Peer {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	nodes := []Node{*s.Self}
+	for _, key := range keys {
+		nodes = append(nodes, s.Peer[key])
+	}
+	name = strings.ToLower(strings.TrimSuffix(name, "."))
+	for _, node := range nodes {
+		dnsName := strings.ToLower(strings.TrimSuffix(node.DNSName, "."))
+		shortName, _, _ := strings.Cut(dnsName, ".")
+		if name == strings.ToLower(node.HostName) || name == dnsName || name == shortName {
+			return node, true
+		}
+		for _, ip := range node.TailscaleIPs {
+			if name == ip {
+				return node, true
+			}
+		}
+	}
+	return Node{}, false
+}
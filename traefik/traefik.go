@@ -0,0 +1,339 @@
+// Package traefik retrieves the routers of the Traefik API.
+package traefik
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dcasado/traefik2unbound/rules"
+)
+
+// Router is an HTTP or TCP router of the Traefik API.
+type Router struct {
+	Name        string   `json:"name"`
+	Rule        string   `json:"rule"`
+	Service     string   `json:"service"`
+	Provider    string   `json:"provider"`
+	Status      string   `json:"status"`
+	EntryPoints []string `json:"entryPoints"`
+	// Middlewares are only reported for HTTP routers.
+	Middlewares []string `json:"middlewares"`
+}
+
+// Status of the routers that Traefik serves.
+const StatusEnabled = "enabled"
+
+// ProviderName returns the provider of the router, taken from the suffix of its name like
+// myapp@docker when the API doesn't report it.
+func (r Router) ProviderName() string {
+	if r.Provider != "" {
+		return r.Provider
+	}
+	if i := strings.LastIndex(r.Name, "@"); i >= 0 {
+		return r.Name[i+1:]
+	}
+	return ""
+}
+
+// ShortName returns the name of the router without its provider suffix.
+func (r Router) ShortName() string {
+	if i := strings.LastIndex(r.Name, "@"); i >= 0 {
+		return r.Name[:i]
+	}
+	return r.Name
+}
+
+// HasMiddleware reports whether the router uses the middleware name, with or without its
+// provider suffix.
+func (r Router) HasMiddleware(name string) bool {
+	for _, middleware := range r.Middlewares {
+		if middleware == name || strings.HasPrefix(middleware, name+"@") {
+			return true
+		}
+	}
+	return false
+}
+
+// IsEnabled reports whether Traefik serves the router. Routers without a status, from
+// versions of the API that don't report it, are considered enabled.
+func (r Router) IsEnabled() bool {
+	return r.Status == "" || r.Status == StatusEnabled
+}
+
+// dashboardPaths are the paths of the dashboard in the rules of its routers.
+var dashboardPaths = map[string]bool{"/dashboard": true, "/dashboard/": true}
+
+// IsDashboard reports whether the router serves the Traefik dashboard or API, by its service
+// or by the path of the dashboard in its rule. Other paths like /dashboards don't match.
+func (r Router) IsDashboard() bool {
+	if r.Service == "api@internal" || r.Service == "dashboard@internal" {
+		return true
+	}
+	result, err := rules.Parse(r.Rule)
+	if err != nil {
+		return false
+	}
+	for _, path := range result.Paths {
+		if dashboardPaths[path] {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSConfig are the settings of the connection to a Traefik API over HTTPS.
+type TLSConfig struct {
+	// CACert is the path of a PEM file with CA certificates to trust besides the system ones.
+	CACert string
+	// InsecureSkipVerify disables the verification of the Traefik certificate.
+	InsecureSkipVerify bool
+	// ClientCert and ClientKey are the paths of the PEM client certificate and key for mutual TLS.
+	ClientCert string
+	ClientKey  string
+}
+
+// NewHTTPClient returns an HTTP client that trusts the CA of config on top of the system
+// ones, presents its client certificate for mutual TLS and, if asked to, skips the
+// verification of the Traefik certificate. Requests taking longer than timeout fail,
+// 0 disables the timeout.
+func NewHTTPClient(config TLSConfig, timeout time.Duration) (*http.Client, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(config.CACert)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", config.CACert)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCert != "" || config.ClientKey != "" {
+		certificate, err := tls.LoadX509KeyPair(config.ClientCert, config.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: timeout}, nil
+}
+
+// Client retrieves the routers of the Traefik API at URL. URL can have a path when the API
+// is served behind a prefix, like https://gw.lan/traefik, and the endpoints are joined to it.
+type Client struct {
+	URL        string
+	HTTPClient *http.Client
+	// HTTPRoutersURL, TCPRoutersURL and RawDataURL replace the default endpoints. They are
+	// resolved against URL, so they can be absolute URLs, absolute paths or relative paths.
+	HTTPRoutersURL string
+	TCPRoutersURL  string
+	RawDataURL     string
+	// Token is sent as a bearer token. Otherwise Username and Password are sent with basic
+	// authentication when Username is set.
+	Token    string
+	Username string
+	Password string
+	// Retries is the number of times a request failing with a connection error or a 5xx
+	// status is retried, waiting RetryBackoff before the first retry and doubling it after
+	// every attempt, with jitter.
+	Retries      int
+	RetryBackoff time.Duration
+	// OnRetry is called before waiting to retry a failed request.
+	OnRetry func(err error, delay time.Duration)
+	// RawData retrieves every router with a single request to /api/rawdata instead of
+	// the HTTP and TCP routers endpoints.
+	RawData bool
+
+	// random is the source of the jitter of the retries, seeded per client so processes
+	// started together don't retry in lockstep.
+	randomMu sync.Mutex
+	random   *rand.Rand
+}
+
+// Routers returns the HTTP and TCP routers of the Traefik API.
+func (c *Client) Routers(ctx context.Context) ([]Router, error) {
+	if c.RawData {
+		return c.rawDataRouters(ctx)
+	}
+
+	httpURL, err := c.endpoint(c.HTTPRoutersURL, "api/http/routers")
+	if err != nil {
+		return nil, err
+	}
+	tcpURL, err := c.endpoint(c.TCPRoutersURL, "api/tcp/routers")
+	if err != nil {
+		return nil, err
+	}
+	httpRouters := []Router{}
+	err = c.get(ctx, httpURL, &httpRouters)
+	if err != nil {
+		return nil, err
+	}
+	tcpRouters := []Router{}
+	err = c.get(ctx, tcpURL, &tcpRouters)
+	if err != nil {
+		return nil, err
+	}
+	return append(httpRouters, tcpRouters...), nil
+}
+
+// rawData is the part of the /api/rawdata payload with the routers, keyed by their names.
+// UDP routers have no rule and are left out.
+type rawData struct {
+	Routers    map[string]Router `json:"routers"`
+	TCPRouters map[string]Router `json:"tcpRouters"`
+}
+
+// rawDataRouters returns the HTTP and TCP routers of the /api/rawdata endpoint, sorted by name.
+func (c *Client) rawDataRouters(ctx context.Context) ([]Router, error) {
+	rawDataURL, err := c.endpoint(c.RawDataURL, "api/rawdata")
+	if err != nil {
+		return nil, err
+	}
+	data := rawData{}
+	err = c.get(ctx, rawDataURL, &data)
+	if err != nil {
+		return nil, err
+	}
+	routers := make([]Router, 0, len(data.Routers)+len(data.TCPRouters))
+	for _, named := range []map[string]Router{data.Routers, data.TCPRouters} {
+		names := make([]string, 0, len(named))
+		for name := range named {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			router := named[name]
+			if router.Name == "" {
+				router.Name = name
+			}
+			routers = append(routers, router)
+		}
+	}
+	return routers, nil
+}
+
+// endpoint resolves the explicit endpoint, or the default path when it is empty, against URL
+// keeping the path and the query of URL.
+func (c *Client) endpoint(explicit string, defaultPath string) (string, error) {
+	base, err := url.Parse(c.URL)
+	if err != nil {
+		return "", fmt.Errorf("invalid traefik url %s. %s", c.URL, err)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		if base.RawPath != "" {
+			base.RawPath += "/"
+		}
+	}
+	if explicit == "" {
+		explicit = defaultPath
+	}
+	ref, err := url.Parse(explicit)
+	if err != nil {
+		return "", fmt.Errorf("invalid endpoint %s. %s", explicit, err)
+	}
+	resolved := base.ResolveReference(ref)
+	if ref.RawQuery == "" {
+		resolved.RawQuery = base.RawQuery
+	}
+	return resolved.String(), nil
+}
+
+// get decodes the JSON response of apiURL into v, retrying the transient errors.
+func (c *Client) get(ctx context.Context, apiURL string, v interface{}) error {
+	for attempt := 0; ; attempt++ {
+		transient, err := c.tryGet(ctx, apiURL, v)
+		if err == nil || !transient || attempt >= c.Retries || ctx.Err() != nil {
+			return err
+		}
+
+		delay := c.backoff(attempt)
+		if c.OnRetry != nil {
+			c.OnRetry(err, delay)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns RetryBackoff doubled attempt times, randomly reduced by up to half so
+// clients retrying at the same time spread out.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.RetryBackoff << attempt
+	if delay <= 0 {
+		return 0
+	}
+	c.randomMu.Lock()
+	defer c.randomMu.Unlock()
+	if c.random == nil {
+		c.random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return delay/2 + time.Duration(c.random.Int63n(int64(delay/2)+1))
+}
+
+// tryGet decodes the JSON response of apiURL into v once. It reports whether the error is
+// transient and worth retrying.
+func (c *Client) tryGet(ctx context.Context, apiURL string, v interface{}) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiURL, nil)
+	if err != nil {
+		return false, err
+	}
+	c.setAuthorization(req)
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("could not retrieve routers from \"%s\". %s", apiURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return resp.StatusCode >= 500, fmt.Errorf("response from %s not successful. Status: %s", apiURL, resp.Status)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return true, fmt.Errorf("error reading traefik response body. %s", err)
+	}
+	err = json.Unmarshal(body, v)
+	if err != nil {
+		return false, fmt.Errorf("error unmarshalling traefik response body. %s", err)
+	}
+	return false, nil
+}
+
+// setAuthorization adds the bearer token or basic auth credentials to req.
+func (c *Client) setAuthorization(req *http.Request) {
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	} else if c.Username != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+}
@@ -0,0 +1,165 @@
+package traefik
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRouterIsDashboard(t *testing.T) {
+	tests := []struct {
+		name   string
+		router Router
+		want   bool
+	}{
+		{"api service", Router{Service: "api@internal", Rule: "Host(`traefik.example.com`)"}, true},
+		{"dashboard service", Router{Service: "dashboard@internal", Rule: "Host(`traefik.example.com`)"}, true},
+		{"dashboard path", Router{Service: "whoami", Rule: "Host(`traefik.example.com`) && (PathPrefix(`/api`) || PathPrefix(`/dashboard`))"}, true},
+		{"dashboard path with slash", Router{Service: "whoami", Rule: "Host(`traefik.example.com`) && PathPrefix(`/dashboard/`)"}, true},
+		{"longer path", Router{Service: "grafana", Rule: "Host(`grafana.example.com`) && PathPrefix(`/dashboards`)"}, false},
+		{"path with suffix", Router{Service: "app", Rule: "Host(`app.example.com`) && PathPrefix(`/dashboard-app`)"}, false},
+		{"negated path", Router{Service: "app", Rule: "Host(`app.example.com`) && !PathPrefix(`/dashboard`)"}, false},
+		{"host only", Router{Service: "app", Rule: "Host(`dashboard.example.com`)"}, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.router.IsDashboard(); got != test.want {
+				t.Errorf("IsDashboard() of %s = %v, want %v", test.router.Rule, got, test.want)
+			}
+		})
+	}
+}
+
+// flakyServer answers the routers endpoints with a 503 the first failures requests and with
+// one router afterwards.
+func flakyServer(t *testing.T, failures int32) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= failures {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/http/routers") {
+			w.Write([]byte("[{\"name\":\"whoami@docker\",\"rule\":\"Host(`whoami.example.com`)\",\"status\":\"enabled\"}]"))
+			return
+		}
+		w.Write([]byte(`[]`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestClientRetriesServerErrors(t *testing.T) {
+	server, requests := flakyServer(t, 2)
+	delays := []time.Duration{}
+	client := &Client{
+		URL:          server.URL,
+		Retries:      3,
+		RetryBackoff: 10 * time.Millisecond,
+		OnRetry: func(err error, delay time.Duration) {
+			delays = append(delays, delay)
+		},
+	}
+
+	routers, err := client.Routers(context.Background())
+	if err != nil {
+		t.Fatalf("Routers() failed. %s", err)
+	}
+	if len(routers) != 1 || routers[0].Name != "whoami@docker" {
+		t.Errorf("Routers() = %v, want the whoami@docker router", routers)
+	}
+	// Two failed requests of the HTTP routers, then the HTTP and TCP routers
+	if got := atomic.LoadInt32(requests); got != 4 {
+		t.Errorf("got %d requests, want 4", got)
+	}
+	if len(delays) != 2 {
+		t.Fatalf("got %d retries, want 2", len(delays))
+	}
+	for attempt, delay := range delays {
+		max := 10 * time.Millisecond << attempt
+		if delay < max/2 || delay > max {
+			t.Errorf("delay of retry %d = %s, want between %s and %s", attempt, delay, max/2, max)
+		}
+	}
+}
+
+func TestClientGivesUpAfterRetries(t *testing.T) {
+	server, requests := flakyServer(t, 100)
+	client := &Client{URL: server.URL, Retries: 2, RetryBackoff: time.Millisecond}
+
+	_, err := client.Routers(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("Routers() error = %v, want the 503 status", err)
+	}
+	if got := atomic.LoadInt32(requests); got != 3 {
+		t.Errorf("got %d requests, want 3", got)
+	}
+}
+
+func TestClientDoesNotRetryClientErrors(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer server.Close()
+	client := &Client{URL: server.URL, Retries: 3, RetryBackoff: time.Millisecond}
+
+	_, err := client.Routers(context.Background())
+	if err == nil {
+		t.Fatal("Routers() succeeded, want the 403 status")
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+func TestClientStopsRetryingWhenCanceled(t *testing.T) {
+	server, _ := flakyServer(t, 100)
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &Client{
+		URL:          server.URL,
+		Retries:      10,
+		RetryBackoff: time.Hour,
+		OnRetry: func(err error, delay time.Duration) {
+			cancel()
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := client.Routers(ctx)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "503") {
+			t.Errorf("Routers() error = %v, want the last 503 status", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Routers() kept waiting after the context was canceled")
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	client := &Client{RetryBackoff: time.Second}
+	seen := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		delay := client.backoff(2)
+		if delay < 2*time.Second || delay > 4*time.Second {
+			t.Fatalf("backoff(2) = %s, want between 2s and 4s", delay)
+		}
+		seen[delay] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("backoff(2) returned the same delay 20 times, want jitter")
+	}
+	if delay := (&Client{}).backoff(3); delay != 0 {
+		t.Errorf("backoff without RetryBackoff = %s, want 0", delay)
+	}
+}